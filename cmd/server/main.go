@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
 	"go-template-structure/internal/handler"
 	"go-template-structure/internal/interfaces"
 	"go-template-structure/internal/middleware"
@@ -18,6 +19,11 @@ import (
 	"go-template-structure/internal/service"
 	"go-template-structure/pkg/database"
 	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/mailer"
+	"go-template-structure/pkg/oauth"
+	"go-template-structure/pkg/storage"
+	"go-template-structure/pkg/utils"
+	"go-template-structure/pkg/webauthn"
 
 	_ "go-template-structure/docs" // swagger docs
 
@@ -58,12 +64,25 @@ func main() {
 	logger.Init(cfg.LogLevel, cfg.LogFormat)
 	logger.Info("Starting application...")
 
+	// Load JWT signing and verification keys
+	keys, err := utils.LoadKeySet(cfg.JWT)
+	if err != nil {
+		logger.Fatal("Failed to load JWT keys:", err)
+	}
+
 	// Initialize primary database connection (required)
 	db, err := database.NewPostgresConnection(cfg.Database)
 	if err != nil {
 		logger.Fatal("Failed to connect to database:", err)
 	}
-	userRepo := repository.NewUserRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	identityRepo := repository.NewUserIdentityRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	webAuthnCredentialRepo := repository.NewWebAuthnCredentialRepository(db)
+	userImportJobRepo := repository.NewUserImportJobRepository(db)
 	logger.Info("Connected to PostgreSQL database")
 
 	// Initialize Redis cache (optional but recommended)
@@ -75,16 +94,97 @@ func main() {
 		logger.Info("Connected to Redis")
 	}
 
+	// Every user write clears the user's cache entry, so cached versions (ETags) stay current
+	userRepo := repository.NewCacheInvalidatingUserRepository(repository.NewUserRepository(db), redisClient)
+
+	// Token state (revocation list) falls back to process memory when Redis is unavailable
+	var tokenStore interfaces.RedisInterface = database.NewMemoryStore()
+	if redisClient != nil {
+		tokenStore = redisClient
+	} else {
+		logger.Warn("Token revocation is stored in memory and will not survive restarts")
+	}
+	tokenRepo := repository.NewTokenRepository(tokenStore)
+	throttleRepo := repository.NewThrottleRepository(tokenStore)
+	oauthStateRepo := repository.NewOAuthStateRepository(tokenStore)
+	magicLinkRepo := repository.NewMagicLinkRepository(tokenStore)
+	webAuthnChallengeRepo := repository.NewWebAuthnChallengeRepository(tokenStore)
+
+	// Key used to encrypt TOTP secrets at rest. It is never derived from the JWT
+	// secret, which has a public default and is unused with RS256/EdDSA.
+	var mfaKey []byte
+	if cfg.Auth.MFAEnabled {
+		mfaKey, err = utils.ParseEncryptionKey(cfg.Auth.MFAEncryptionKey)
+		if err != nil {
+			logger.Fatal("Failed to load MFA encryption key (set AUTH_MFA_ENCRYPTION_KEY or AUTH_MFA_ENABLED=false):", err)
+		}
+	}
+
+	// Pagination cursors are signed with their own secret for the same reason
+	if cfg.Server.CursorSecret == "" || cfg.Server.CursorSecret == cfg.JWT.Secret {
+		logger.Fatal("SERVER_CURSOR_SECRET must be set and differ from JWT_SECRET")
+	}
+
+	// Password hasher for new hashes; existing hashes of other algorithms still verify
+	hasher, err := utils.NewPasswordHasher(cfg.Password)
+	if err != nil {
+		logger.Fatal("Failed to initialize password hasher:", err)
+	}
+
+	// Breached password corpus; disabled unless PASSWORD_BREACHED_FILE is set
+	breachedChecker, err := utils.NewBreachedPasswordChecker(cfg.Password)
+	if err != nil {
+		logger.Fatal("Failed to open breached password file:", err)
+	}
+
+	// Initialize mailer
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		logger.Fatal("Failed to initialize mailer:", err)
+	}
+
+	// Storage for uploaded files such as avatars
+	blobStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize storage:", err)
+	}
+
 	// Initialize services
-	userService := service.NewUserService(userRepo, redisClient, cfg.JWT)
-	authService := service.NewAuthService(userRepo, cfg.JWT)
+	lockoutService := service.NewLockoutService(userRepo, throttleRepo, cfg.Lockout)
+	authService := service.NewAuthService(userRepo, tokenRepo, sessionRepo, throttleRepo, lockoutService, hasher, breachedChecker, keys, mail, cfg.JWT, cfg.Auth, cfg.Password)
+	userService := service.NewUserService(userRepo, tokenRepo, sessionRepo, apiKeyRepo, redisClient, hasher, authService, cfg.JWT, cfg.Server.CursorSecret)
+	sessionService := service.NewSessionService(sessionRepo, tokenRepo, cfg.JWT)
+	passwordService := service.NewPasswordService(userRepo, passwordResetRepo, passwordHistoryRepo, tokenRepo, sessionRepo, throttleRepo, lockoutService, sessionService, hasher, breachedChecker, mail, cfg.Auth, cfg.Password, cfg.JWT)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo)
+	mfaService := service.NewMFAService(userRepo, mfaRepo, tokenRepo, throttleRepo, lockoutService, authService, hasher, keys, mfaKey, cfg.Auth)
+	oauthService := service.NewOAuthService(oauth.NewProviders(cfg.OAuth), userRepo, identityRepo, oauthStateRepo, authService, hasher, cfg.OAuth)
+	webAuthnService := service.NewWebAuthnService(webauthn.NewRelyingParty(cfg.WebAuthn), userRepo, webAuthnCredentialRepo, webAuthnChallengeRepo, lockoutService, authService)
+	magicLinkService := service.NewMagicLinkService(userRepo, magicLinkRepo, throttleRepo, lockoutService, authService, keys, mail, cfg.Auth)
+	userImportService := service.NewUserImportService(userRepo, userImportJobRepo, hasher, cfg.Import)
+	avatarService := service.NewAvatarService(userRepo, blobStorage, cfg.Avatar)
+
+	// Import jobs run in memory, so jobs left unfinished by a previous run are failed
+	if err := userImportService.FailInterruptedJobs(); err != nil {
+		logger.Warn("Failed to clean up user import jobs:", err)
+	}
 
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
 	authHandler := handler.NewAuthHandler(authService)
+	passwordHandler := handler.NewPasswordHandler(passwordService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	oauthHandler := handler.NewOAuthHandler(oauthService, cfg.OAuth)
+	magicLinkHandler := handler.NewMagicLinkHandler(magicLinkService)
+	webAuthnHandler := handler.NewWebAuthnHandler(webAuthnService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	adminHandler := handler.NewAdminHandler(userService, lockoutService, authService, userImportService)
+	jwksHandler := handler.NewJWKSHandler(keys)
+	avatarHandler := handler.NewAvatarHandler(avatarService)
+	mediaHandler := handler.NewMediaHandler(blobStorage)
 
 	// Setup router
-	router := setupRouter(cfg, userHandler, authHandler)
+	router := setupRouter(cfg, keys, tokenRepo, apiKeyService, userHandler, authHandler, passwordHandler, mfaHandler, oauthHandler, magicLinkHandler, webAuthnHandler, sessionHandler, apiKeyHandler, adminHandler, jwksHandler, avatarHandler, mediaHandler)
 
 	// Setup server
 	srv := &http.Server{
@@ -107,7 +207,7 @@ func main() {
 	gracefulShutdown(srv)
 }
 
-func setupRouter(cfg *config.Config, userHandler *handler.UserHandler, authHandler *handler.AuthHandler) *gin.Engine {
+func setupRouter(cfg *config.Config, keys *utils.KeySet, tokenRepo repository.TokenRepository, apiKeyService service.APIKeyService, userHandler *handler.UserHandler, authHandler *handler.AuthHandler, passwordHandler *handler.PasswordHandler, mfaHandler *handler.MFAHandler, oauthHandler *handler.OAuthHandler, magicLinkHandler *handler.MagicLinkHandler, webAuthnHandler *handler.WebAuthnHandler, sessionHandler *handler.SessionHandler, apiKeyHandler *handler.APIKeyHandler, adminHandler *handler.AdminHandler, jwksHandler *handler.JWKSHandler, avatarHandler *handler.AvatarHandler, mediaHandler *handler.MediaHandler) *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.GinMode)
 
@@ -125,6 +225,7 @@ func setupRouter(cfg *config.Config, userHandler *handler.UserHandler, authHandl
 	router.Use(middleware.Logger())                                            // 6. Request/Response logging
 	router.Use(middleware.AuditLog())                                          // 7. Security audit logging
 	router.Use(middleware.Recovery())                                          // 8. Panic recovery
+	router.Use(middleware.RequireIfMatch(cfg.Server.RequireIfMatch))           // 9. Optimistic concurrency on configured routes
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -135,6 +236,12 @@ func setupRouter(cfg *config.Config, userHandler *handler.UserHandler, authHandl
 		})
 	})
 
+	// Public keys for verifying our tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// Uploaded files, such as avatars
+	router.GET("/media/*key", mediaHandler.GetMedia)
+
 	// Prometheus metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
@@ -144,27 +251,99 @@ func setupRouter(cfg *config.Config, userHandler *handler.UserHandler, authHandl
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
+		jwtAuth := middleware.JWTAuth(keys, tokenRepo)
+
 		// Auth routes
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", jwtAuth, authHandler.Logout)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", authHandler.ResendVerification)
+			auth.POST("/password/forgot", passwordHandler.ForgotPassword)
+			auth.POST("/password/reset", passwordHandler.ResetPassword)
+
+			// Without MFA, users who had enabled it cannot finish a login until it is turned back on
+			if cfg.Auth.MFAEnabled {
+				auth.POST("/mfa/verify", mfaHandler.Verify)
+				auth.POST("/mfa/enroll", jwtAuth, middleware.RejectImpersonation(), mfaHandler.Enroll)
+				auth.POST("/mfa/confirm", jwtAuth, middleware.RejectImpersonation(), mfaHandler.Confirm)
+				auth.POST("/mfa/disable", jwtAuth, middleware.RejectImpersonation(), mfaHandler.Disable)
+			}
+
+			// Social login
+			auth.GET("/oauth/:provider", oauthHandler.Authorize)
+			auth.GET("/oauth/:provider/callback", oauthHandler.Callback)
+
+			// Passwordless login
+			auth.POST("/magic-link", magicLinkHandler.Send)
+			auth.POST("/magic-link/verify", magicLinkHandler.Verify)
+
+			// Passkey login
+			auth.POST("/passkeys/options", webAuthnHandler.BeginLogin)
+			auth.POST("/passkeys/login", webAuthnHandler.FinishLogin)
 		}
 
-		// Protected routes
+		// Protected routes accept an access token or an API key
 		protected := v1.Group("/")
-		protected.Use(middleware.JWTAuth(cfg.JWT.Secret))
+		protected.Use(middleware.APIKeyAuth(apiKeyService, jwtAuth))
 		{
-			// User routes
+			// User routes (non-admins may only access their own record)
 			users := protected.Group("/users")
 			{
-				users.GET("/profile", userHandler.GetProfile)
-				users.PUT("/profile", userHandler.UpdateProfile)
-				users.GET("/", userHandler.GetUsers)
-				users.GET("/:id", userHandler.GetUser)
-				users.PUT("/:id", userHandler.UpdateUser)
-				users.DELETE("/:id", userHandler.DeleteUser)
+				users.GET("/profile", middleware.RequireAPIKeyScope(domain.PermissionProfileRead), userHandler.GetProfile)
+
+				// Writes can change the email, which leads to a password reset, or remove the
+				// account, so an impersonating admin may only read
+				users.PUT("/profile", middleware.RejectImpersonation(), middleware.RequireAPIKeyScope(domain.PermissionProfileWrite), userHandler.UpdateProfile)
+				users.PATCH("/profile", middleware.RejectImpersonation(), middleware.RequireAPIKeyScope(domain.PermissionProfileWrite), userHandler.PatchProfile)
+				users.POST("/me/avatar", middleware.RejectImpersonation(), middleware.RequireAPIKeyScope(domain.PermissionProfileWrite), avatarHandler.UploadAvatar)
+				users.DELETE("/me/avatar", middleware.RejectImpersonation(), middleware.RequireAPIKeyScope(domain.PermissionProfileWrite), avatarHandler.DeleteAvatar)
+
+				// Credentials and sessions can only be managed with the user's own login
+				me := users.Group("/me", middleware.RejectAPIKey(), middleware.RejectImpersonation())
+				{
+					me.PUT("/password", passwordHandler.ChangePassword)
+					me.GET("/sessions", sessionHandler.ListMySessions)
+					me.DELETE("/sessions", sessionHandler.RevokeMyOtherSessions)
+					me.DELETE("/sessions/:session_id", sessionHandler.RevokeMySession)
+					me.GET("/api-keys", apiKeyHandler.ListMyAPIKeys)
+					me.POST("/api-keys", apiKeyHandler.CreateMyAPIKey)
+					me.DELETE("/api-keys/:key_id", apiKeyHandler.RevokeMyAPIKey)
+					me.GET("/passkeys", webAuthnHandler.ListPasskeys)
+					me.POST("/passkeys/options", webAuthnHandler.BeginRegistration)
+					me.POST("/passkeys", webAuthnHandler.FinishRegistration)
+					me.DELETE("/passkeys/:passkey_id", webAuthnHandler.DeletePasskey)
+				}
+
+				users.GET("/", middleware.RequirePermission(domain.PermissionUsersRead), userHandler.GetUsers)
+				users.GET("/:id", middleware.RequireSelfOrPermission(domain.PermissionProfileRead, domain.PermissionUsersRead), userHandler.GetUser)
+				users.PUT("/:id", middleware.RejectImpersonation(), middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersWrite), userHandler.UpdateUser)
+				users.PATCH("/:id", middleware.RejectImpersonation(), middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersWrite), userHandler.PatchUser)
+				users.DELETE("/:id", middleware.RejectImpersonation(), middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersDelete), userHandler.DeleteUser)
+			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			{
+				admin.GET("/users", middleware.RequirePermission(domain.PermissionUsersDelete), adminHandler.ListUsers)
+				admin.DELETE("/users/:id", middleware.RequirePermission(domain.PermissionUsersDelete), adminHandler.DeleteUser)
+				admin.POST("/users/:id/restore", middleware.RequirePermission(domain.PermissionUsersDelete), adminHandler.RestoreUser)
+				admin.PUT("/users/:id/role", middleware.RequirePermission(domain.PermissionRolesManage), adminHandler.GrantRole)
+				admin.DELETE("/users/:id/role", middleware.RequirePermission(domain.PermissionRolesManage), adminHandler.RevokeRole)
+				admin.POST("/users/import", middleware.RequirePermission(domain.PermissionUsersWrite), adminHandler.ImportUsers)
+				admin.GET("/users/import/:id", middleware.RequirePermission(domain.PermissionUsersWrite), adminHandler.GetImportJob)
+				admin.GET("/users/export", middleware.RequirePermission(domain.PermissionUsersRead), adminHandler.ExportUsers)
+				admin.POST("/users/:id/impersonate", middleware.RequirePermission(domain.PermissionUsersImpersonate), middleware.RejectAPIKey(), middleware.RejectImpersonation(), adminHandler.Impersonate)
+				admin.POST("/users/:id/unlock", middleware.RequirePermission(domain.PermissionUsersUnlock), adminHandler.UnlockUser)
+				admin.GET("/users/:id/sessions", middleware.RequirePermission(domain.PermissionSessionsManage), sessionHandler.ListUserSessions)
+				admin.DELETE("/users/:id/sessions", middleware.RequirePermission(domain.PermissionSessionsManage), sessionHandler.RevokeUserSessions)
+				admin.DELETE("/users/:id/sessions/:session_id", middleware.RequirePermission(domain.PermissionSessionsManage), sessionHandler.RevokeUserSession)
+				admin.GET("/users/:id/api-keys", middleware.RequirePermission(domain.PermissionAPIKeysManage), apiKeyHandler.ListUserAPIKeys)
+				admin.POST("/users/:id/api-keys", middleware.RequirePermission(domain.PermissionAPIKeysManage), middleware.RejectAPIKey(), middleware.RejectImpersonation(), apiKeyHandler.CreateUserAPIKey)
+				admin.DELETE("/users/:id/api-keys/:key_id", middleware.RequirePermission(domain.PermissionAPIKeysManage), apiKeyHandler.RevokeUserAPIKey)
 			}
 		}
 	}
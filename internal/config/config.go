@@ -14,14 +14,25 @@ type Config struct {
 	Redis     RedisConfig     `mapstructure:"redis"`
 	JWT       JWTConfig       `mapstructure:"jwt"`
 	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Lockout   LockoutConfig   `mapstructure:"lockout"`
+	Password  PasswordConfig  `mapstructure:"password"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Mail      MailConfig      `mapstructure:"mail"`
+	OAuth     OAuthConfig     `mapstructure:"oauth"`
+	WebAuthn  WebAuthnConfig  `mapstructure:"webauthn"`
+	Import    ImportConfig    `mapstructure:"import"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Avatar    AvatarConfig    `mapstructure:"avatar"`
 	LogLevel  string          `mapstructure:"log_level"`
 	LogFormat string          `mapstructure:"log_format"`
 }
 
 type ServerConfig struct {
-	Host    string `mapstructure:"host"`
-	Port    string `mapstructure:"port"`
-	GinMode string `mapstructure:"gin_mode"`
+	Host           string   `mapstructure:"host"`
+	Port           string   `mapstructure:"port"`
+	GinMode        string   `mapstructure:"gin_mode"`
+	RequireIfMatch []string `mapstructure:"require_if_match"` // "METHOD /route" writes that return 428 without If-Match
+	CursorSecret   string   `mapstructure:"cursor_secret"`    // Signs pagination cursors; must differ from the JWT secret
 }
 
 type DatabaseConfig struct {
@@ -41,8 +52,92 @@ type RedisConfig struct {
 }
 
 type JWTConfig struct {
-	Secret     string        `mapstructure:"secret"`
-	Expiration time.Duration `mapstructure:"expiration"`
+	Secret            string        `mapstructure:"secret"`
+	Expiration        time.Duration `mapstructure:"expiration"`
+	RefreshExpiration time.Duration `mapstructure:"refresh_expiration"`
+	Algorithm         string        `mapstructure:"algorithm"`        // HS256, RS256 or EdDSA
+	PrivateKeyFile    string        `mapstructure:"private_key_file"` // PEM signing key for RS256/EdDSA
+	PublicKeyFiles    []string      `mapstructure:"public_key_files"` // Extra PEM keys accepted during rotation
+}
+
+type AuthConfig struct {
+	PasswordResetURL            string        `mapstructure:"password_reset_url"` // Frontend page receiving ?token=
+	PasswordResetExpiration     time.Duration `mapstructure:"password_reset_expiration"`
+	PasswordResetRequestLimit   int           `mapstructure:"password_reset_request_limit"` // Reset emails that may be requested per email within the window
+	PasswordResetRequestWindow  time.Duration `mapstructure:"password_reset_request_window"`
+	RequireEmailVerification    bool          `mapstructure:"require_email_verification"` // Block login until the email is verified
+	EmailVerificationURL        string        `mapstructure:"email_verification_url"`     // Frontend page receiving ?token=
+	EmailVerificationExpiration time.Duration `mapstructure:"email_verification_expiration"`
+	VerificationResendCooldown  time.Duration `mapstructure:"verification_resend_cooldown"`
+	MFAEnabled                  bool          `mapstructure:"mfa_enabled"`        // Requires MFAEncryptionKey
+	MFAIssuer                   string        `mapstructure:"mfa_issuer"`         // Shown in authenticator apps
+	MFAEncryptionKey            string        `mapstructure:"mfa_encryption_key"` // Base64 32-byte key for TOTP secrets
+	MFAChallengeExpiration      time.Duration `mapstructure:"mfa_challenge_expiration"`
+	ImpersonationExpiration     time.Duration `mapstructure:"impersonation_expiration"` // Lifetime of admin impersonation tokens
+	MagicLinkURL                string        `mapstructure:"magic_link_url"`           // Frontend page receiving ?token=
+	MagicLinkExpiration         time.Duration `mapstructure:"magic_link_expiration"`
+	MagicLinkRequestLimit       int           `mapstructure:"magic_link_request_limit"` // Links that may be requested per email within the window
+	MagicLinkRequestWindow      time.Duration `mapstructure:"magic_link_request_window"`
+}
+
+type MailConfig struct {
+	Driver   string `mapstructure:"driver"` // smtp or log
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	LogFile  string `mapstructure:"log_file"` // log driver: write emails to this file instead of the log
+}
+
+// OAuthConfig configures sign-in with external identity providers.
+// A provider is enabled when its client ID is set.
+type OAuthConfig struct {
+	RedirectURL     string              `mapstructure:"redirect_url"` // Callback base; "/<provider>/callback" is appended
+	StateExpiration time.Duration       `mapstructure:"state_expiration"`
+	Google          OAuthProviderConfig `mapstructure:"google"`
+	GitHub          OAuthProviderConfig `mapstructure:"github"`
+	OIDC            OAuthProviderConfig `mapstructure:"oidc"` // Generic OpenID Connect provider
+}
+
+type OAuthProviderConfig struct {
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	IssuerURL    string   `mapstructure:"issuer_url"` // OIDC discovery base
+	Scopes       []string `mapstructure:"scopes"`
+	AuthURL      string   `mapstructure:"auth_url"`  // Non-OIDC providers only
+	TokenURL     string   `mapstructure:"token_url"` // Non-OIDC providers only
+	APIURL       string   `mapstructure:"api_url"`   // Non-OIDC providers only
+}
+
+// WebAuthnConfig configures passkey registration and login
+type WebAuthnConfig struct {
+	RPID             string        `mapstructure:"rp_id"`             // Relying party ID, the site's domain without scheme or port
+	RPName           string        `mapstructure:"rp_name"`           // Shown by the authenticator
+	Origins          []string      `mapstructure:"origins"`           // Origins allowed to run the ceremonies, e.g. https://app.example.com
+	Timeout          time.Duration `mapstructure:"timeout"`           // Lifetime of a ceremony challenge
+	UserVerification string        `mapstructure:"user_verification"` // required, preferred or discouraged
+}
+
+// ImportConfig bounds CSV and NDJSON user imports
+type ImportConfig struct {
+	MaxBytes       int64 `mapstructure:"max_bytes"`       // Largest accepted file
+	MaxRows        int   `mapstructure:"max_rows"`        // Most rows in one import
+	AsyncThreshold int   `mapstructure:"async_threshold"` // Imports with more rows run as background jobs
+	BatchSize      int   `mapstructure:"batch_size"`      // Rows looked up and saved per query and transaction
+}
+
+// StorageConfig selects where uploaded files such as avatars are stored
+type StorageConfig struct {
+	Driver    string `mapstructure:"driver"`     // local or memory
+	LocalDir  string `mapstructure:"local_dir"`  // local driver: directory holding the files
+	PublicURL string `mapstructure:"public_url"` // Base URL the stored files are served from
+}
+
+// AvatarConfig bounds avatar uploads
+type AvatarConfig struct {
+	MaxBytes  int64 `mapstructure:"max_bytes"`  // Largest accepted image file
+	MaxPixels int   `mapstructure:"max_pixels"` // Largest accepted width times height, bounds decoding memory
 }
 
 type RateLimitConfig struct {
@@ -50,6 +145,36 @@ type RateLimitConfig struct {
 	Burst int `mapstructure:"burst"` // Maximum burst size
 }
 
+type LockoutConfig struct {
+	MaxAttempts   int           `mapstructure:"max_attempts"`    // Failed logins per account before lockout
+	IPMaxAttempts int           `mapstructure:"ip_max_attempts"` // Failed logins per IP before the IP is blocked
+	Window        time.Duration `mapstructure:"window"`          // Period in which failures are counted
+	Duration      time.Duration `mapstructure:"duration"`        // How long a lockout lasts
+	BackoffBase   time.Duration `mapstructure:"backoff_base"`    // Delay after the first failure, doubled per failure
+	BackoffMax    time.Duration `mapstructure:"backoff_max"`
+}
+
+// PasswordConfig is the password policy enforced on register, change and reset,
+// and the parameters used to hash new passwords
+type PasswordConfig struct {
+	MinLength      int  `mapstructure:"min_length"`
+	MaxLength      int  `mapstructure:"max_length"` // bcrypt ignores bytes after 72
+	RequireUpper   bool `mapstructure:"require_upper"`
+	RequireLower   bool `mapstructure:"require_lower"`
+	RequireNumber  bool `mapstructure:"require_number"`
+	RequireSpecial bool `mapstructure:"require_special"`
+	MinStrength    int  `mapstructure:"min_strength"`  // 1-5, see utils.GetPasswordStrength
+	HistoryCount   int  `mapstructure:"history_count"` // Reject the last N passwords, 0 disables
+
+	HashAlgorithm     string `mapstructure:"hash_algorithm"` // bcrypt or argon2id
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"` // KiB
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+
+	BreachedPasswordFile string `mapstructure:"breached_password_file"` // Sorted SHA-1 HIBP corpus, empty disables
+}
+
 func Load() (*Config, error) {
 	// Load .env file if it exists
 	_ = godotenv.Load()
@@ -91,6 +216,7 @@ func setDefaults() {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.gin_mode", "debug")
+	viper.SetDefault("server.cursor_secret", "")
 
 	// Database defaults
 	viper.SetDefault("database.host", "localhost")
@@ -109,11 +235,94 @@ func setDefaults() {
 	// JWT defaults
 	viper.SetDefault("jwt.secret", "your-super-secret-jwt-key")
 	viper.SetDefault("jwt.expiration", 24*time.Hour)
+	viper.SetDefault("jwt.refresh_expiration", 7*24*time.Hour)
+	viper.SetDefault("jwt.algorithm", "HS256")
 
 	// Rate limit defaults
 	viper.SetDefault("rate_limit.rps", 10)
 	viper.SetDefault("rate_limit.burst", 20)
 
+	// OAuth defaults
+	viper.SetDefault("oauth.redirect_url", "http://localhost:8080/api/v1/auth/oauth")
+	viper.SetDefault("oauth.state_expiration", 10*time.Minute)
+	viper.SetDefault("oauth.google.issuer_url", "https://accounts.google.com")
+	viper.SetDefault("oauth.google.scopes", []string{"openid", "email", "profile"})
+	viper.SetDefault("oauth.github.scopes", []string{"read:user", "user:email"})
+	viper.SetDefault("oauth.github.auth_url", "https://github.com/login/oauth/authorize")
+	viper.SetDefault("oauth.github.token_url", "https://github.com/login/oauth/access_token")
+	viper.SetDefault("oauth.github.api_url", "https://api.github.com")
+	viper.SetDefault("oauth.oidc.scopes", []string{"openid", "email", "profile"})
+
+	// WebAuthn defaults
+	viper.SetDefault("webauthn.rp_id", "localhost")
+	viper.SetDefault("webauthn.rp_name", "GoTemplateStructure")
+	viper.SetDefault("webauthn.origins", []string{"http://localhost:3000"})
+	viper.SetDefault("webauthn.timeout", 5*time.Minute)
+	viper.SetDefault("webauthn.user_verification", "required")
+
+	// Import defaults
+	viper.SetDefault("import.max_bytes", 10<<20)
+	viper.SetDefault("import.max_rows", 20000)
+	viper.SetDefault("import.async_threshold", 500)
+	viper.SetDefault("import.batch_size", 500)
+
+	// Storage defaults
+	viper.SetDefault("storage.driver", "local")
+	viper.SetDefault("storage.local_dir", "./storage")
+	viper.SetDefault("storage.public_url", "http://localhost:8080/media")
+
+	// Avatar defaults
+	viper.SetDefault("avatar.max_bytes", 5<<20)
+	viper.SetDefault("avatar.max_pixels", 40_000_000)
+
+	// Lockout defaults
+	viper.SetDefault("lockout.max_attempts", 5)
+	viper.SetDefault("lockout.ip_max_attempts", 20)
+	viper.SetDefault("lockout.window", 15*time.Minute)
+	viper.SetDefault("lockout.duration", 15*time.Minute)
+	viper.SetDefault("lockout.backoff_base", time.Second)
+	viper.SetDefault("lockout.backoff_max", 30*time.Second)
+
+	// Password policy defaults
+	viper.SetDefault("password.min_length", 8)
+	viper.SetDefault("password.max_length", 72)
+	viper.SetDefault("password.require_upper", true)
+	viper.SetDefault("password.require_lower", true)
+	viper.SetDefault("password.require_number", true)
+	viper.SetDefault("password.require_special", false)
+	viper.SetDefault("password.min_strength", 3)
+	viper.SetDefault("password.history_count", 5)
+	viper.SetDefault("password.hash_algorithm", "argon2id")
+	viper.SetDefault("password.bcrypt_cost", 12)
+	viper.SetDefault("password.argon2_memory", 19456) // OWASP recommended minimum
+	viper.SetDefault("password.argon2_iterations", 2)
+	viper.SetDefault("password.argon2_parallelism", 1)
+	viper.SetDefault("password.breached_password_file", "")
+
+	// Auth defaults
+	viper.SetDefault("auth.password_reset_url", "http://localhost:3000/reset-password")
+	viper.SetDefault("auth.password_reset_expiration", time.Hour)
+	viper.SetDefault("auth.password_reset_request_limit", 3)
+	viper.SetDefault("auth.password_reset_request_window", 15*time.Minute)
+	viper.SetDefault("auth.require_email_verification", false)
+	viper.SetDefault("auth.email_verification_url", "http://localhost:3000/verify-email")
+	viper.SetDefault("auth.email_verification_expiration", 24*time.Hour)
+	viper.SetDefault("auth.verification_resend_cooldown", time.Minute)
+	viper.SetDefault("auth.mfa_enabled", true)
+	viper.SetDefault("auth.mfa_issuer", "GoTemplateStructure")
+	viper.SetDefault("auth.mfa_encryption_key", "")
+	viper.SetDefault("auth.mfa_challenge_expiration", 5*time.Minute)
+	viper.SetDefault("auth.impersonation_expiration", 15*time.Minute)
+	viper.SetDefault("auth.magic_link_url", "http://localhost:3000/magic-link")
+	viper.SetDefault("auth.magic_link_expiration", 10*time.Minute)
+	viper.SetDefault("auth.magic_link_request_limit", 3)
+	viper.SetDefault("auth.magic_link_request_window", 15*time.Minute)
+
+	// Mail defaults
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.port", "587")
+	viper.SetDefault("mail.from", "no-reply@example.com")
+
 	// Logging defaults
 	viper.SetDefault("log_level", "info")
 	viper.SetDefault("log_format", "json")
@@ -124,6 +333,8 @@ func bindEnvVars() {
 	viper.BindEnv("server.host", "SERVER_HOST")
 	viper.BindEnv("server.port", "SERVER_PORT")
 	viper.BindEnv("server.gin_mode", "GIN_MODE")
+	viper.BindEnv("server.require_if_match", "SERVER_REQUIRE_IF_MATCH")
+	viper.BindEnv("server.cursor_secret", "SERVER_CURSOR_SECRET")
 
 	// Database
 	viper.BindEnv("database.host", "DB_HOST")
@@ -140,11 +351,101 @@ func bindEnvVars() {
 
 	// JWT
 	viper.BindEnv("jwt.secret", "JWT_SECRET")
+	viper.BindEnv("jwt.refresh_expiration", "JWT_REFRESH_EXPIRATION")
+	viper.BindEnv("jwt.algorithm", "JWT_ALGORITHM")
+	viper.BindEnv("jwt.private_key_file", "JWT_PRIVATE_KEY_FILE")
+	viper.BindEnv("jwt.public_key_files", "JWT_PUBLIC_KEY_FILES")
 
 	// Rate Limit
 	viper.BindEnv("rate_limit.rps", "RATE_LIMIT_RPS")
 	viper.BindEnv("rate_limit.burst", "RATE_LIMIT_BURST")
 
+	// OAuth
+	viper.BindEnv("oauth.redirect_url", "OAUTH_REDIRECT_URL")
+	viper.BindEnv("oauth.state_expiration", "OAUTH_STATE_EXPIRATION")
+	viper.BindEnv("oauth.google.client_id", "OAUTH_GOOGLE_CLIENT_ID")
+	viper.BindEnv("oauth.google.client_secret", "OAUTH_GOOGLE_CLIENT_SECRET")
+	viper.BindEnv("oauth.github.client_id", "OAUTH_GITHUB_CLIENT_ID")
+	viper.BindEnv("oauth.github.client_secret", "OAUTH_GITHUB_CLIENT_SECRET")
+	viper.BindEnv("oauth.oidc.client_id", "OAUTH_OIDC_CLIENT_ID")
+	viper.BindEnv("oauth.oidc.client_secret", "OAUTH_OIDC_CLIENT_SECRET")
+	viper.BindEnv("oauth.oidc.issuer_url", "OAUTH_OIDC_ISSUER_URL")
+	viper.BindEnv("oauth.oidc.scopes", "OAUTH_OIDC_SCOPES")
+
+	// WebAuthn
+	viper.BindEnv("webauthn.rp_id", "WEBAUTHN_RP_ID")
+	viper.BindEnv("webauthn.rp_name", "WEBAUTHN_RP_NAME")
+	viper.BindEnv("webauthn.origins", "WEBAUTHN_ORIGINS")
+	viper.BindEnv("webauthn.timeout", "WEBAUTHN_TIMEOUT")
+	viper.BindEnv("webauthn.user_verification", "WEBAUTHN_USER_VERIFICATION")
+
+	// Import
+	viper.BindEnv("import.max_bytes", "IMPORT_MAX_BYTES")
+	viper.BindEnv("import.max_rows", "IMPORT_MAX_ROWS")
+	viper.BindEnv("import.async_threshold", "IMPORT_ASYNC_THRESHOLD")
+	viper.BindEnv("import.batch_size", "IMPORT_BATCH_SIZE")
+
+	// Storage
+	viper.BindEnv("storage.driver", "STORAGE_DRIVER")
+	viper.BindEnv("storage.local_dir", "STORAGE_LOCAL_DIR")
+	viper.BindEnv("storage.public_url", "STORAGE_PUBLIC_URL")
+
+	// Avatar
+	viper.BindEnv("avatar.max_bytes", "AVATAR_MAX_BYTES")
+	viper.BindEnv("avatar.max_pixels", "AVATAR_MAX_PIXELS")
+
+	// Lockout
+	viper.BindEnv("lockout.max_attempts", "LOCKOUT_MAX_ATTEMPTS")
+	viper.BindEnv("lockout.ip_max_attempts", "LOCKOUT_IP_MAX_ATTEMPTS")
+	viper.BindEnv("lockout.window", "LOCKOUT_WINDOW")
+	viper.BindEnv("lockout.duration", "LOCKOUT_DURATION")
+	viper.BindEnv("lockout.backoff_base", "LOCKOUT_BACKOFF_BASE")
+	viper.BindEnv("lockout.backoff_max", "LOCKOUT_BACKOFF_MAX")
+
+	// Password policy
+	viper.BindEnv("password.min_length", "PASSWORD_MIN_LENGTH")
+	viper.BindEnv("password.max_length", "PASSWORD_MAX_LENGTH")
+	viper.BindEnv("password.require_upper", "PASSWORD_REQUIRE_UPPER")
+	viper.BindEnv("password.require_lower", "PASSWORD_REQUIRE_LOWER")
+	viper.BindEnv("password.require_number", "PASSWORD_REQUIRE_NUMBER")
+	viper.BindEnv("password.require_special", "PASSWORD_REQUIRE_SPECIAL")
+	viper.BindEnv("password.min_strength", "PASSWORD_MIN_STRENGTH")
+	viper.BindEnv("password.history_count", "PASSWORD_HISTORY_COUNT")
+	viper.BindEnv("password.hash_algorithm", "PASSWORD_HASH_ALGORITHM")
+	viper.BindEnv("password.bcrypt_cost", "PASSWORD_BCRYPT_COST")
+	viper.BindEnv("password.argon2_memory", "PASSWORD_ARGON2_MEMORY")
+	viper.BindEnv("password.argon2_iterations", "PASSWORD_ARGON2_ITERATIONS")
+	viper.BindEnv("password.argon2_parallelism", "PASSWORD_ARGON2_PARALLELISM")
+	viper.BindEnv("password.breached_password_file", "PASSWORD_BREACHED_FILE")
+
+	// Auth
+	viper.BindEnv("auth.password_reset_url", "AUTH_PASSWORD_RESET_URL")
+	viper.BindEnv("auth.password_reset_expiration", "AUTH_PASSWORD_RESET_EXPIRATION")
+	viper.BindEnv("auth.password_reset_request_limit", "AUTH_PASSWORD_RESET_REQUEST_LIMIT")
+	viper.BindEnv("auth.password_reset_request_window", "AUTH_PASSWORD_RESET_REQUEST_WINDOW")
+	viper.BindEnv("auth.require_email_verification", "AUTH_REQUIRE_EMAIL_VERIFICATION")
+	viper.BindEnv("auth.email_verification_url", "AUTH_EMAIL_VERIFICATION_URL")
+	viper.BindEnv("auth.email_verification_expiration", "AUTH_EMAIL_VERIFICATION_EXPIRATION")
+	viper.BindEnv("auth.verification_resend_cooldown", "AUTH_VERIFICATION_RESEND_COOLDOWN")
+	viper.BindEnv("auth.mfa_enabled", "AUTH_MFA_ENABLED")
+	viper.BindEnv("auth.mfa_issuer", "AUTH_MFA_ISSUER")
+	viper.BindEnv("auth.mfa_encryption_key", "AUTH_MFA_ENCRYPTION_KEY")
+	viper.BindEnv("auth.mfa_challenge_expiration", "AUTH_MFA_CHALLENGE_EXPIRATION")
+	viper.BindEnv("auth.impersonation_expiration", "AUTH_IMPERSONATION_EXPIRATION")
+	viper.BindEnv("auth.magic_link_url", "AUTH_MAGIC_LINK_URL")
+	viper.BindEnv("auth.magic_link_expiration", "AUTH_MAGIC_LINK_EXPIRATION")
+	viper.BindEnv("auth.magic_link_request_limit", "AUTH_MAGIC_LINK_REQUEST_LIMIT")
+	viper.BindEnv("auth.magic_link_request_window", "AUTH_MAGIC_LINK_REQUEST_WINDOW")
+
+	// Mail
+	viper.BindEnv("mail.driver", "MAIL_DRIVER")
+	viper.BindEnv("mail.host", "MAIL_HOST")
+	viper.BindEnv("mail.port", "MAIL_PORT")
+	viper.BindEnv("mail.username", "MAIL_USERNAME")
+	viper.BindEnv("mail.password", "MAIL_PASSWORD")
+	viper.BindEnv("mail.from", "MAIL_FROM")
+	viper.BindEnv("mail.log_file", "MAIL_LOG_FILE")
+
 	// Logging
 	viper.BindEnv("log_level", "LOG_LEVEL")
 	viper.BindEnv("log_format", "LOG_FORMAT")
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PermissionList is a list of permissions stored as a comma separated string
+type PermissionList []Permission
+
+// Value implements driver.Valuer
+func (l PermissionList) Value() (driver.Value, error) {
+	parts := make([]string, len(l))
+	for i, p := range l {
+		parts[i] = string(p)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Scan implements sql.Scanner
+func (l *PermissionList) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*l = PermissionList{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PermissionList", value)
+	}
+
+	list := PermissionList{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, Permission(part))
+		}
+	}
+	*l = list
+	return nil
+}
+
+// Contains reports whether the list contains the permission
+func (l PermissionList) Contains(permission Permission) bool {
+	for _, p := range l {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey represents a long-lived credential for scripts and integrations.
+// Only the SHA-256 hash of the key is stored; the prefix identifies it in listings.
+type APIKey struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	Name       string         `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string         `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string         `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	Scopes     PermissionList `json:"scopes" gorm:"type:text"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// TableName specifies the table name for APIKey model
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key.
+// Scopes limit the key to a subset of the owner's role permissions.
+type CreateAPIKeyRequest struct {
+	Name      string       `json:"name" binding:"required,max=100"`
+	Scopes    []Permission `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is returned once when a key is created; the key cannot be retrieved later
+type CreateAPIKeyResponse struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"path"
+	"strconv"
+)
+
+// AvatarSizes are the sizes in pixels of the square variants made of every uploaded
+// avatar. The first, largest one is the user's avatar.
+var AvatarSizes = []int{256, 128, 64}
+
+// Avatar is an uploaded avatar
+type Avatar struct {
+	URL      string            `json:"url"`      // Largest variant, also returned as the user's avatar
+	Variants map[string]string `json:"variants"` // URL of every variant by size, e.g. "64"
+}
+
+// AvatarVariantKey is the storage key of a variant. Variants are stored next to
+// each other as <dir>/<size><ext>, where key is the key of any one of them.
+func AvatarVariantKey(key string, size int) string {
+	return path.Join(path.Dir(key), strconv.Itoa(size)+path.Ext(key))
+}
+
+// AvatarVariantKeys are the storage keys of every variant of an avatar
+func AvatarVariantKeys(key string) []string {
+	keys := make([]string, 0, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		keys = append(keys, AvatarVariantKey(key, size))
+	}
+	return keys
+}
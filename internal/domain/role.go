@@ -0,0 +1,82 @@
+package domain
+
+// Role represents a user's role in the system
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+// Permission represents an action that can be granted to a role
+type Permission string
+
+const (
+	PermissionUsersRead        Permission = "users:read"
+	PermissionUsersWrite       Permission = "users:write"
+	PermissionUsersDelete      Permission = "users:delete"
+	PermissionUsersUnlock      Permission = "users:unlock"
+	PermissionUsersImpersonate Permission = "users:impersonate"
+	PermissionRolesManage      Permission = "roles:manage"
+	PermissionSessionsManage   Permission = "sessions:manage"
+	PermissionAPIKeysManage    Permission = "api_keys:manage"
+
+	// Profile permissions only cover the user's own account. Every role has them,
+	// so any user can scope a personal API key to their own profile.
+	PermissionProfileRead  Permission = "profile:read"
+	PermissionProfileWrite Permission = "profile:write"
+)
+
+// rolePermissions is the permission matrix. Regular users have no global
+// permissions and may only access their own record. Impersonation is admin-only.
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermissionProfileRead,
+		PermissionProfileWrite,
+	},
+	RoleSupport: {
+		PermissionProfileRead,
+		PermissionProfileWrite,
+		PermissionUsersRead,
+		PermissionUsersUnlock,
+	},
+	RoleAdmin: {
+		PermissionProfileRead,
+		PermissionProfileWrite,
+		PermissionUsersRead,
+		PermissionUsersWrite,
+		PermissionUsersDelete,
+		PermissionUsersUnlock,
+		PermissionUsersImpersonate,
+		PermissionRolesManage,
+		PermissionSessionsManage,
+		PermissionAPIKeysManage,
+	},
+}
+
+// IsValid reports whether the role is a known role
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// HasPermission reports whether the role is granted the permission
+func (r Role) HasPermission(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// UpdateRoleRequest represents the request payload for granting a role
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=user support admin"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Session represents a login on a device. Its ID is the refresh token family,
+// so revoking a session invalidates every token issued from it.
+type Session struct {
+	ID         string     `json:"id" gorm:"type:varchar(36);primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar(512)"`
+	IP         string     `json:"ip" gorm:"type:varchar(45)"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current" gorm:"-"` // The session of the requesting token
+}
+
+// TableName specifies the table name for Session model
+func (Session) TableName() string {
+	return "sessions"
+}
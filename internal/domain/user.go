@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,17 +9,24 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"` // Never return password in JSON
-	FirstName string         `json:"first_name"`
-	LastName  string         `json:"last_name"`
-	Avatar    string         `json:"avatar"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // Soft delete
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	Email             string         `json:"email" gorm:"uniqueIndex:idx_users_email,where:deleted_at IS NULL;not null"`
+	Username          string         `json:"username" gorm:"uniqueIndex:idx_users_username,where:deleted_at IS NULL;not null"`
+	Password          string         `json:"-" gorm:"not null"` // Never return password in JSON
+	FirstName         string         `json:"first_name"`
+	LastName          string         `json:"last_name"`
+	Avatar            string         `json:"avatar"`                                         // Set by uploading an avatar, never by the client directly
+	AvatarKey         string         `json:"-" gorm:"type:varchar(255);not null;default:''"` // Storage key of the uploaded avatar, see AvatarVariantKeys
+	Role              Role           `json:"role" gorm:"type:varchar(20);not null;default:user"`
+	IsActive          bool           `json:"is_active" gorm:"default:true"`
+	EmailVerifiedAt   *time.Time     `json:"email_verified_at"`
+	MFAEnabled        bool           `json:"mfa_enabled" gorm:"column:mfa_enabled;default:false"`
+	MFASecret         string         `json:"-" gorm:"column:mfa_secret"`        // AES-GCM encrypted TOTP secret
+	PasswordChangedAt *time.Time     `json:"-"`                                 // Reset tokens issued before this are invalid
+	Version           uint           `json:"version" gorm:"not null;default:1"` // Incremented on every update, served as the ETag
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"` // Soft delete, set only on users listed with deleted=include or only
 }
 
 // TableName specifies the table name for User model
@@ -30,7 +38,7 @@ func (User) TableName() string {
 type CreateUserRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Username  string `json:"username" binding:"required,min=3,max=50"`
-	Password  string `json:"password" binding:"required,min=6"`
+	Password  string `json:"password" binding:"required"` // Checked against the password policy
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 }
@@ -41,7 +49,54 @@ type UpdateUserRequest struct {
 	Username  string `json:"username" binding:"omitempty,min=3,max=50"`
 	FirstName string `json:"first_name" binding:"omitempty"`
 	LastName  string `json:"last_name" binding:"omitempty"`
-	Avatar    string `json:"avatar" binding:"omitempty"`
+}
+
+// PatchUserRequest is a JSON Merge Patch (RFC 7396) of a user: absent keys are
+// left alone and null clears a field
+type PatchUserRequest struct {
+	Email     PatchString `json:"email" swaggertype:"string"`
+	Username  PatchString `json:"username" swaggertype:"string"`
+	FirstName PatchString `json:"first_name" swaggertype:"string"`
+	LastName  PatchString `json:"last_name" swaggertype:"string"`
+	IsActive  PatchBool   `json:"is_active" swaggertype:"boolean"` // Not allowed on your own account
+}
+
+// PatchString is a string member of a merge patch
+type PatchString struct {
+	Set   bool // The key was present
+	Null  bool
+	Value string
+}
+
+func (p *PatchString) UnmarshalJSON(data []byte) error {
+	p.Set = true
+	if string(data) == "null" {
+		p.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &p.Value)
+}
+
+// PatchBool is a boolean member of a merge patch
+type PatchBool struct {
+	Set   bool // The key was present
+	Null  bool
+	Value bool
+}
+
+func (p *PatchBool) UnmarshalJSON(data []byte) error {
+	p.Set = true
+	if string(data) == "null" {
+		p.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &p.Value)
+}
+
+// FieldError describes why a field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // LoginRequest represents the request payload for user login
@@ -50,12 +105,23 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// AuthResponse represents the response for authentication
+// ClientInfo describes the client making an authentication request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// AuthResponse represents the response for authentication.
+// Tokens are omitted when the user still has to verify their email. When MFA is
+// enabled, login only returns MFARequired and a short-lived MFAToken that is
+// exchanged for tokens at /auth/mfa/verify.
 type AuthResponse struct {
-	User         *User  `json:"user"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
+	User         *User  `json:"user,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
 }
 
 // RefreshTokenRequest represents the request payload for token refresh
@@ -63,6 +129,35 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// VerifyEmailRequest represents the request payload for email verification
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest represents the request payload for resending the verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkRequest represents the request payload for requesting a passwordless login
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkVerifyRequest represents the request payload for completing a passwordless login.
+// Either the token from the emailed link, or the email address and the emailed code are required.
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+	Email string `json:"email" binding:"omitempty,email"`
+	Code  string `json:"code" binding:"omitempty,len=6,numeric"`
+}
+
+// LogoutRequest represents the request payload for logout.
+// The refresh token is optional; when present it is revoked together with the access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // APIResponse represents a standard API response
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -79,8 +174,19 @@ type PaginationResponse struct {
 	TotalPages int   `json:"total_pages"`
 }
 
-// UserListResponse represents the response for user list
+// CursorPaginationResponse represents cursor pagination metadata. Empty cursors
+// mean there is no page in that direction.
+type CursorPaginationResponse struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"` // Only counted when requested
+}
+
+// UserListResponse represents the response for user list. Pagination is set in
+// offset mode and CursorPagination in cursor mode.
 type UserListResponse struct {
-	Users      []User              `json:"users"`
-	Pagination *PaginationResponse `json:"pagination"`
+	Users            []User                    `json:"users"`
+	Pagination       *PaginationResponse       `json:"pagination,omitempty"`
+	CursorPagination *CursorPaginationResponse `json:"cursor_pagination,omitempty"`
 }
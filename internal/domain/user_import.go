@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// UserFileFormat is the file format of a user import or export
+type UserFileFormat string
+
+const (
+	UserFileCSV    UserFileFormat = "csv"
+	UserFileNDJSON UserFileFormat = "ndjson" // One JSON object per line
+	UserFileJSON   UserFileFormat = "json"   // {"users": [...]}, import only
+)
+
+// IsValid reports whether the format is supported for both import and export
+func (f UserFileFormat) IsValid() bool {
+	return f == UserFileCSV || f == UserFileNDJSON
+}
+
+// IsImportable reports whether users can be imported in the format
+func (f UserFileFormat) IsImportable() bool {
+	return f.IsValid() || f == UserFileJSON
+}
+
+// DuplicatePolicy decides what an import does with rows whose email or username
+// belongs to an existing user
+type DuplicatePolicy string
+
+const (
+	DuplicateSkip   DuplicatePolicy = "skip"   // Leave the existing user unchanged
+	DuplicateUpdate DuplicatePolicy = "update" // Update the existing user with the same email
+	DuplicateFail   DuplicatePolicy = "fail"   // Import nothing if any row is a duplicate
+)
+
+// IsValid reports whether the policy is one of the known values
+func (p DuplicatePolicy) IsValid() bool {
+	return p == DuplicateSkip || p == DuplicateUpdate || p == DuplicateFail
+}
+
+// UserImportRow is a user in a CSV, NDJSON or JSON import. CSV files have a header
+// row with these names; email and username are required. Users without a password
+// hash get a password nobody knows and set one through the password reset flow.
+type UserImportRow struct {
+	Email         string `json:"email"`
+	Username      string `json:"username"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	PasswordHash  string `json:"password_hash"` // Any format supported by utils.PasswordHasher
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// UserImportColumns are the CSV columns of a user import
+var UserImportColumns = map[string]bool{
+	"email":          true,
+	"username":       true,
+	"first_name":     true,
+	"last_name":      true,
+	"password_hash":  true,
+	"email_verified": true,
+}
+
+// UserImportOptions configures a user import
+type UserImportOptions struct {
+	Format          UserFileFormat
+	DuplicatePolicy DuplicatePolicy
+	DryRun          bool // Validate and report what would happen without writing
+	Async           bool // Run as a background job regardless of size
+}
+
+// UserImportRowStatus is the outcome of a row. In a dry run it is the outcome the
+// row would have had.
+type UserImportRowStatus string
+
+const (
+	ImportRowCreated   UserImportRowStatus = "created"
+	ImportRowUpdated   UserImportRowStatus = "updated"
+	ImportRowSkipped   UserImportRowStatus = "skipped"   // Duplicate with the skip policy, or not imported because the import failed
+	ImportRowDuplicate UserImportRowStatus = "duplicate" // Duplicate with the fail policy, or conflicting with another user on update
+	ImportRowInvalid   UserImportRowStatus = "invalid"
+	ImportRowFailed    UserImportRowStatus = "failed" // Valid, but could not be saved
+)
+
+// UserImportRowResult reports the outcome of one row of an import
+type UserImportRowResult struct {
+	Line   int                 `json:"line"` // Line in the file, the CSV header being line 1; position in users for JSON
+	Email  string              `json:"email,omitempty"`
+	Status UserImportRowStatus `json:"status"`
+	UserID uint                `json:"user_id,omitempty"`
+	Errors []FieldError        `json:"errors,omitempty"`
+}
+
+// UserImportResults are the row results of an import job, stored as JSON
+type UserImportResults []UserImportRowResult
+
+// Value implements driver.Valuer
+func (r UserImportResults) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	encoded, err := json.Marshal(r)
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (r *UserImportResults) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = UserImportResults{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case []byte:
+		return json.Unmarshal(v, r)
+	default:
+		return fmt.Errorf("cannot scan %T into UserImportResults", value)
+	}
+}
+
+// UserImportJobStatus is the state of an import job
+type UserImportJobStatus string
+
+const (
+	ImportJobPending   UserImportJobStatus = "pending"
+	ImportJobRunning   UserImportJobStatus = "running"
+	ImportJobCompleted UserImportJobStatus = "completed"
+	ImportJobFailed    UserImportJobStatus = "failed"
+)
+
+// UserImportJob tracks a user import. Small imports finish within the request;
+// large ones run in the background and are polled through the status endpoint.
+type UserImportJob struct {
+	ID              string              `json:"id" gorm:"type:varchar(36);primaryKey"`
+	ActorID         uint                `json:"actor_id" gorm:"not null;index"`
+	Format          UserFileFormat      `json:"format" gorm:"type:varchar(10);not null"`
+	DuplicatePolicy DuplicatePolicy     `json:"on_duplicate" gorm:"type:varchar(10);not null"`
+	DryRun          bool                `json:"dry_run" gorm:"not null;default:false"`
+	Status          UserImportJobStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	TotalRows       int                 `json:"total_rows"`
+	ProcessedRows   int                 `json:"processed_rows"`
+	Created         int                 `json:"created"`
+	Updated         int                 `json:"updated"`
+	Skipped         int                 `json:"skipped"`
+	Duplicates      int                 `json:"duplicates"`
+	Invalid         int                 `json:"invalid"`
+	Failed          int                 `json:"failed"`
+	Error           string              `json:"error,omitempty"`
+	Results         UserImportResults   `json:"results" gorm:"type:jsonb"` // Filled when the job finishes
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	StartedAt       *time.Time          `json:"started_at"`
+	FinishedAt      *time.Time          `json:"finished_at"`
+}
+
+// TableName specifies the table name for UserImportJob model
+func (UserImportJob) TableName() string {
+	return "user_import_jobs"
+}
+
+// Finished reports whether the job has completed or failed
+func (j *UserImportJob) Finished() bool {
+	return j.Status == ImportJobCompleted || j.Status == ImportJobFailed
+}
+
+// UserExportRow is a user in a CSV or NDJSON export. The CSV columns are the
+// JSON names in the same order.
+type UserExportRow struct {
+	ID            uint      `json:"id"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Role          Role      `json:"role"`
+	IsActive      bool      `json:"is_active"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// NewUserExportRow selects the exported fields of a user
+func NewUserExportRow(user *User) UserExportRow {
+	return UserExportRow{
+		ID:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Role:          user.Role,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+}
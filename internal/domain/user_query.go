@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// UserSortFields are the fields the user list can be sorted by
+var UserSortFields = map[string]bool{
+	"id":         true,
+	"email":      true,
+	"username":   true,
+	"first_name": true,
+	"last_name":  true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// DeletedFilter selects soft-deleted users in the user list
+type DeletedFilter string
+
+const (
+	DeletedExclude DeletedFilter = ""        // Only users that are not deleted
+	DeletedInclude DeletedFilter = "include" // Deleted and not deleted users
+	DeletedOnly    DeletedFilter = "only"    // Only deleted users
+)
+
+// IsValid reports whether the filter is one of the known values
+func (f DeletedFilter) IsValid() bool {
+	return f == DeletedExclude || f == DeletedInclude || f == DeletedOnly
+}
+
+// UserQuery filters and sorts the user list. Zero values do not filter.
+type UserQuery struct {
+	Deleted       DeletedFilter
+	IsActive      *bool
+	CreatedAfter  *time.Time // Inclusive
+	CreatedBefore *time.Time // Exclusive
+	EmailDomain   string     // Lowercase domain after the "@"
+	Search        string     // Matched against username, email, first and last name
+	Sort          []UserSort // Applied in order, then by id
+}
+
+// UserSort orders the user list by one of UserSortFields
+type UserSort struct {
+	Field string
+	Desc  bool
+}
+
+// UserKeyset is a position in the user list ordered by (created_at, id), used
+// for cursor pagination
+type UserKeyset struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uint      `json:"i"`
+	Before    bool      `json:"b,omitempty"` // Read the page ending before the position instead of starting after it
+}
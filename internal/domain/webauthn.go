@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"go-template-structure/pkg/webauthn"
+)
+
+// WebAuthnCredential is a passkey registered by a user. The sign count is the
+// last signature counter reported by the authenticator, used to detect clones.
+type WebAuthnCredential struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	Name         string     `json:"name" gorm:"type:varchar(100);not null"`
+	CredentialID string     `json:"credential_id" gorm:"type:varchar(1400);uniqueIndex;not null"` // base64url
+	PublicKey    []byte     `json:"-" gorm:"not null"`                                            // COSE_Key
+	SignCount    uint32     `json:"sign_count" gorm:"not null;default:0"`
+	AAGUID       string     `json:"aaguid" gorm:"type:varchar(36)"` // Authenticator model, all zeros when not disclosed
+	Transports   string     `json:"transports" gorm:"type:varchar(255)"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for WebAuthnCredential model
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// RegisterPasskeyRequest represents the request payload for completing a passkey registration
+type RegisterPasskeyRequest struct {
+	Name       string                       `json:"name" binding:"max=100"`
+	Credential webauthn.AttestationResponse `json:"credential" binding:"required"`
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateMyAPIKey godoc
+// @Summary Create API key
+// @Description Create an API key for the current user. The key is only shown in this response.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.CreateAPIKeyRequest true "API key label, scopes and expiration"
+// @Success 201 {object} domain.APIResponse{data=domain.CreateAPIKeyResponse}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/api-keys [post]
+func (h *APIKeyHandler) CreateMyAPIKey(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	h.create(c, userID, userID)
+}
+
+// ListMyAPIKeys godoc
+// @Summary List API keys
+// @Description Get the API keys of the current user
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse{data=[]domain.APIKey}
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/api-keys [get]
+func (h *APIKeyHandler) ListMyAPIKeys(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	h.list(c, userID)
+}
+
+// RevokeMyAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke one of the current user's API keys
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param key_id path int true "API key ID"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/api-keys/{key_id} [delete]
+func (h *APIKeyHandler) RevokeMyAPIKey(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	h.revoke(c, userID, userID)
+}
+
+// CreateUserAPIKey godoc
+// @Summary Create API key for user
+// @Description Create an API key for any user, e.g. a service account (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body domain.CreateAPIKeyRequest true "API key label, scopes and expiration"
+// @Success 201 {object} domain.APIResponse{data=domain.CreateAPIKeyResponse}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /admin/users/{id}/api-keys [post]
+func (h *APIKeyHandler) CreateUserAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	h.create(c, utils.GetUserIDFromContext(c), uint(id))
+}
+
+// ListUserAPIKeys godoc
+// @Summary List user API keys
+// @Description Get the API keys of any user (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} domain.APIResponse{data=[]domain.APIKey}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /admin/users/{id}/api-keys [get]
+func (h *APIKeyHandler) ListUserAPIKeys(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	h.list(c, uint(id))
+}
+
+// RevokeUserAPIKey godoc
+// @Summary Revoke user API key
+// @Description Revoke an API key of any user (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param key_id path int true "API key ID"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /admin/users/{id}/api-keys/{key_id} [delete]
+func (h *APIKeyHandler) RevokeUserAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	h.revoke(c, utils.GetUserIDFromContext(c), uint(id))
+}
+
+func (h *APIKeyHandler) create(c *gin.Context, actorID, userID uint) {
+	var req domain.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	created, err := h.apiKeyService.Create(actorID, userID, &req)
+	if err != nil {
+		switch {
+		case err.Error() == "user not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		case strings.HasPrefix(err.Error(), "invalid scope"), err.Error() == "expiration must be in the future":
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create API key", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create API key", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "api_key_created")
+	c.JSON(http.StatusCreated, domain.APIResponse{
+		Success: true,
+		Message: "API key created successfully",
+		Data:    created,
+	})
+}
+
+func (h *APIKeyHandler) list(c *gin.Context, userID uint) {
+	keys, err := h.apiKeyService.List(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get API keys", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "API keys retrieved successfully", keys)
+}
+
+func (h *APIKeyHandler) revoke(c *gin.Context, actorID, userID uint) {
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID", err.Error())
+		return
+	}
+
+	err = h.apiKeyService.Revoke(actorID, userID, uint(keyID))
+	if err != nil {
+		if err.Error() == "api key not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "API key not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "api_key_revoked")
+	utils.SuccessResponse(c, "API key revoked successfully", nil)
+}
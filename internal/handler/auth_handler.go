@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"go-template-structure/internal/domain"
 	"go-template-structure/internal/service"
@@ -28,7 +31,7 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 // @Produce json
 // @Param user body domain.CreateUserRequest true "User registration data"
 // @Success 201 {object} domain.APIResponse{data=domain.AuthResponse}
-// @Failure 400 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse{error=[]utils.PasswordViolation}
 // @Failure 409 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /auth/register [post]
@@ -39,8 +42,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.Register(&req)
+	authResponse, err := h.authService.Register(&req, clientInfo(c))
 	if err != nil {
+		if passwordPolicyFailed(c, err) {
+			return
+		}
 		if err.Error() == "user with this email or username already exists" {
 			utils.ErrorResponse(c, http.StatusConflict, "User already exists", err.Error())
 			return
@@ -66,6 +72,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} domain.APIResponse{data=domain.AuthResponse}
 // @Failure 400 {object} domain.APIResponse
 // @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -75,12 +83,19 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.Login(&req)
+	authResponse, err := h.authService.Login(&req, clientInfo(c))
 	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
 		if err.Error() == "invalid email or password" || err.Error() == "user account is inactive" {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication failed", err.Error())
 			return
 		}
+		if err.Error() == "email_not_verified" {
+			utils.ErrorResponse(c, http.StatusForbidden, "Email address has not been verified", err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login", err.Error())
 		return
 	}
@@ -90,7 +105,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 // RefreshToken godoc
 // @Summary Refresh access token
-// @Description Get new access token using refresh token
+// @Description Get new access token using refresh token. Refresh tokens are single-use and rotated on every call.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -107,8 +122,13 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.RefreshToken(req.RefreshToken)
+	authResponse, err := h.authService.RefreshToken(req.RefreshToken, clientInfo(c))
 	if err != nil {
+		if err.Error() == "refresh token reuse detected" {
+			c.Set("audit_event", "refresh_token_reuse")
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Token refresh failed", err.Error())
+			return
+		}
 		if err.Error() == "invalid refresh token" || err.Error() == "user not found" || err.Error() == "user account is inactive" {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "Token refresh failed", err.Error())
 			return
@@ -119,3 +139,126 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	utils.SuccessResponse(c, "Token refreshed successfully", authResponse)
 }
+
+// Logout godoc
+// @Summary User logout
+// @Description Revoke the current access token, its token family and, optionally, the given refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token body domain.LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req domain.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+			return
+		}
+	}
+
+	claims := utils.GetTokenClaimsFromContext(c)
+
+	err := h.authService.Logout(claims, req.RefreshToken)
+	if err != nil {
+		if err.Error() == "invalid access token" || err.Error() == "invalid refresh token" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Logout failed", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "logout")
+	utils.SuccessResponse(c, "Logout successful", nil)
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the user's email address with the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body domain.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req domain.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	err := h.authService.VerifyEmail(req.Token)
+	if err != nil {
+		if err.Error() == "invalid or expired verification token" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Email verification failed", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify email", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "email_verified")
+	utils.SuccessResponse(c, "Email verified successfully", nil)
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Send a new verification link. The response is the same whether or not the account exists.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body domain.ResendVerificationRequest true "Email address"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req domain.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	err := h.authService.ResendVerification(req.Email)
+	if err != nil {
+		if err.Error() == "verification email recently sent" {
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resend verification email", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "If the account exists and is not verified, a verification email has been sent", nil)
+}
+
+// loginThrottled writes a 429 with Retry-After and returns true if err is a lockout
+func loginThrottled(c *gin.Context, err error) bool {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	c.Set("audit_event", "login_throttled")
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many login attempts", err.Error())
+	return true
+}
+
+// clientInfo describes the client of the request for session records and lockouts
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
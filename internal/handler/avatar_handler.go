@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAvatarFormParts bounds the multipart parts read while looking for the file
+const maxAvatarFormParts = 10
+
+type AvatarHandler struct {
+	avatarService service.AvatarService
+}
+
+func NewAvatarHandler(avatarService service.AvatarService) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+	}
+}
+
+// UploadAvatar godoc
+// @Summary Upload avatar
+// @Description Upload a JPEG, PNG or GIF image as the current user's avatar. The type is detected from the file contents.
+// @Description The image is cropped to a square and stored as 256, 128 and 64 pixel variants without its metadata (EXIF). The previous avatar is deleted.
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param avatar formData file true "Image file, at most AVATAR_MAX_BYTES"
+// @Success 200 {object} domain.APIResponse{data=domain.Avatar}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 413 {object} domain.APIResponse
+// @Failure 415 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/avatar [post]
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	// The file is streamed to the service, which enforces the size limit, instead
+	// of being buffered by ParseMultipartForm
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported media type", "use multipart/form-data with an avatar file")
+		return
+	}
+
+	var file io.Reader
+	for i := 0; i < maxAvatarFormParts; i++ {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FormName() == "avatar" && part.FileName() != "" {
+			file = part
+			break
+		}
+	}
+	if file == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", "avatar file is required")
+		return
+	}
+
+	avatar, err := h.avatarService.UploadAvatar(utils.GetUserIDFromContext(c), file)
+	if err != nil {
+		switch {
+		case err.Error() == "avatar file too large", errors.Is(err, utils.ErrImageTooLarge):
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Invalid avatar", err.Error())
+		case errors.Is(err, utils.ErrUnsupportedImage):
+			utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Invalid avatar", "avatar must be a JPEG, PNG or GIF image")
+		case err.Error() == "version mismatch":
+			utils.ErrorResponse(c, http.StatusConflict, "Failed to upload avatar", "profile was updated at the same time, try again")
+		case err.Error() == "user not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to upload avatar", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "avatar_uploaded")
+	utils.SuccessResponse(c, "Avatar uploaded successfully", avatar)
+}
+
+// DeleteAvatar godoc
+// @Summary Delete avatar
+// @Description Remove the current user's avatar and delete its stored files
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/avatar [delete]
+func (h *AvatarHandler) DeleteAvatar(c *gin.Context) {
+	if err := h.avatarService.DeleteAvatar(utils.GetUserIDFromContext(c)); err != nil {
+		switch err.Error() {
+		case "version mismatch":
+			utils.ErrorResponse(c, http.StatusConflict, "Failed to delete avatar", "profile was updated at the same time, try again")
+		case "user not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete avatar", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "avatar_deleted")
+	utils.SuccessResponse(c, "Avatar deleted successfully", nil)
+}
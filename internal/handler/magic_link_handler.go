@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService service.MagicLinkService
+}
+
+func NewMagicLinkHandler(magicLinkService service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+	}
+}
+
+// Send godoc
+// @Summary Request passwordless login
+// @Description Email a single-use sign-in link and 6-digit code. The response is the same whether or not the account exists.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body domain.MagicLinkRequest true "Email address"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/magic-link [post]
+func (h *MagicLinkHandler) Send(c *gin.Context) {
+	var req domain.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	err := h.magicLinkService.Send(req.Email)
+	if err != nil {
+		if err.Error() == "too many magic link requests" {
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send magic link", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "If the account exists, a sign-in link has been sent", nil)
+}
+
+// Verify godoc
+// @Summary Complete passwordless login
+// @Description Exchange the token from the emailed link, or the email address and code, for tokens. Returns an MFA challenge when MFA is enabled.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.MagicLinkVerifyRequest true "Link token, or email and code"
+// @Success 200 {object} domain.APIResponse{data=domain.AuthResponse}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/magic-link/verify [post]
+func (h *MagicLinkHandler) Verify(c *gin.Context) {
+	var req domain.MagicLinkVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	authResponse, err := h.magicLinkService.Verify(&req, clientInfo(c))
+	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+
+		switch err.Error() {
+		case "token or email and code are required":
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		case "invalid or expired magic link", "user account is inactive":
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication failed", err.Error())
+		case "too many magic link attempts":
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many login attempts", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Login successful", authResponse)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"go-template-structure/internal/interfaces"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mediaContentTypes are the file types served from storage, by extension
+var mediaContentTypes = map[string]string{
+	".jpg": "image/jpeg",
+	".png": "image/png",
+}
+
+type MediaHandler struct {
+	storage interfaces.BlobStorage
+}
+
+func NewMediaHandler(storage interfaces.BlobStorage) *MediaHandler {
+	return &MediaHandler{
+		storage: storage,
+	}
+}
+
+// GetMedia godoc
+// @Summary Get stored file
+// @Description Serve a stored file such as an avatar variant. Keys are never reused, so files may be cached indefinitely.
+// @Tags media
+// @Produce png,jpeg
+// @Param key path string true "Storage key, e.g. avatars/1/<id>/256.jpg"
+// @Success 200 {file} file
+// @Failure 404 {object} domain.APIResponse
+// @Router /media/{key} [get]
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	contentType, ok := mediaContentTypes[path.Ext(key)]
+	if !ok {
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found", "file not found")
+		return
+	}
+
+	file, err := h.storage.Open(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			utils.ErrorResponse(c, http.StatusNotFound, "File not found", "file not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to read file", err.Error())
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, -1, contentType, file, map[string]string{
+		"Cache-Control":           "public, max-age=31536000, immutable",
+		"Content-Security-Policy": "default-src 'none'",
+	})
+}
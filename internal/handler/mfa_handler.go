@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	mfaService service.MFAService
+}
+
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// Enroll godoc
+// @Summary Start MFA enrollment
+// @Description Generate a TOTP secret, otpauth URI and one-time recovery codes. MFA is enabled after confirmation.
+// @Tags mfa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse{data=domain.MFAEnrollResponse}
+// @Failure 401 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	enrollment, err := h.mfaService.Enroll(userID)
+	if err != nil {
+		if err.Error() == "mfa already enabled" {
+			utils.ErrorResponse(c, http.StatusConflict, "MFA enrollment failed", err.Error())
+			return
+		}
+		if err.Error() == "user not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start MFA enrollment", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "Scan the QR code and confirm with a code from your authenticator app", enrollment)
+}
+
+// Confirm godoc
+// @Summary Confirm MFA enrollment
+// @Description Enable MFA by submitting a code from the authenticator app
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.MFAConfirmRequest true "TOTP code"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req domain.MFAConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	err := h.mfaService.ConfirmEnrollment(userID, req.Code, clientInfo(c))
+	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "invalid mfa code", "mfa enrollment not started":
+			utils.ErrorResponse(c, http.StatusBadRequest, "MFA confirmation failed", err.Error())
+		case "mfa already enabled":
+			utils.ErrorResponse(c, http.StatusConflict, "MFA confirmation failed", err.Error())
+		case "user not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to confirm MFA enrollment", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "mfa_enabled")
+	utils.SuccessResponse(c, "MFA enabled successfully", nil)
+}
+
+// Disable godoc
+// @Summary Disable MFA
+// @Description Turn MFA off. Requires the current password and a TOTP or recovery code.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.MFADisableRequest true "Password and MFA code"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req domain.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	err := h.mfaService.Disable(userID, req.Password, req.Code, clientInfo(c))
+	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "invalid password", "invalid mfa code":
+			utils.ErrorResponse(c, http.StatusUnauthorized, "MFA disable failed", err.Error())
+		case "mfa not enabled":
+			utils.ErrorResponse(c, http.StatusBadRequest, "MFA disable failed", err.Error())
+		case "user not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable MFA", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "mfa_disabled")
+	utils.SuccessResponse(c, "MFA disabled successfully", nil)
+}
+
+// Verify godoc
+// @Summary Complete MFA login
+// @Description Exchange the MFA challenge token returned by login and a TOTP or recovery code for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.MFAVerifyRequest true "MFA challenge token and code"
+// @Success 200 {object} domain.APIResponse{data=domain.AuthResponse}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req domain.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	authResponse, err := h.mfaService.Verify(req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "invalid mfa code":
+			c.Set("audit_event", "mfa_failed")
+			utils.ErrorResponse(c, http.StatusUnauthorized, "MFA verification failed", err.Error())
+		case "invalid or expired mfa token", "user account is inactive":
+			utils.ErrorResponse(c, http.StatusUnauthorized, "MFA verification failed", err.Error())
+		case "too many mfa attempts":
+			c.Set("audit_event", "mfa_failed")
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "MFA verification failed", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify MFA", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Login successful", authResponse)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateCookie holds the state of the flow started by this browser until the callback
+const (
+	oauthStateCookie     = "oauth_state"
+	oauthStateCookiePath = "/api/v1/auth/oauth"
+)
+
+type OAuthHandler struct {
+	oauthService service.OAuthService
+	oauthConfig  config.OAuthConfig
+}
+
+func NewOAuthHandler(oauthService service.OAuthService, oauthConfig config.OAuthConfig) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		oauthConfig:  oauthConfig,
+	}
+}
+
+// Authorize godoc
+// @Summary Start social login
+// @Description Redirect to the identity provider (google, github or oidc) using the authorization code flow with PKCE. Sets a short-lived oauth_state cookie that the callback must present.
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/oauth/{provider} [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	authURL, state, err := h.oauthService.AuthorizationURL(c.Param("provider"))
+	if err != nil {
+		if err.Error() == "unknown provider" {
+			utils.ErrorResponse(c, http.StatusNotFound, "Provider not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start login", err.Error())
+		return
+	}
+
+	// Lax lets the cookie ride along on the provider's top-level redirect back to the callback
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, int(h.oauthConfig.StateExpiration.Seconds()), oauthStateCookiePath, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback godoc
+// @Summary Complete social login
+// @Description Exchange the authorization code, create or link the user and return tokens. Requires the oauth_state cookie set by the authorization request. Returns an MFA challenge when MFA is enabled.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param state query string true "State from the authorization request"
+// @Param code query string true "Authorization code"
+// @Success 200 {object} domain.APIResponse{data=domain.AuthResponse}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	// The provider reports a denied consent or other failure through the error parameter
+	if providerErr := c.Query("error"); providerErr != "" {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication failed", providerErr)
+		return
+	}
+
+	// The state cookie is single-use like the server-side state
+	boundState, _ := c.Cookie(oauthStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, "", -1, oauthStateCookiePath, "", c.Request.TLS != nil, true)
+
+	authResponse, err := h.oauthService.Callback(c.Param("provider"), c.Query("state"), boundState, c.Query("code"), clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "unknown provider":
+			utils.ErrorResponse(c, http.StatusNotFound, "Provider not found", err.Error())
+		case "invalid oauth state":
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		case "oauth authentication failed", "user account is inactive", "user not found":
+			c.Set("audit_event", "oauth_login_failed")
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication failed", err.Error())
+		case "oauth email not verified", "email_not_verified":
+			utils.ErrorResponse(c, http.StatusForbidden, "Email address has not been verified", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "oauth_login")
+	utils.SuccessResponse(c, "Login successful", authResponse)
+}
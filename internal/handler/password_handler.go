@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordHandler struct {
+	passwordService service.PasswordService
+}
+
+func NewPasswordHandler(passwordService service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+	}
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Email a single-use password reset link. Requests are rate limited per email, and the response is the same whether or not the account exists.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var req domain.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := h.passwordService.ForgotPassword(req.Email); err != nil {
+		if err.Error() == "too many password reset requests" {
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to request password reset", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "password_reset_requested")
+	utils.SuccessResponse(c, "If the account exists, a password reset email has been sent", nil)
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password using a reset token. All existing sessions are revoked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse{error=[]utils.PasswordViolation}
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/password/reset [post]
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req domain.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := h.passwordService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if passwordPolicyFailed(c, err) {
+			return
+		}
+		if err.Error() == "invalid or expired reset token" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Password reset failed", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "password_reset")
+	utils.SuccessResponse(c, "Password has been reset successfully", nil)
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the current user's password. Requires the current password; wrong attempts count toward the account lockout and every other session is signed out.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse{error=[]utils.PasswordViolation}
+// @Failure 401 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/password [put]
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req domain.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := h.passwordService.ChangePassword(userID, currentSessionID(c), req.CurrentPassword, req.NewPassword, clientInfo(c)); err != nil {
+		if loginThrottled(c, err) || passwordPolicyFailed(c, err) {
+			return
+		}
+		if err.Error() == "current password is incorrect" {
+			c.Set("audit_event", "password_change_failed")
+			utils.ErrorResponse(c, http.StatusBadRequest, "Password change failed", err.Error())
+			return
+		}
+		if err.Error() == "user not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to change password", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "password_changed")
+	utils.SuccessResponse(c, "Password changed successfully", nil)
+}
+
+// passwordPolicyFailed writes the violated policy rules and returns true if err is a policy error
+func passwordPolicyFailed(c *gin.Context, err error) bool {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet the policy", policyErr.Violations)
+	return true
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// ListMySessions godoc
+// @Summary List my sessions
+// @Description Get the active sessions (devices) of the current user
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse{data=[]domain.Session}
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/sessions [get]
+func (h *SessionHandler) ListMySessions(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	sessions, err := h.sessionService.List(userID, currentSessionID(c))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get sessions", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeMySession godoc
+// @Summary Revoke a session
+// @Description Sign out one of the current user's sessions
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/sessions/{session_id} [delete]
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	h.revokeSession(c, userID, userID, c.Param("session_id"))
+}
+
+// RevokeMyOtherSessions godoc
+// @Summary Sign out everywhere else
+// @Description Sign out every session of the current user except the one making the request
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/sessions [delete]
+func (h *SessionHandler) RevokeMyOtherSessions(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	h.revokeSessions(c, userID, userID, currentSessionID(c))
+}
+
+// ListUserSessions godoc
+// @Summary List user sessions
+// @Description Get the active sessions of any user (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} domain.APIResponse{data=[]domain.Session}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /admin/users/{id}/sessions [get]
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	sessions, err := h.sessionService.List(uint(id), "")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get sessions", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke a user session
+// @Description Sign out one session of any user (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /admin/users/{id}/sessions/{session_id} [delete]
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	h.revokeSession(c, utils.GetUserIDFromContext(c), uint(id), c.Param("session_id"))
+}
+
+// RevokeUserSessions godoc
+// @Summary Revoke all user sessions
+// @Description Sign out every session of any user (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /admin/users/{id}/sessions [delete]
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	h.revokeSessions(c, utils.GetUserIDFromContext(c), uint(id), "")
+}
+
+func (h *SessionHandler) revokeSession(c *gin.Context, actorID, userID uint, sessionID string) {
+	err := h.sessionService.Revoke(actorID, userID, sessionID)
+	if err != nil {
+		if err.Error() == "session not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "Session not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "session_revoked")
+	utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
+
+func (h *SessionHandler) revokeSessions(c *gin.Context, actorID, userID uint, keepSessionID string) {
+	count, err := h.sessionService.RevokeOthers(actorID, userID, keepSessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "sessions_revoked")
+	utils.SuccessResponse(c, "Sessions revoked successfully", gin.H{"revoked": count})
+}
+
+// currentSessionID returns the session (token family) of the authenticated request
+func currentSessionID(c *gin.Context) string {
+	if claims := utils.GetTokenClaimsFromContext(c); claims != nil {
+		return claims.FamilyID
+	}
+	return ""
+}
@@ -1,8 +1,18 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"go-template-structure/internal/domain"
 	"go-template-structure/internal/service"
@@ -29,6 +39,7 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} domain.APIResponse{data=domain.User}
+// @Header 200 {string} ETag "Version of the user, for If-Match"
 // @Failure 401 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /users/profile [get]
@@ -41,6 +52,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
+	setETag(c, user)
 	utils.SuccessResponse(c, "Profile retrieved successfully", user)
 }
 
@@ -52,9 +64,13 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param user body domain.UpdateUserRequest true "User update data"
+// @Param If-Match header string false "ETag from a previous response"
 // @Success 200 {object} domain.APIResponse{data=domain.User}
 // @Failure 400 {object} domain.APIResponse
 // @Failure 401 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 412 {object} domain.APIResponse
+// @Failure 428 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /users/profile [put]
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
@@ -66,33 +82,100 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateProfile(userID, &req)
+	user, err := h.userService.UpdateProfile(userID, &req, ifMatchVersions(c))
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile", err.Error())
+		updateFailed(c, "Failed to update profile", err)
 		return
 	}
 
+	setETag(c, user)
+	utils.SuccessResponse(c, "Profile updated successfully", user)
+}
+
+// PatchProfile godoc
+// @Summary Patch user profile
+// @Description Partially update the current user with a JSON Merge Patch (RFC 7396). Absent keys are left alone and null clears a field.
+// @Tags users
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param user body domain.PatchUserRequest true "Fields to change"
+// @Param If-Match header string false "ETag from a previous response"
+// @Success 200 {object} domain.APIResponse{data=domain.User}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 412 {object} domain.APIResponse
+// @Failure 415 {object} domain.APIResponse
+// @Failure 428 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/profile [patch]
+func (h *UserHandler) PatchProfile(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+
+	var patch domain.PatchUserRequest
+	if !bindMergePatch(c, &patch) {
+		return
+	}
+
+	user, err := h.userService.PatchProfile(userID, &patch, ifMatchVersions(c))
+	if err != nil {
+		updateFailed(c, "Failed to update profile", err)
+		return
+	}
+
+	setETag(c, user)
 	utils.SuccessResponse(c, "Profile updated successfully", user)
 }
 
 // GetUsers godoc
 // @Summary Get users list
-// @Description Get paginated list of users
+// @Description Get paginated list of users, optionally filtered and sorted. Unknown parameters are rejected.
+// @Description Passing cursor (empty for the first page) switches to cursor pagination, which returns
+// @Description next/prev cursors and RFC 8288 Link headers instead of page numbers and only supports sorting by created_at.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param is_active query bool false "Filter by active status"
+// @Param created_after query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param email_domain query string false "Email domain, e.g. example.com"
+// @Param q query string false "Search username, email and name"
+// @Param sort query string false "Comma-separated fields, prefix with - for descending, e.g. -created_at,username"
+// @Param cursor query string false "Cursor from a previous page, empty for the first page"
+// @Param include_total query bool false "Count matching users in cursor mode" default(false)
 // @Success 200 {object} domain.APIResponse{data=domain.UserListResponse}
+// @Failure 400 {object} domain.APIResponse
 // @Failure 401 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
+	listUsers(c, h.userService, userQueryParams)
+}
+
+// listUsers serves a user list in offset or cursor mode, accepting the given
+// query parameters
+func listUsers(c *gin.Context, userService service.UserService, params map[string]bool) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	users, pagination, err := h.userService.GetUsers(page, limit)
+	query, err := parseUserQuery(c, params)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query", err.Error())
+		return
+	}
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		listUsersByCursor(c, userService, query, cursor, limit)
+		return
+	}
+
+	users, pagination, err := userService.GetUsers(query, page, limit)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get users", err.Error())
 		return
@@ -106,6 +189,59 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 	utils.SuccessResponse(c, "Users retrieved successfully", response)
 }
 
+// listUsersByCursor serves listUsers in cursor mode
+func listUsersByCursor(c *gin.Context, userService service.UserService, query *domain.UserQuery, cursor string, limit int) {
+	if _, ok := c.GetQuery("page"); ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query", "page cannot be combined with cursor")
+		return
+	}
+
+	includeTotal := false
+	if v := c.Query("include_total"); v != "" {
+		var err error
+		if includeTotal, err = strconv.ParseBool(v); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query", "invalid include_total: "+v)
+			return
+		}
+	}
+
+	users, pagination, err := userService.GetUsersByCursor(query, cursor, limit, includeTotal)
+	if err != nil {
+		switch err.Error() {
+		case "invalid cursor", "cursor pagination only supports sorting by created_at":
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get users", err.Error())
+		}
+		return
+	}
+
+	links := []string{cursorLink(c, "", "first")}
+	if pagination.PrevCursor != "" {
+		links = append(links, cursorLink(c, pagination.PrevCursor, "prev"))
+	}
+	if pagination.NextCursor != "" {
+		links = append(links, cursorLink(c, pagination.NextCursor, "next"))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+
+	response := domain.UserListResponse{
+		Users:            users,
+		CursorPagination: pagination,
+	}
+
+	utils.SuccessResponse(c, "Users retrieved successfully", response)
+}
+
+// cursorLink formats an RFC 8288 link to the current request with another cursor.
+// The target is relative so it never depends on the Host header.
+func cursorLink(c *gin.Context, cursor, rel string) string {
+	values := c.Request.URL.Query()
+	values.Set("cursor", cursor)
+	target := url.URL{Path: c.Request.URL.Path, RawQuery: values.Encode()}
+	return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+}
+
 // GetUser godoc
 // @Summary Get user by ID
 // @Description Get user details by ID
@@ -115,6 +251,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "User ID"
 // @Success 200 {object} domain.APIResponse{data=domain.User}
+// @Header 200 {string} ETag "Version of the user, for If-Match"
 // @Failure 400 {object} domain.APIResponse
 // @Failure 401 {object} domain.APIResponse
 // @Failure 404 {object} domain.APIResponse
@@ -138,6 +275,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
+	setETag(c, user)
 	utils.SuccessResponse(c, "User retrieved successfully", user)
 }
 
@@ -150,10 +288,14 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "User ID"
 // @Param user body domain.UpdateUserRequest true "User update data"
+// @Param If-Match header string false "ETag from a previous response"
 // @Success 200 {object} domain.APIResponse{data=domain.User}
 // @Failure 400 {object} domain.APIResponse
 // @Failure 401 {object} domain.APIResponse
 // @Failure 404 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 412 {object} domain.APIResponse
+// @Failure 428 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
@@ -170,19 +312,127 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	user, err := h.userService.UpdateUser(uint(id), &req, ifMatchVersions(c))
 	if err != nil {
-		if err.Error() == "user not found" {
-			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
-			return
-		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update user", err.Error())
+		updateFailed(c, "Failed to update user", err)
+		return
+	}
+
+	setETag(c, user)
+	utils.SuccessResponse(c, "User updated successfully", user)
+}
+
+// PatchUser godoc
+// @Summary Patch user
+// @Description Partially update a user with a JSON Merge Patch (RFC 7396). Absent keys are left alone and null clears a field. is_active cannot be changed on your own account.
+// @Tags users
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param user body domain.PatchUserRequest true "Fields to change"
+// @Param If-Match header string false "ETag from a previous response"
+// @Success 200 {object} domain.APIResponse{data=domain.User}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 412 {object} domain.APIResponse
+// @Failure 415 {object} domain.APIResponse
+// @Failure 428 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/{id} [patch]
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return
+	}
+
+	var patch domain.PatchUserRequest
+	if !bindMergePatch(c, &patch) {
+		return
+	}
+
+	user, err := h.userService.PatchUser(utils.GetUserIDFromContext(c), uint(id), &patch, ifMatchVersions(c))
+	if err != nil {
+		updateFailed(c, "Failed to update user", err)
 		return
 	}
 
+	if patch.IsActive.Set {
+		c.Set("audit_event", "user_active_changed")
+	}
+	setETag(c, user)
 	utils.SuccessResponse(c, "User updated successfully", user)
 }
 
+// maxPatchSize bounds the body of a merge patch
+const maxPatchSize = 64 << 10
+
+// bindMergePatch decodes a JSON Merge Patch body, rejecting unknown keys. It writes
+// the error response and returns false if the body is not a valid patch.
+func bindMergePatch(c *gin.Context, patch interface{}) bool {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported media type", "use application/merge-patch+json")
+		return false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchSize+1))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return false
+	}
+	if len(body) > maxPatchSize {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Invalid request data", "request body is too large")
+		return false
+	}
+
+	// Any other JSON value would replace the whole user
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '{' {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", "merge patch must be a JSON object")
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return false
+	}
+	if decoder.More() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", "unexpected data after the merge patch")
+		return false
+	}
+
+	return true
+}
+
+// updateFailed writes the error response of a failed update or patch
+func updateFailed(c *gin.Context, message string, err error) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", validationErr.Fields)
+		return
+	}
+
+	switch err.Error() {
+	case "user not found":
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+	case "version mismatch":
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed", err.Error())
+	case "cannot change is_active of your own account":
+		utils.ErrorResponse(c, http.StatusForbidden, message, err.Error())
+	case "email already in use", "username already in use":
+		utils.ErrorResponse(c, http.StatusConflict, message, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
 // DeleteUser godoc
 // @Summary Delete user
 // @Description Delete user by ID
@@ -191,10 +441,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "User ID"
+// @Param If-Match header string false "ETag from a previous response"
 // @Success 200 {object} domain.APIResponse
 // @Failure 400 {object} domain.APIResponse
 // @Failure 401 {object} domain.APIResponse
 // @Failure 404 {object} domain.APIResponse
+// @Failure 412 {object} domain.APIResponse
+// @Failure 428 {object} domain.APIResponse
 // @Failure 500 {object} domain.APIResponse
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
@@ -205,15 +458,174 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = h.userService.DeleteUser(uint(id))
+	err = h.userService.DeleteUser(uint(id), ifMatchVersions(c))
 	if err != nil {
-		if err.Error() == "user not found" {
+		switch err.Error() {
+		case "user not found":
 			utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
-			return
+		case "version mismatch":
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete user", err.Error())
 		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete user", err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, "User deleted successfully", nil)
 }
+
+// setETag sets the ETag of a user response to the user's version
+func setETag(c *gin.Context, user *domain.User) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, user.Version))
+}
+
+// ifMatchVersions parses the If-Match header into user versions. It returns nil
+// when the header is absent or "*", which place no condition on the write. Other
+// tags, including weak ones, can never match and are dropped.
+func ifMatchVersions(c *gin.Context) []uint {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []uint{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32); err == nil {
+			versions = append(versions, uint(version))
+		}
+	}
+	return versions
+}
+
+// userQueryParams are the query parameters accepted by GetUsers
+var userQueryParams = map[string]bool{
+	"page":           true,
+	"limit":          true,
+	"is_active":      true,
+	"created_after":  true,
+	"created_before": true,
+	"email_domain":   true,
+	"q":              true,
+	"sort":           true,
+	"cursor":         true,
+	"include_total":  true,
+}
+
+// adminUserQueryParams are the query parameters accepted by the admin user list,
+// which can include soft-deleted users
+var adminUserQueryParams = withParams(userQueryParams, "deleted")
+
+// userExportParams are the query parameters accepted by the user export. It has
+// the filters of the user list, but no pagination or sort.
+var userExportParams = map[string]bool{
+	"format":         true,
+	"is_active":      true,
+	"created_after":  true,
+	"created_before": true,
+	"email_domain":   true,
+	"q":              true,
+}
+
+func withParams(params map[string]bool, extra ...string) map[string]bool {
+	combined := make(map[string]bool, len(params)+len(extra))
+	for name := range params {
+		combined[name] = true
+	}
+	for _, name := range extra {
+		combined[name] = true
+	}
+	return combined
+}
+
+var emailDomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// maxSearchLength bounds the free-text search term
+const maxSearchLength = 100
+
+// parseUserQuery builds the user list filters from the query string, rejecting
+// parameters that are not in params
+func parseUserQuery(c *gin.Context, params map[string]bool) (*domain.UserQuery, error) {
+	values, err := url.ParseQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("malformed query string: %w", err)
+	}
+	for name, v := range values {
+		if !params[name] {
+			return nil, fmt.Errorf("unknown query parameter: %s", name)
+		}
+		if len(v) > 1 {
+			return nil, fmt.Errorf("query parameter %s must be given once", name)
+		}
+	}
+
+	query := &domain.UserQuery{Deleted: domain.DeletedFilter(values.Get("deleted"))}
+	if !query.Deleted.IsValid() {
+		return nil, fmt.Errorf("invalid deleted: %s", values.Get("deleted"))
+	}
+
+	if v := values.Get("is_active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid is_active: %s", v)
+		}
+		query.IsActive = &active
+	}
+
+	for name, target := range map[string]**time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+	} {
+		if v := values.Get(name); v != "" {
+			t, err := parseQueryTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, v)
+			}
+			*target = &t
+		}
+	}
+
+	if v := values.Get("email_domain"); v != "" {
+		domainName := strings.ToLower(strings.TrimPrefix(v, "@"))
+		if len(domainName) > 253 || !emailDomainPattern.MatchString(domainName) {
+			return nil, fmt.Errorf("invalid email_domain: %s", v)
+		}
+		query.EmailDomain = domainName
+	}
+
+	query.Search = strings.TrimSpace(values.Get("q"))
+	if len(query.Search) > maxSearchLength {
+		return nil, fmt.Errorf("q must be at most %d characters", maxSearchLength)
+	}
+
+	if v := values.Get("sort"); v != "" {
+		seen := make(map[string]bool)
+		for _, field := range strings.Split(v, ",") {
+			sort := domain.UserSort{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(sort.Field, "-") {
+				sort.Field, sort.Desc = sort.Field[1:], true
+			}
+			if !domain.UserSortFields[sort.Field] {
+				return nil, fmt.Errorf("invalid sort field: %s", field)
+			}
+			if seen[sort.Field] {
+				return nil, fmt.Errorf("duplicate sort field: %s", sort.Field)
+			}
+			seen[sort.Field] = true
+			query.Sort = append(query.Sort, sort)
+		}
+	}
+
+	return query, nil
+}
+
+// parseQueryTime accepts an RFC 3339 timestamp or a date, which means midnight UTC
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
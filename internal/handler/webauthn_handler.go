@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+	"go-template-structure/pkg/webauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebAuthnHandler struct {
+	webAuthnService service.WebAuthnService
+}
+
+func NewWebAuthnHandler(webAuthnService service.WebAuthnService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webAuthnService: webAuthnService,
+	}
+}
+
+// BeginRegistration godoc
+// @Summary Start passkey registration
+// @Description Get the options to pass to navigator.credentials.create(). Binary values are base64url encoded.
+// @Tags passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse{data=webauthn.CreationOptions}
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/passkeys/options [post]
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start passkey registration", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "Passkey registration started", options)
+}
+
+// FinishRegistration godoc
+// @Summary Register passkey
+// @Description Verify the credential returned by navigator.credentials.create() and store the passkey
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.RegisterPasskeyRequest true "Passkey name and credential"
+// @Success 201 {object} domain.APIResponse{data=domain.WebAuthnCredential}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 409 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/passkeys [post]
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req domain.RegisterPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid or expired challenge", "invalid passkey":
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to register passkey", err.Error())
+		case "passkey already registered":
+			utils.ErrorResponse(c, http.StatusConflict, "Failed to register passkey", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register passkey", err.Error())
+		}
+		return
+	}
+
+	c.Set("audit_event", "passkey_registered")
+	c.JSON(http.StatusCreated, domain.APIResponse{
+		Success: true,
+		Message: "Passkey registered successfully",
+		Data:    credential,
+	})
+}
+
+// ListPasskeys godoc
+// @Summary List my passkeys
+// @Description Get the passkeys registered by the current user
+// @Tags passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.APIResponse{data=[]domain.WebAuthnCredential}
+// @Failure 401 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/passkeys [get]
+func (h *WebAuthnHandler) ListPasskeys(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	credentials, err := h.webAuthnService.ListCredentials(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get passkeys", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "Passkeys retrieved successfully", credentials)
+}
+
+// DeletePasskey godoc
+// @Summary Remove a passkey
+// @Description Remove one of the current user's passkeys
+// @Tags passkeys
+// @Produce json
+// @Security BearerAuth
+// @Param passkey_id path int true "Passkey ID"
+// @Success 200 {object} domain.APIResponse
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 404 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /users/me/passkeys/{passkey_id} [delete]
+func (h *WebAuthnHandler) DeletePasskey(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("passkey_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid passkey ID", err.Error())
+		return
+	}
+
+	if err := h.webAuthnService.DeleteCredential(userID, uint(id)); err != nil {
+		if err.Error() == "passkey not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "Passkey not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove passkey", err.Error())
+		return
+	}
+
+	c.Set("audit_event", "passkey_removed")
+	utils.SuccessResponse(c, "Passkey removed successfully", nil)
+}
+
+// BeginLogin godoc
+// @Summary Start passkey login
+// @Description Get the options to pass to navigator.credentials.get(). Binary values are base64url encoded.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} domain.APIResponse{data=webauthn.RequestOptions}
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/passkeys/options [post]
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	options, err := h.webAuthnService.BeginLogin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start passkey login", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "Passkey login started", options)
+}
+
+// FinishLogin godoc
+// @Summary Login with passkey
+// @Description Verify the credential returned by navigator.credentials.get() and return tokens. Returns an MFA challenge when MFA is enabled.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credential body webauthn.AssertionResponse true "Credential"
+// @Success 200 {object} domain.APIResponse{data=domain.AuthResponse}
+// @Failure 400 {object} domain.APIResponse
+// @Failure 401 {object} domain.APIResponse
+// @Failure 403 {object} domain.APIResponse
+// @Failure 429 {object} domain.APIResponse
+// @Failure 500 {object} domain.APIResponse
+// @Router /auth/passkeys/login [post]
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req webauthn.AssertionResponse
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	authResponse, err := h.webAuthnService.FinishLogin(&req, clientInfo(c))
+	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+
+		switch err.Error() {
+		case "invalid or expired challenge":
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		case "invalid passkey", "user account is inactive":
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication failed", err.Error())
+		case "email_not_verified":
+			utils.ErrorResponse(c, http.StatusForbidden, "Email address has not been verified", err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to login", err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Login successful", authResponse)
+}
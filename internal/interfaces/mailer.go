@@ -0,0 +1,8 @@
+package interfaces
+
+import "context"
+
+// Mailer defines methods for sending transactional emails
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
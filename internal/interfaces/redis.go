@@ -2,12 +2,24 @@ package interfaces
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrKeyNotFound is returned by Get when the key does not exist or has expired
+var ErrKeyNotFound = errors.New("key not found")
+
 // RedisInterface defines methods for Redis operations
 type RedisInterface interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	// SetNX atomically sets key only if it does not exist. It reports whether the
+	// value was set.
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
 	Get(ctx context.Context, key string) (string, error)
 	Del(ctx context.Context, keys ...string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	// CompareAndSwap atomically sets key to value if it currently holds old. It
+	// reports whether the value was set.
+	CompareAndSwap(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error)
 }
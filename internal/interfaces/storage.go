@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+	"io"
+)
+
+// BlobStorage stores files that are served to clients, such as avatars.
+// Keys are slash-separated paths whose extension decides the content type.
+type BlobStorage interface {
+	Put(ctx context.Context, key string, body io.Reader) error
+	// Open returns an error wrapping fs.ErrNotExist if the key is not stored
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds if the key is not stored
+	Delete(ctx context.Context, key string) error
+	// URL is the public URL the key is served from
+	URL(key string) string
+}
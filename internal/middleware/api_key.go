@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyAuth authenticates requests carrying an API key in the "X-API-Key" header or
+// as "Authorization: ApiKey <key>". Requests without an API key are passed to next,
+// which is normally JWTAuth. The same user context keys as JWTAuth are set, and the
+// key's scopes further restrict RequirePermission.
+func APIKeyAuth(apiKeyService service.APIKeyService, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := apiKeyFromRequest(c)
+		if rawKey == "" {
+			next(c)
+			return
+		}
+
+		key, user, err := apiKeyService.Authenticate(rawKey)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAPIKey) || errors.Is(err, service.ErrAPIKeyOwnerInactive) {
+				c.Set("audit_event", "invalid_api_key")
+				utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid API key", err.Error())
+			} else {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify API key", err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("user_email", user.Email)
+		c.Set("user_role", user.Role)
+		c.Set("api_key_id", key.ID)
+		c.Set("api_key_scopes", key.Scopes)
+
+		c.Next()
+	}
+}
+
+// RejectAPIKey blocks routes that must only be used with a user's own login,
+// such as managing credentials and sessions
+func RejectAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key_id"); ok {
+			c.Set("audit_event", "access_denied")
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied", "api keys cannot access this resource")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "ApiKey" {
+		return strings.TrimSpace(parts[1])
+	}
+
+	return ""
+}
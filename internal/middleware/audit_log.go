@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"go-template-structure/pkg/logger"
@@ -15,20 +16,24 @@ func AuditLog() gin.HandlerFunc {
 		path := c.Request.URL.Path
 		method := c.Request.Method
 
-		// Get user info if authenticated
-		userID, _ := c.Get("user_id")
-		email, _ := c.Get("email")
 		requestID, _ := c.Get("RequestID")
 
 		// Process request
 		c.Next()
 
+		// Get user info if authenticated (set by auth middleware during c.Next)
+		userID, _ := c.Get("user_id")
+		email, _ := c.Get("user_email")
+		event, _ := c.Get("audit_event")
+		actorID, _ := c.Get("actor_id")
+		actorEmail, _ := c.Get("actor_email")
+
 		// Calculate latency
 		latency := time.Since(start)
 		statusCode := c.Writer.Status()
 
-		// Only log important actions
-		if shouldAuditLog(method, path, statusCode) {
+		// Only log important actions; every impersonated request is logged
+		if actorID != nil || shouldAuditLog(method, path, statusCode) {
 			logData := map[string]interface{}{
 				"request_id": requestID,
 				"method":     method,
@@ -46,6 +51,13 @@ func AuditLog() gin.HandlerFunc {
 			if email != nil {
 				logData["email"] = email
 			}
+			if event != nil {
+				logData["event"] = event
+			}
+			if actorID != nil {
+				logData["actor_id"] = actorID
+				logData["actor_email"] = actorEmail
+			}
 
 			// Log based on status
 			if statusCode >= 500 {
@@ -61,8 +73,8 @@ func AuditLog() gin.HandlerFunc {
 
 // shouldAuditLog determines if the request should be logged
 func shouldAuditLog(method, path string, status int) bool {
-	// Log all POST, PUT, DELETE requests (data modifications)
-	if method == "POST" || method == "PUT" || method == "DELETE" {
+	// Log all POST, PUT, PATCH, DELETE requests (data modifications)
+	if method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE" {
 		return true
 	}
 
@@ -77,6 +89,17 @@ func shouldAuditLog(method, path string, status int) bool {
 		"/api/v1/auth/register",
 		"/api/v1/auth/refresh",
 		"/api/v1/auth/logout",
+		"/api/v1/auth/password/forgot",
+		"/api/v1/auth/password/reset",
+		"/api/v1/auth/verify-email",
+		"/api/v1/auth/verify-email/resend",
+		"/api/v1/auth/mfa/verify",
+		"/api/v1/auth/mfa/enroll",
+		"/api/v1/auth/mfa/confirm",
+		"/api/v1/auth/mfa/disable",
+		"/api/v1/auth/magic-link",
+		"/api/v1/auth/magic-link/verify",
+		"/api/v1/auth/passkeys/login",
 	}
 	for _, authPath := range authPaths {
 		if path == authPath {
@@ -84,6 +107,11 @@ func shouldAuditLog(method, path string, status int) bool {
 		}
 	}
 
+	// Social login redirects and callbacks include the provider name in the path
+	if strings.HasPrefix(path, "/api/v1/auth/oauth/") {
+		return true
+	}
+
 	// Don't log health checks and swagger
 	if path == "/health" || path == "/swagger/index.html" {
 		return false
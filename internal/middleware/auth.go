@@ -4,13 +4,17 @@ import (
 	"net/http"
 	"strings"
 
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
 	"go-template-structure/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// JWTAuth middleware for JWT authentication
-func JWTAuth(secretKey string) gin.HandlerFunc {
+// JWTAuth middleware for JWT authentication.
+// Only access tokens are accepted. Tokens that were revoked through logout, or whose
+// token family was revoked, are rejected even if they have not expired yet.
+func JWTAuth(keys *utils.KeySet, tokenRepo repository.TokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -30,17 +34,84 @@ func JWTAuth(secretKey string) gin.HandlerFunc {
 		token := tokenParts[1]
 
 		// Validate JWT token
-		claims, err := utils.ValidateJWT(token, secretKey)
+		claims, err := keys.ValidateToken(token)
 		if err != nil {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", err.Error())
 			c.Abort()
 			return
 		}
 
+		// Refresh tokens must only be presented to /auth/refresh
+		if claims.TokenUse != utils.TokenUseAccess {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", "token is not an access token")
+			c.Abort()
+			return
+		}
+
+		// Tokens without an ID cannot be revoked, so they are not accepted
+		if claims.ID == "" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", "token is missing jti claim")
+			c.Abort()
+			return
+		}
+
+		// Check the revocation list
+		revoked, err := isRevoked(tokenRepo, claims)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify token", err.Error())
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.Set("audit_event", "revoked_token_used")
+			c.Set("user_id", claims.UserID)
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked", "token has been revoked")
+			c.Abort()
+			return
+		}
+
 		// Set user information in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("user_role", domain.Role(claims.Role))
+		c.Set("token_claims", claims)
+
+		// Impersonation tokens also carry the admin acting as the user
+		if claims.Actor != nil {
+			c.Set("actor_id", claims.Actor.UserID)
+			c.Set("actor_email", claims.Actor.Email)
+		}
 
 		c.Next()
 	}
 }
+
+// isRevoked reports whether the token itself, its token family or all of the
+// user's tokens were revoked
+func isRevoked(tokenRepo repository.TokenRepository, claims *utils.JWTClaims) (bool, error) {
+	revoked, err := tokenRepo.IsTokenRevoked(claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	if claims.FamilyID != "" {
+		revoked, err = tokenRepo.IsFamilyRevoked(claims.FamilyID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+
+	// Signing the impersonating admin out everywhere also ends the impersonation
+	if claims.Actor != nil {
+		revoked, err = tokenRepo.IsUserTokenRevoked(claims.Actor.UserID, claims.IssuedAt.Time)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	return tokenRepo.IsUserTokenRevoked(claims.UserID, claims.IssuedAt.Time)
+}
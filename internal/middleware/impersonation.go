@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejectImpersonation blocks sensitive operations, such as changing credentials,
+// while an admin is impersonating the user
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("actor_id"); ok {
+			c.Set("audit_event", "access_denied")
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied", "not allowed while impersonating a user")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
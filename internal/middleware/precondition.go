@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireIfMatch rejects requests to the given routes that do not send If-Match,
+// so clients cannot overwrite changes they have not seen. Routes are the method and
+// the registered path, e.g. "PUT /api/v1/users/:id".
+func RequireIfMatch(routes []string) gin.HandlerFunc {
+	required := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if method, path, ok := strings.Cut(strings.TrimSpace(route), " "); ok {
+			required[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if required[c.Request.Method+" "+c.FullPath()] && c.GetHeader("If-Match") == "" {
+			utils.ErrorResponse(c, http.StatusPreconditionRequired, "Precondition required", "this request requires an If-Match header")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
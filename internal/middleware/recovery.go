@@ -12,6 +12,12 @@ import (
 // Recovery middleware for panic recovery
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		// Handlers abort on purpose to cut the connection, e.g. when a streamed
+		// response fails halfway; net/http handles this without logging
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+
 		logger.WithFields(map[string]interface{}{
 			"error":  recovered,
 			"method": c.Request.Method,
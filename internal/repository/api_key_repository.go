@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"go-template-structure/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(key *domain.APIKey) error
+	GetByID(id uint) (*domain.APIKey, error)
+	GetByHash(keyHash string) (*domain.APIKey, error)
+	ListByUserID(userID uint) ([]domain.APIKey, error)
+	Revoke(id uint) error
+	RevokeByUserID(userID uint) error
+	TouchLastUsed(id uint) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+func (r *apiKeyRepository) Create(key *domain.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) GetByID(id uint) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.First(&key, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByHash(keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) ListByUserID(userID uint) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) Revoke(id uint) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeByUserID revokes every active key of a user
+func (r *apiKeyRepository) RevokeByUserID(userID uint) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id uint) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", time.Now()).Error
+}
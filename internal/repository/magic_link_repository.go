@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-template-structure/internal/interfaces"
+)
+
+// MagicLink is a pending passwordless login. The link carries TokenID as its jti;
+// the emailed code is only stored as a hash.
+type MagicLink struct {
+	TokenID  string `json:"token_id"`
+	CodeHash string `json:"code_hash"`
+}
+
+// MagicLinkRepository stores the pending passwordless login of each user.
+// Only the most recently requested link or code is valid.
+type MagicLinkRepository interface {
+	Save(userID uint, link *MagicLink, ttl time.Duration) error
+	Get(userID uint) (*MagicLink, error)
+	Consume(userID uint, tokenID string, ttl time.Duration) (bool, error)
+	Delete(userID uint) error
+}
+
+type magicLinkRepository struct {
+	store interfaces.RedisInterface
+}
+
+// NewMagicLinkRepository creates a magic link repository backed by Redis or the in-memory store
+func NewMagicLinkRepository(store interfaces.RedisInterface) MagicLinkRepository {
+	return &magicLinkRepository{
+		store: store,
+	}
+}
+
+func (r *magicLinkRepository) Save(userID uint, link *MagicLink, ttl time.Duration) error {
+	value, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	return r.store.Set(context.Background(), magicLinkKey(userID), string(value), ttl)
+}
+
+// Get returns the pending magic link of the user, or nil if there is none
+func (r *magicLinkRepository) Get(userID uint) (*MagicLink, error) {
+	value, err := r.store.Get(context.Background(), magicLinkKey(userID))
+	if err != nil {
+		if errors.Is(err, interfaces.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var link MagicLink
+	if err := json.Unmarshal([]byte(value), &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Consume marks the link as used and removes it. It returns false if the link
+// was already used, so concurrent requests cannot both log in with it.
+func (r *magicLinkRepository) Consume(userID uint, tokenID string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	usedKey := fmt.Sprintf("magic_link_used:%s", tokenID)
+
+	count, err := r.store.Incr(ctx, usedKey)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		if err := r.store.Expire(ctx, usedKey, ttl); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.store.Del(ctx, magicLinkKey(userID)); err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+func (r *magicLinkRepository) Delete(userID uint) error {
+	return r.store.Del(context.Background(), magicLinkKey(userID))
+}
+
+func magicLinkKey(userID uint) string {
+	return fmt.Sprintf("magic_link:%d", userID)
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-template-structure/internal/interfaces"
+)
+
+// OAuthState is the data kept between redirecting to a provider and its callback
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// OAuthStateRepository stores pending OAuth authorization requests by their state parameter
+type OAuthStateRepository interface {
+	Save(state string, data *OAuthState, ttl time.Duration) error
+	Consume(state string) (*OAuthState, error)
+}
+
+type oauthStateRepository struct {
+	store interfaces.RedisInterface
+}
+
+// NewOAuthStateRepository creates an OAuth state repository backed by Redis or the in-memory store
+func NewOAuthStateRepository(store interfaces.RedisInterface) OAuthStateRepository {
+	return &oauthStateRepository{
+		store: store,
+	}
+}
+
+func (r *oauthStateRepository) Save(state string, data *OAuthState, ttl time.Duration) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return r.store.Set(context.Background(), fmt.Sprintf("oauth_state:%s", state), string(value), ttl)
+}
+
+// Consume returns the stored state and deletes it, so each state can only be used once.
+// It returns nil if the state is unknown or expired.
+func (r *oauthStateRepository) Consume(state string) (*OAuthState, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("oauth_state:%s", state)
+
+	value, err := r.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := r.store.Del(ctx, key); err != nil {
+		return nil, err
+	}
+
+	var data OAuthState
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"go-template-structure/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository interface {
+	Create(entry *domain.PasswordHistory) error
+	ListRecentByUserID(userID uint, limit int) ([]domain.PasswordHistory, error)
+	Prune(userID uint, keep int) error
+}
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{
+		db: db,
+	}
+}
+
+func (r *passwordHistoryRepository) Create(entry *domain.PasswordHistory) error {
+	return r.db.Create(entry).Error
+}
+
+// ListRecentByUserID returns the user's most recent previous passwords, newest first
+func (r *passwordHistoryRepository) ListRecentByUserID(userID uint, limit int) ([]domain.PasswordHistory, error) {
+	var entries []domain.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// Prune deletes all but the newest keep entries of the user
+func (r *passwordHistoryRepository) Prune(userID uint, keep int) error {
+	if keep <= 0 {
+		return r.db.Where("user_id = ?", userID).Delete(&domain.PasswordHistory{}).Error
+	}
+
+	recent := r.db.Model(&domain.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(keep)
+
+	return r.db.Where("user_id = ? AND id NOT IN (?)", userID, recent).
+		Delete(&domain.PasswordHistory{}).Error
+}
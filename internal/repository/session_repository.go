@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"go-template-structure/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *domain.Session) error
+	GetByID(id string) (*domain.Session, error)
+	ListActiveByUserID(userID uint) ([]domain.Session, error)
+	Touch(id, ip, userAgent string, expiresAt time.Time) error
+	Revoke(id string) error
+	RevokeByUserID(userID uint) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+func (r *sessionRepository) Create(session *domain.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(id string) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveByUserID returns the sessions that are neither revoked nor expired, most recently used first
+func (r *sessionRepository) ListActiveByUserID(userID uint) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records that the session was used to refresh its tokens
+func (r *sessionRepository) Touch(id, ip, userAgent string, expiresAt time.Time) error {
+	return r.db.Model(&domain.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_used_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+func (r *sessionRepository) Revoke(id string) error {
+	return r.db.Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeByUserID(userID uint) error {
+	return r.db.Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-template-structure/internal/interfaces"
+)
+
+// ThrottleRepository limits how often an action may be performed for a given key
+type ThrottleRepository interface {
+	Acquire(key string, cooldown time.Duration) (bool, error)
+	Hit(key string, window time.Duration) (int64, error)
+	Reset(key string) error
+	Block(key string, duration time.Duration) error
+	BlockedFor(key string) (time.Duration, error)
+	Unblock(key string) error
+}
+
+type throttleRepository struct {
+	store interfaces.RedisInterface
+}
+
+// NewThrottleRepository creates a throttle repository backed by Redis or the in-memory store
+func NewThrottleRepository(store interfaces.RedisInterface) ThrottleRepository {
+	return &throttleRepository{
+		store: store,
+	}
+}
+
+// Acquire returns true and starts the cooldown if the action is allowed,
+// or false if the key is still cooling down. Of concurrent calls, only one acquires.
+func (r *throttleRepository) Acquire(key string, cooldown time.Duration) (bool, error) {
+	return r.store.SetNX(context.Background(), fmt.Sprintf("throttle:%s", key), "1", cooldown)
+}
+
+// Hit counts an attempt and returns the number of attempts within the window.
+// The window starts with the first attempt.
+func (r *throttleRepository) Hit(key string, window time.Duration) (int64, error) {
+	ctx := context.Background()
+	counterKey := fmt.Sprintf("attempts:%s", key)
+
+	count, err := r.store.Incr(ctx, counterKey)
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := r.store.Expire(ctx, counterKey, window); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// Reset clears the attempt counter of the key
+func (r *throttleRepository) Reset(key string) error {
+	return r.store.Del(context.Background(), fmt.Sprintf("attempts:%s", key))
+}
+
+// Block blocks the key for the given duration
+func (r *throttleRepository) Block(key string, duration time.Duration) error {
+	until := time.Now().Add(duration).UnixNano()
+	return r.store.Set(context.Background(), fmt.Sprintf("block:%s", key), until, duration)
+}
+
+// BlockedFor returns how long the key remains blocked, or zero if it is not blocked
+func (r *throttleRepository) BlockedFor(key string) (time.Duration, error) {
+	value, err := r.store.Get(context.Background(), fmt.Sprintf("block:%s", key))
+	if errors.Is(err, interfaces.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	until, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block value: %w", err)
+	}
+
+	remaining := time.Until(time.Unix(0, until))
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (r *throttleRepository) Unblock(key string) error {
+	return r.store.Del(context.Background(), fmt.Sprintf("block:%s", key))
+}
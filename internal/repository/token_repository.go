@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-template-structure/internal/interfaces"
+)
+
+// TokenRepository keeps server-side token state: the revocation list,
+// per-user revocation and the current refresh token of each token family
+type TokenRepository interface {
+	RevokeToken(jti string, ttl time.Duration) error
+	IsTokenRevoked(jti string) (bool, error)
+	SetFamilyToken(familyID, jti string, ttl time.Duration) error
+	GetFamilyToken(familyID string) (string, error)
+	SwapFamilyToken(familyID, previousJTI, jti string, ttl time.Duration) (bool, error)
+	RevokeFamily(familyID string, ttl time.Duration) error
+	IsFamilyRevoked(familyID string) (bool, error)
+	RevokeUserTokens(userID uint, ttl time.Duration) error
+	IsUserTokenRevoked(userID uint, issuedAt time.Time) (bool, error)
+}
+
+// revokedUserMilliThreshold separates revocation markers in Unix seconds from
+// those in Unix milliseconds (the year 33658 in seconds, 2001 in milliseconds)
+const revokedUserMilliThreshold = 1_000_000_000_000
+
+type tokenRepository struct {
+	store interfaces.RedisInterface
+}
+
+// NewTokenRepository creates a token repository backed by Redis or the in-memory store
+func NewTokenRepository(store interfaces.RedisInterface) TokenRepository {
+	return &tokenRepository{
+		store: store,
+	}
+}
+
+// RevokeToken blacklists a token ID until its natural expiry
+func (r *tokenRepository) RevokeToken(jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// Token has already expired, nothing to revoke
+		return nil
+	}
+
+	ctx := context.Background()
+	return r.store.Set(ctx, revokedTokenKey(jti), "1", ttl)
+}
+
+func (r *tokenRepository) IsTokenRevoked(jti string) (bool, error) {
+	return r.exists(revokedTokenKey(jti))
+}
+
+// SetFamilyToken records the only refresh token of the family that may still be used
+func (r *tokenRepository) SetFamilyToken(familyID, jti string, ttl time.Duration) error {
+	ctx := context.Background()
+	return r.store.Set(ctx, familyTokenKey(familyID), jti, ttl)
+}
+
+// SwapFamilyToken replaces the current refresh token of the family, but only if it
+// is still previousJTI. Of concurrent refreshes with the same token, only one wins.
+func (r *tokenRepository) SwapFamilyToken(familyID, previousJTI, jti string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	return r.store.CompareAndSwap(ctx, familyTokenKey(familyID), previousJTI, jti, ttl)
+}
+
+// GetFamilyToken returns the current refresh token ID of the family, or an empty
+// string when the family is unknown or expired
+func (r *tokenRepository) GetFamilyToken(familyID string) (string, error) {
+	ctx := context.Background()
+	jti, err := r.store.Get(ctx, familyTokenKey(familyID))
+	if err != nil {
+		if errors.Is(err, interfaces.ErrKeyNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return jti, nil
+}
+
+// RevokeFamily invalidates every token issued within the family
+func (r *tokenRepository) RevokeFamily(familyID string, ttl time.Duration) error {
+	ctx := context.Background()
+	if err := r.store.Set(ctx, revokedFamilyKey(familyID), "1", ttl); err != nil {
+		return err
+	}
+	return r.store.Del(ctx, familyTokenKey(familyID))
+}
+
+func (r *tokenRepository) IsFamilyRevoked(familyID string) (bool, error) {
+	return r.exists(revokedFamilyKey(familyID))
+}
+
+// RevokeUserTokens invalidates every token issued to the user up to now.
+// The marker must live at least as long as the longest token lifetime.
+func (r *tokenRepository) RevokeUserTokens(userID uint, ttl time.Duration) error {
+	ctx := context.Background()
+	return r.store.Set(ctx, revokedUserKey(userID), strconv.FormatInt(time.Now().UnixMilli(), 10), ttl)
+}
+
+// IsUserTokenRevoked reports whether a token issued at issuedAt predates the
+// user's last RevokeUserTokens call
+func (r *tokenRepository) IsUserTokenRevoked(userID uint, issuedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	value, err := r.store.Get(ctx, revokedUserKey(userID))
+	if err != nil {
+		if errors.Is(err, interfaces.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid revocation marker: %w", err)
+	}
+
+	// Markers written before timestamps had millisecond precision hold seconds
+	if revokedAt < revokedUserMilliThreshold {
+		revokedAt *= 1000
+	}
+
+	// A token issued in the same millisecond as the revocation is treated as revoked
+	return issuedAt.UnixMilli() <= revokedAt, nil
+}
+
+func (r *tokenRepository) exists(key string) (bool, error) {
+	ctx := context.Background()
+	if _, err := r.store.Get(ctx, key); err != nil {
+		if errors.Is(err, interfaces.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func revokedTokenKey(jti string) string {
+	return fmt.Sprintf("revoked_token:%s", jti)
+}
+
+func familyTokenKey(familyID string) string {
+	return fmt.Sprintf("token_family:%s", familyID)
+}
+
+func revokedFamilyKey(familyID string) string {
+	return fmt.Sprintf("revoked_family:%s", familyID)
+}
+
+func revokedUserKey(userID uint) string {
+	return fmt.Sprintf("revoked_user:%d", userID)
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/interfaces"
+)
+
+// UserCacheKey is the cache key of a user read through UserService.GetUser
+func UserCacheKey(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// cacheInvalidatingUserRepository clears a user's cache entry whenever the user is
+// written, so the cached user, and the ETag built from its version, is never older
+// than the database, whichever service made the change
+type cacheInvalidatingUserRepository struct {
+	UserRepository
+	cache interfaces.RedisInterface
+}
+
+// NewCacheInvalidatingUserRepository wraps repo so that writes clear the users' cache
+// entries. It returns repo itself when there is no cache.
+func NewCacheInvalidatingUserRepository(repo UserRepository, cache interfaces.RedisInterface) UserRepository {
+	if cache == nil {
+		return repo
+	}
+	return &cacheInvalidatingUserRepository{
+		UserRepository: repo,
+		cache:          cache,
+	}
+}
+
+func (r *cacheInvalidatingUserRepository) Update(user *domain.User) error {
+	err := r.UserRepository.Update(user)
+	r.invalidate(user.ID)
+	return err
+}
+
+func (r *cacheInvalidatingUserRepository) Delete(id uint) error {
+	err := r.UserRepository.Delete(id)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cacheInvalidatingUserRepository) Restore(id uint) error {
+	err := r.UserRepository.Restore(id)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cacheInvalidatingUserRepository) HardDelete(id uint) error {
+	err := r.UserRepository.HardDelete(id)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cacheInvalidatingUserRepository) SaveBatch(creates, updates []*domain.User) error {
+	err := r.UserRepository.SaveBatch(creates, updates)
+	ids := make([]uint, 0, len(updates))
+	for _, user := range updates {
+		ids = append(ids, user.ID)
+	}
+	r.invalidate(ids...)
+	return err
+}
+
+// invalidate clears the entries even after a failed write, which may have been
+// a version conflict with a change the cache does not know about yet
+func (r *cacheInvalidatingUserRepository) invalidate(ids ...uint) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, UserCacheKey(id))
+	}
+	r.cache.Del(context.Background(), keys...)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"go-template-structure/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type UserImportJobRepository interface {
+	Create(job *domain.UserImportJob) error
+	GetByID(id string) (*domain.UserImportJob, error)
+	Update(job *domain.UserImportJob) error
+	FailStale(before time.Time, reason string) (int64, error)
+}
+
+type userImportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewUserImportJobRepository(db *gorm.DB) UserImportJobRepository {
+	return &userImportJobRepository{
+		db: db,
+	}
+}
+
+func (r *userImportJobRepository) Create(job *domain.UserImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *userImportJobRepository) GetByID(id string) (*domain.UserImportJob, error) {
+	var job domain.UserImportJob
+	err := r.db.Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *userImportJobRepository) Update(job *domain.UserImportJob) error {
+	return r.db.Save(job).Error
+}
+
+// FailStale fails pending and running jobs that have not made progress since before.
+// Their rows only lived in the memory of the process that ran them.
+func (r *userImportJobRepository) FailStale(before time.Time, reason string) (int64, error) {
+	now := time.Now()
+	result := r.db.Model(&domain.UserImportJob{}).
+		Where("status IN ? AND updated_at < ?", []domain.UserImportJobStatus{domain.ImportJobPending, domain.ImportJobRunning}, before).
+		Updates(map[string]interface{}{
+			"status":      domain.ImportJobFailed,
+			"error":       reason,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
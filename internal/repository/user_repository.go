@@ -1,11 +1,20 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"go-template-structure/internal/domain"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrVersionConflict is returned by Update when the user was changed since it was read
+var ErrVersionConflict = errors.New("version conflict")
+
 type UserRepository interface {
 	Create(user *domain.User) error
 	GetByID(id uint) (*domain.User, error)
@@ -13,8 +22,15 @@ type UserRepository interface {
 	GetByUsername(username string) (*domain.User, error)
 	Update(user *domain.User) error
 	Delete(id uint) error
-	List(offset, limit int) ([]domain.User, int64, error)
+	GetByIDWithDeleted(id uint) (*domain.User, error)
+	Restore(id uint) error
+	HardDelete(id uint) error
+	List(query *domain.UserQuery, offset, limit int) ([]domain.User, int64, error)
+	ListByKeyset(query *domain.UserQuery, position *domain.UserKeyset, limit int) ([]domain.User, bool, error)
+	Count(query *domain.UserQuery) (int64, error)
 	Exists(email, username string) (bool, error)
+	FindByEmailsOrUsernames(emails, usernames []string) ([]domain.User, error)
+	SaveBatch(creates, updates []*domain.User) error
 }
 
 type userRepository struct {
@@ -58,25 +74,87 @@ func (r *userRepository) GetByUsername(username string) (*domain.User, error) {
 	return &user, nil
 }
 
+// Update saves the user only if its version is still the one that was read, and
+// increments the version. It returns ErrVersionConflict if another update won.
 func (r *userRepository) Update(user *domain.User) error {
-	return r.db.Save(user).Error
+	return updateVersioned(r.db, user)
+}
+
+func updateVersioned(db *gorm.DB, user *domain.User) error {
+	expected := user.Version
+	user.Version++
+
+	result := db.Model(user).Where("version = ?", expected).Select("*").Omit("id", "created_at").Updates(user)
+	if result.Error != nil {
+		user.Version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		user.Version = expected
+		return ErrVersionConflict
+	}
+	return nil
 }
 
 func (r *userRepository) Delete(id uint) error {
 	return r.db.Delete(&domain.User{}, id).Error
 }
 
-func (r *userRepository) List(offset, limit int) ([]domain.User, int64, error) {
+// GetByIDWithDeleted returns the user whether or not it is soft-deleted
+func (r *userRepository) GetByIDWithDeleted(id uint) (*domain.User, error) {
+	var user domain.User
+	err := r.db.Unscoped().First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore clears the deletion of a soft-deleted user and increments its version.
+// It returns gorm.ErrRecordNotFound if the user does not exist or is not deleted.
+func (r *userRepository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&domain.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumns(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// HardDelete permanently removes the user. Rows referencing the user are removed
+// by the ON DELETE CASCADE foreign keys.
+func (r *userRepository) HardDelete(id uint) error {
+	return r.db.Unscoped().Delete(&domain.User{}, id).Error
+}
+
+func (r *userRepository) List(query *domain.UserQuery, offset, limit int) ([]domain.User, int64, error) {
 	var users []domain.User
 	var total int64
 
+	if query == nil {
+		query = &domain.UserQuery{}
+	}
+
 	// Count total records
-	if err := r.db.Model(&domain.User{}).Count(&total).Error; err != nil {
+	if err := r.filterUsers(query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order, err := userOrder(query.Sort)
+	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated records
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+	err = r.filterUsers(query).Clauses(order).Offset(offset).Limit(limit).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -84,6 +162,119 @@ func (r *userRepository) List(offset, limit int) ([]domain.User, int64, error) {
 	return users, total, nil
 }
 
+// ListByKeyset returns up to limit users after the position, or before it when
+// position.Before is set, ordered by (created_at, id). Only a created_at sort is
+// supported. The returned flag reports whether more users follow in the direction read.
+func (r *userRepository) ListByKeyset(query *domain.UserQuery, position *domain.UserKeyset, limit int) ([]domain.User, bool, error) {
+	if query == nil {
+		query = &domain.UserQuery{}
+	}
+
+	desc := false
+	if len(query.Sort) > 1 || (len(query.Sort) == 1 && query.Sort[0].Field != "created_at") {
+		return nil, false, errors.New("keyset pagination only supports sorting by created_at")
+	} else if len(query.Sort) == 1 {
+		desc = query.Sort[0].Desc
+	}
+
+	// Reading backwards scans in the opposite order, then restores it below
+	backward := position != nil && position.Before
+	scanDesc := desc != backward
+
+	db := r.filterUsers(query)
+	if position != nil {
+		operator := ">"
+		if scanDesc {
+			operator = "<"
+		}
+		db = db.Where("(created_at, id) "+operator+" (?, ?)", position.CreatedAt, position.ID)
+	}
+
+	var users []domain.User
+	err := db.Clauses(clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: "created_at"}, Desc: scanDesc},
+		{Column: clause.Column{Name: "id"}, Desc: scanDesc},
+	}}).Limit(limit + 1).Find(&users).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	more := len(users) > limit
+	if more {
+		users = users[:limit]
+	}
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	return users, more, nil
+}
+
+func (r *userRepository) Count(query *domain.UserQuery) (int64, error) {
+	if query == nil {
+		query = &domain.UserQuery{}
+	}
+
+	var total int64
+	err := r.filterUsers(query).Count(&total).Error
+	return total, err
+}
+
+// filterUsers applies the filters of the query. Every value is bound as a parameter.
+func (r *userRepository) filterUsers(query *domain.UserQuery) *gorm.DB {
+	db := r.db.Model(&domain.User{})
+
+	switch query.Deleted {
+	case domain.DeletedInclude:
+		db = db.Unscoped()
+	case domain.DeletedOnly:
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if query.IsActive != nil {
+		db = db.Where("is_active = ?", *query.IsActive)
+	}
+	if query.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *query.CreatedBefore)
+	}
+	if query.EmailDomain != "" {
+		db = db.Where("LOWER(email) LIKE ?", "%@"+escapeLike(strings.ToLower(query.EmailDomain)))
+	}
+	if query.Search != "" {
+		pattern := "%" + escapeLike(query.Search) + "%"
+		db = db.Where("username ILIKE @q OR email ILIKE @q OR first_name ILIKE @q OR last_name ILIKE @q", map[string]interface{}{"q": pattern})
+	}
+
+	return db
+}
+
+// userOrder builds the ORDER BY clause, ending with id so pages are stable
+func userOrder(sort []domain.UserSort) (clause.OrderBy, error) {
+	var order clause.OrderBy
+	hasID := false
+	for _, s := range sort {
+		if !domain.UserSortFields[s.Field] {
+			return order, fmt.Errorf("invalid sort field: %s", s.Field)
+		}
+		order.Columns = append(order.Columns, clause.OrderByColumn{Column: clause.Column{Name: s.Field}, Desc: s.Desc})
+		hasID = hasID || s.Field == "id"
+	}
+	if !hasID {
+		order.Columns = append(order.Columns, clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+	}
+	return order, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a value matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
 func (r *userRepository) Exists(email, username string) (bool, error) {
 	var count int64
 	err := r.db.Model(&domain.User{}).
@@ -92,3 +283,54 @@ func (r *userRepository) Exists(email, username string) (bool, error) {
 
 	return count > 0, err
 }
+
+// FindByEmailsOrUsernames returns the users having any of the emails or usernames
+func (r *userRepository) FindByEmailsOrUsernames(emails, usernames []string) ([]domain.User, error) {
+	var users []domain.User
+	db := r.db
+	switch {
+	case len(emails) > 0 && len(usernames) > 0:
+		db = db.Where("email IN ? OR username IN ?", emails, usernames)
+	case len(emails) > 0:
+		db = db.Where("email IN ?", emails)
+	case len(usernames) > 0:
+		db = db.Where("username IN ?", usernames)
+	default:
+		return users, nil
+	}
+	err := db.Find(&users).Error
+	return users, err
+}
+
+// SaveBatch inserts and updates users in one transaction, so either the whole batch
+// is saved or none of it. Updates are versioned like Update.
+func (r *userRepository) SaveBatch(creates, updates []*domain.User) error {
+	versions := make([]uint, len(updates))
+	for i, user := range updates {
+		versions[i] = user.Version
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(creates) > 0 {
+			if err := tx.Create(creates).Error; err != nil {
+				return err
+			}
+		}
+		for _, user := range updates {
+			if err := updateVersioned(tx, user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Nothing was saved, so the versions were not incremented either
+		for i, user := range updates {
+			user.Version = versions[i]
+		}
+		for _, user := range creates {
+			user.ID = 0
+		}
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-template-structure/internal/interfaces"
+)
+
+// WebAuthn ceremonies a challenge can be issued for
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnChallenge is the data kept between starting a WebAuthn ceremony and
+// receiving the authenticator's response. UserID is zero for logins.
+type WebAuthnChallenge struct {
+	Ceremony string `json:"ceremony"`
+	UserID   uint   `json:"user_id"`
+}
+
+// WebAuthnChallengeRepository stores pending WebAuthn ceremonies by their challenge
+type WebAuthnChallengeRepository interface {
+	Save(challenge string, data *WebAuthnChallenge, ttl time.Duration) error
+	Consume(challenge string) (*WebAuthnChallenge, error)
+}
+
+type webAuthnChallengeRepository struct {
+	store interfaces.RedisInterface
+}
+
+// NewWebAuthnChallengeRepository creates a WebAuthn challenge repository backed by Redis or the in-memory store
+func NewWebAuthnChallengeRepository(store interfaces.RedisInterface) WebAuthnChallengeRepository {
+	return &webAuthnChallengeRepository{
+		store: store,
+	}
+}
+
+func (r *webAuthnChallengeRepository) Save(challenge string, data *WebAuthnChallenge, ttl time.Duration) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return r.store.Set(context.Background(), fmt.Sprintf("webauthn_challenge:%s", challenge), string(value), ttl)
+}
+
+// Consume returns the stored challenge and deletes it, so each challenge can only be used once.
+// It returns nil if the challenge is unknown or expired.
+func (r *webAuthnChallengeRepository) Consume(challenge string) (*WebAuthnChallenge, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("webauthn_challenge:%s", challenge)
+
+	value, err := r.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := r.store.Del(ctx, key); err != nil {
+		return nil, err
+	}
+
+	var data WebAuthnChallenge
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"go-template-structure/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type WebAuthnCredentialRepository interface {
+	Create(credential *domain.WebAuthnCredential) error
+	GetByID(id uint) (*domain.WebAuthnCredential, error)
+	GetByCredentialID(credentialID string) (*domain.WebAuthnCredential, error)
+	ListByUserID(userID uint) ([]domain.WebAuthnCredential, error)
+	UpdateSignCount(id uint, signCount uint32) error
+	Delete(id uint) error
+}
+
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{
+		db: db,
+	}
+}
+
+func (r *webAuthnCredentialRepository) Create(credential *domain.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *webAuthnCredentialRepository) GetByID(id uint) (*domain.WebAuthnCredential, error) {
+	var credential domain.WebAuthnCredential
+	err := r.db.First(&credential, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *webAuthnCredentialRepository) GetByCredentialID(credentialID string) (*domain.WebAuthnCredential, error) {
+	var credential domain.WebAuthnCredential
+	err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *webAuthnCredentialRepository) ListByUserID(userID uint) ([]domain.WebAuthnCredential, error) {
+	var credentials []domain.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+// UpdateSignCount stores the counter of a successful login and marks the credential as used
+func (r *webAuthnCredentialRepository) UpdateSignCount(id uint, signCount uint32) error {
+	return r.db.Model(&domain.WebAuthnCredential{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"sign_count":   signCount,
+			"last_used_at": time.Now(),
+		}).Error
+}
+
+func (r *webAuthnCredentialRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.WebAuthnCredential{}, id).Error
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix       = "gts_"
+	apiKeyVisibleChars = 12
+)
+
+// Errors returned by APIKeyService.Authenticate for keys that must be rejected
+var (
+	ErrInvalidAPIKey       = errors.New("invalid api key")
+	ErrAPIKeyOwnerInactive = errors.New("user account is inactive")
+)
+
+type APIKeyService interface {
+	Create(actorID, userID uint, req *domain.CreateAPIKeyRequest) (*domain.CreateAPIKeyResponse, error)
+	List(userID uint) ([]domain.APIKey, error)
+	Revoke(actorID, userID, keyID uint) error
+	Authenticate(rawKey string) (*domain.APIKey, *domain.User, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// Create issues a new API key for the user. The plain key is only returned here.
+// Scopes must be permissions the user's role already has.
+func (s *apiKeyService) Create(actorID, userID uint, req *domain.CreateAPIKeyRequest) (*domain.CreateAPIKeyResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	scopes := domain.PermissionList{}
+	for _, scope := range req.Scopes {
+		if !user.Role.HasPermission(scope) {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+		if !scopes.Contains(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiration must be in the future")
+	}
+
+	rawKey, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	key := &domain.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    rawKey[:apiKeyVisibleChars],
+		KeyHash:   hashToken(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id":   actorID,
+		"user_id":    user.ID,
+		"api_key_id": key.ID,
+		"event":      "api_key_created",
+	}).Info("Audit Log - API key created")
+
+	return &domain.CreateAPIKeyResponse{
+		APIKey: key,
+		Key:    rawKey,
+	}, nil
+}
+
+func (s *apiKeyService) List(userID uint) ([]domain.APIKey, error) {
+	keys, err := s.apiKeyRepo.ListByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) Revoke(actorID, userID, keyID uint) error {
+	key, err := s.apiKeyRepo.GetByID(keyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("api key not found")
+		}
+		return fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	// Do not reveal keys of other users
+	if key.UserID != userID {
+		return errors.New("api key not found")
+	}
+
+	if err := s.apiKeyRepo.Revoke(key.ID); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id":   actorID,
+		"user_id":    userID,
+		"api_key_id": key.ID,
+		"event":      "api_key_revoked",
+	}).Info("Audit Log - API key revoked")
+
+	return nil
+}
+
+// Authenticate resolves a presented key to its record and owner
+func (s *apiKeyService) Authenticate(rawKey string) (*domain.APIKey, *domain.User, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByHash(hashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil, nil, ErrAPIKeyOwnerInactive
+	}
+
+	// Record usage at most once a minute to avoid a write on every request
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > time.Minute {
+		if err := s.apiKeyRepo.TouchLastUsed(key.ID); err != nil {
+			logger.WithFields(map[string]interface{}{
+				"api_key_id": key.ID,
+				"error":      err.Error(),
+			}).Warn("Failed to record API key usage")
+		}
+	}
+
+	return key, user, nil
+}
+
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
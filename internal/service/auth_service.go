@@ -1,38 +1,74 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"go-template-structure/internal/config"
 	"go-template-structure/internal/domain"
+	"go-template-structure/internal/interfaces"
 	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
 	"go-template-structure/pkg/utils"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	Register(req *domain.CreateUserRequest) (*domain.AuthResponse, error)
-	Login(req *domain.LoginRequest) (*domain.AuthResponse, error)
-	RefreshToken(refreshToken string) (*domain.AuthResponse, error)
+	Register(req *domain.CreateUserRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+	Login(req *domain.LoginRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+	RefreshToken(refreshToken string, client domain.ClientInfo) (*domain.AuthResponse, error)
+	Logout(claims *utils.JWTClaims, refreshToken string) error
+	VerifyEmail(token string) error
+	ResendVerification(email string) error
+	SendVerificationEmail(user *domain.User) error
+	IssueTokens(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error)
+	CompleteLogin(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error)
+	Impersonate(actorID, userID uint) (*domain.AuthResponse, error)
 }
 
 type authService struct {
-	userRepo  repository.UserRepository
-	jwtConfig config.JWTConfig
+	userRepo        repository.UserRepository
+	tokenRepo       repository.TokenRepository
+	sessionRepo     repository.SessionRepository
+	throttleRepo    repository.ThrottleRepository
+	lockoutService  LockoutService
+	hasher          utils.PasswordHasher
+	breachedChecker utils.BreachedPasswordChecker
+	keys            *utils.KeySet
+	mailer          interfaces.Mailer
+	jwtConfig       config.JWTConfig
+	authConfig      config.AuthConfig
+	passwordConfig  config.PasswordConfig
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtConfig config.JWTConfig) AuthService {
+func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository, throttleRepo repository.ThrottleRepository, lockoutService LockoutService, hasher utils.PasswordHasher, breachedChecker utils.BreachedPasswordChecker, keys *utils.KeySet, mailer interfaces.Mailer, jwtConfig config.JWTConfig, authConfig config.AuthConfig, passwordConfig config.PasswordConfig) AuthService {
 	return &authService{
-		userRepo:  userRepo,
-		jwtConfig: jwtConfig,
+		userRepo:        userRepo,
+		tokenRepo:       tokenRepo,
+		sessionRepo:     sessionRepo,
+		throttleRepo:    throttleRepo,
+		lockoutService:  lockoutService,
+		hasher:          hasher,
+		breachedChecker: breachedChecker,
+		keys:            keys,
+		mailer:          mailer,
+		jwtConfig:       jwtConfig,
+		authConfig:      authConfig,
+		passwordConfig:  passwordConfig,
 	}
 }
 
-func (s *authService) Register(req *domain.CreateUserRequest) (*domain.AuthResponse, error) {
+func (s *authService) Register(req *domain.CreateUserRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	if err := checkPasswordPolicy(s.passwordConfig, s.breachedChecker, req.Password); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	exists, err := s.userRepo.Exists(req.Email, req.Username)
 	if err != nil {
@@ -44,7 +80,7 @@ func (s *authService) Register(req *domain.CreateUserRequest) (*domain.AuthRespo
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -53,9 +89,10 @@ func (s *authService) Register(req *domain.CreateUserRequest) (*domain.AuthRespo
 	user := &domain.User{
 		Email:     req.Email,
 		Username:  req.Username,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Role:      domain.RoleUser,
 		IsActive:  true,
 	}
 
@@ -63,31 +100,34 @@ func (s *authService) Register(req *domain.CreateUserRequest) (*domain.AuthRespo
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Generate tokens
-	accessToken, err := utils.GenerateJWT(user.ID, user.Email, s.jwtConfig.Secret, s.jwtConfig.Expiration)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	// The account stays usable if the email cannot be delivered; the user can request a new link
+	if err := s.SendVerificationEmail(user); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to send verification email")
 	}
 
-	refreshToken, err := utils.GenerateJWT(user.ID, user.Email, s.jwtConfig.Secret, 7*24*time.Hour)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	// Unverified users only receive tokens once they have confirmed their email
+	if s.authConfig.RequireEmailVerification {
+		return &domain.AuthResponse{User: user}, nil
 	}
 
-	return &domain.AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(s.jwtConfig.Expiration.Seconds()),
-	}, nil
+	// Generate tokens for a new token family
+	return s.issueTokens(user, "", "", client)
 }
 
-func (s *authService) Login(req *domain.LoginRequest) (*domain.AuthResponse, error) {
+func (s *authService) Login(req *domain.LoginRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// Reject locked accounts and IPs before looking at the password
+	if err := s.lockoutService.Check(req.Email, client.IP); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid email or password")
+			return nil, s.loginFailed(req.Email, client)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -98,35 +138,203 @@ func (s *authService) Login(req *domain.LoginRequest) (*domain.AuthResponse, err
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid email or password")
+	match, needsRehash, err := s.hasher.Verify(user.Password, req.Password)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to verify password hash")
+	}
+	if !match {
+		return nil, s.loginFailed(req.Email, client)
+	}
+
+	// Upgrade hashes made with an outdated algorithm or parameters while the plaintext is at hand
+	if needsRehash {
+		s.rehashPassword(user, req.Password)
+	}
+
+	return s.CompleteLogin(user, client)
+}
+
+// CompleteLogin finishes a login once the user's primary credential has been checked.
+// It enforces email verification and starts an MFA challenge when required, otherwise
+// it clears the user's login failures and issues tokens for a new session.
+func (s *authService) CompleteLogin(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// Checked after the credential so the verification state is only revealed to the owner
+	if s.authConfig.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return nil, errors.New("email_not_verified")
+	}
+
+	// With MFA enabled the first factor alone only earns a short-lived challenge token
+	if user.MFAEnabled {
+		mfaToken, err := s.keys.GenerateToken(&utils.JWTClaims{
+			UserID:   user.ID,
+			Email:    user.Email,
+			TokenUse: utils.TokenUseMFAChallenge,
+		}, s.authConfig.MFAChallengeExpiration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+
+		return &domain.AuthResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	return s.IssueTokens(user, client)
+}
+
+// rehashPassword replaces the user's password hash with one using the current
+// algorithm and parameters. Failures are logged; the login still succeeds.
+func (s *authService) rehashPassword(user *domain.User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err == nil {
+		user.Password = hashedPassword
+		err = s.userRepo.Update(user)
 	}
 
-	// Generate tokens
-	accessToken, err := utils.GenerateJWT(user.ID, user.Email, s.jwtConfig.Secret, s.jwtConfig.Expiration)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to upgrade password hash")
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "password_rehashed",
+	}).Info("Audit Log - Password hash upgraded")
+}
+
+// loginFailed records a failed login attempt and returns the error for the client
+func (s *authService) loginFailed(email string, client domain.ClientInfo) error {
+	if err := s.lockoutService.RecordFailure(email, client.IP); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"email": email,
+		"ip":    client.IP,
+		"event": "login_failed",
+	}).Warn("Audit Log - Failed login attempt")
+
+	return errors.New("invalid email or password")
+}
+
+// IssueTokens starts a new session for a user who has already been authenticated,
+// e.g. after a second factor has been verified
+func (s *authService) IssueTokens(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	// Failures are only cleared once every factor has been checked, otherwise the
+	// first factor alone would reset the lockout of the second
+	if err := s.lockoutService.RecordSuccess(user.Email); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, "", "", client)
+}
+
+// Impersonate issues a short-lived access token that acts as the target user and
+// names the actor in its "act" claim. No refresh token or session is created, so
+// impersonation ends when the token expires or is logged out.
+func (s *authService) Impersonate(actorID, userID uint) (*domain.AuthResponse, error) {
+	if actorID == userID {
+		return nil, errors.New("cannot impersonate yourself")
 	}
 
-	refreshToken, err := utils.GenerateJWT(user.ID, user.Email, s.jwtConfig.Secret, 7*24*time.Hour)
+	actor, err := s.userRepo.GetByID(actorID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Impersonating an admin would hand out every permission
+	if user.Role == domain.RoleAdmin {
+		return nil, errors.New("cannot impersonate an admin")
+	}
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
 	}
 
+	accessToken, err := s.keys.GenerateToken(&utils.JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     string(user.Role),
+		TokenUse: utils.TokenUseAccess,
+		Actor:    &utils.ActorClaims{UserID: actor.ID, Email: actor.Email},
+	}, s.authConfig.ImpersonationExpiration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": actor.ID,
+		"user_id":  user.ID,
+		"event":    "impersonation_started",
+	}).Warn("Audit Log - Impersonation started")
+
 	return &domain.AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(s.jwtConfig.Expiration.Seconds()),
+		User:        user,
+		AccessToken: accessToken,
+		ExpiresIn:   int64(s.authConfig.ImpersonationExpiration.Seconds()),
 	}, nil
 }
 
-func (s *authService) RefreshToken(refreshToken string) (*domain.AuthResponse, error) {
+// RefreshToken exchanges a refresh token for a new token pair.
+// Refresh tokens are single-use: each one is rotated, and presenting a token that
+// was already rotated revokes its whole family because the token was likely stolen.
+func (s *authService) RefreshToken(refreshToken string, client domain.ClientInfo) (*domain.AuthResponse, error) {
 	// Validate refresh token
-	claims, err := utils.ValidateJWT(refreshToken, s.jwtConfig.Secret)
+	claims, err := s.keys.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Access tokens cannot be used to refresh
+	if claims.TokenUse != utils.TokenUseRefresh || claims.ID == "" || claims.FamilyID == "" || claims.IssuedAt == nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Reject refresh tokens that were revoked through logout or reuse detection
+	revoked, err := s.tokenRepo.IsTokenRevoked(claims.ID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	familyRevoked, err := s.tokenRepo.IsFamilyRevoked(claims.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	userRevoked, err := s.tokenRepo.IsUserTokenRevoked(claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked || familyRevoked || userRevoked {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Only the most recently issued refresh token of the family may be used
+	currentID, err := s.tokenRepo.GetFamilyToken(claims.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token family: %w", err)
+	}
+	if currentID == "" {
 		return nil, errors.New("invalid refresh token")
 	}
+	if currentID != claims.ID {
+		return nil, s.rejectReusedToken(claims)
+	}
 
 	// Get user
 	user, err := s.userRepo.GetByID(claims.UserID)
@@ -142,21 +350,256 @@ func (s *authService) RefreshToken(refreshToken string) (*domain.AuthResponse, e
 		return nil, errors.New("user account is inactive")
 	}
 
-	// Generate new tokens
-	accessToken, err := utils.GenerateJWT(user.ID, user.Email, s.jwtConfig.Secret, s.jwtConfig.Expiration)
+	// Rotate: issue a new pair within the same family
+	// The check above is repeated atomically when the new token is stored, in case
+	// the same token is being refreshed concurrently
+	resp, err := s.issueTokens(user, claims.FamilyID, claims.ID, client)
+	if errors.Is(err, errRefreshTokenSuperseded) {
+		return nil, s.rejectReusedToken(claims)
+	}
+	return resp, err
+}
+
+// rejectReusedToken revokes the family of a refresh token that was already used.
+// Either the token was stolen or a client replayed it, so no token of the family
+// can be trusted any more.
+func (s *authService) rejectReusedToken(claims *utils.JWTClaims) error {
+	if err := s.revokeFamily(claims.FamilyID); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":   claims.UserID,
+		"family_id": claims.FamilyID,
+	}).Warn("Refresh token reuse detected, token family revoked")
+
+	return errors.New("refresh token reuse detected")
+}
+
+func (s *authService) Logout(claims *utils.JWTClaims, refreshToken string) error {
+	if claims == nil {
+		return errors.New("invalid access token")
+	}
+
+	// Revoke the access token used for this request
+	if err := s.revokeToken(claims); err != nil {
+		return err
+	}
+
+	// Optionally revoke the given refresh token as well
+	if refreshToken != "" {
+		refreshClaims, err := s.keys.ValidateToken(refreshToken)
+		if err != nil || refreshClaims.UserID != claims.UserID {
+			return errors.New("invalid refresh token")
+		}
+
+		if err := s.revokeToken(refreshClaims); err != nil {
+			return err
+		}
+	}
+
+	// Revoke the family so the session cannot be renewed
+	if claims.FamilyID != "" {
+		if err := s.revokeFamily(claims.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the user's email as verified using a signed verification token.
+// The token is bound to the address it was sent to, so it stops working if the email changes.
+func (s *authService) VerifyEmail(token string) error {
+	claims, err := s.keys.ValidateToken(token)
+	if err != nil || claims.TokenUse != utils.TokenUseEmailVerification {
+		return errors.New("invalid or expired verification token")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid or expired verification token")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return errors.New("invalid or expired verification token")
+	}
+
+	// Verifying twice is harmless
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "email_verified",
+	}).Info("Audit Log - Email verified")
+
+	return nil
+}
+
+// ResendVerification sends a new verification link. Requests are throttled per
+// address, and unknown or already verified addresses are silently ignored so the
+// endpoint cannot be used to discover registered emails.
+func (s *authService) ResendVerification(email string) error {
+	allowed, err := s.throttleRepo.Acquire("verify_email:"+strings.ToLower(email), s.authConfig.VerificationResendCooldown)
+	if err != nil {
+		return fmt.Errorf("failed to check resend throttle: %w", err)
+	}
+	if !allowed {
+		return errors.New("verification email recently sent")
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsActive || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.SendVerificationEmail(user); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to send verification email")
+	}
+
+	return nil
+}
+
+// SendVerificationEmail emails a signed verification link to the user
+func (s *authService) SendVerificationEmail(user *domain.User) error {
+	token, err := s.keys.GenerateToken(&utils.JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		TokenUse: utils.TokenUseEmailVerification,
+	}, s.authConfig.EmailVerificationExpiration)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s?token=%s", s.authConfig.EmailVerificationURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below within %s:\n\n%s\n\n"+
+		"If you did not create an account, you can ignore this email.\n",
+		user.FirstName, s.authConfig.EmailVerificationExpiration, link)
+
+	return s.mailer.Send(context.Background(), user.Email, "Verify your email address", body)
+}
+
+// errRefreshTokenSuperseded is returned by issueTokens when the refresh token being
+// rotated is no longer the current one of its family
+var errRefreshTokenSuperseded = errors.New("refresh token superseded")
+
+// issueTokens generates an access/refresh token pair. An empty familyID starts a
+// new family and records a new session for it. Otherwise previousJTI is the refresh
+// token being rotated, which must still be the current one of the family.
+func (s *authService) issueTokens(user *domain.User, familyID, previousJTI string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	userAgent := client.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	expiresAt := time.Now().Add(s.jwtConfig.RefreshExpiration)
+
+	newFamily := familyID == ""
+	if newFamily {
+		familyID = uuid.New().String()
+
+		now := time.Now()
+		session := &domain.Session{
+			ID:         familyID,
+			UserID:     user.ID,
+			UserAgent:  userAgent,
+			IP:         client.IP,
+			LastUsedAt: now,
+			ExpiresAt:  expiresAt,
+		}
+		if err := s.sessionRepo.Create(session); err != nil {
+			return nil, fmt.Errorf("failed to create session: %w", err)
+		}
+	}
+
+	accessToken, err := s.keys.GenerateToken(&utils.JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     string(user.Role),
+		TokenUse: utils.TokenUseAccess,
+		FamilyID: familyID,
+	}, s.jwtConfig.Expiration)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	newRefreshToken, err := utils.GenerateJWT(user.ID, user.Email, s.jwtConfig.Secret, 7*24*time.Hour)
+	refreshClaims := &utils.JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		TokenUse: utils.TokenUseRefresh,
+		FamilyID: familyID,
+	}
+	refreshToken, err := s.keys.GenerateToken(refreshClaims, s.jwtConfig.RefreshExpiration)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
+	// The new refresh token becomes the only valid one in the family
+	if newFamily {
+		if err := s.tokenRepo.SetFamilyToken(familyID, refreshClaims.ID, s.jwtConfig.RefreshExpiration); err != nil {
+			return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		}
+	} else {
+		swapped, err := s.tokenRepo.SwapFamilyToken(familyID, previousJTI, refreshClaims.ID, s.jwtConfig.RefreshExpiration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		}
+		if !swapped {
+			return nil, errRefreshTokenSuperseded
+		}
+		if err := s.sessionRepo.Touch(familyID, client.IP, userAgent, expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to update session: %w", err)
+		}
+	}
+
 	return &domain.AuthResponse{
 		User:         user,
 		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
+		RefreshToken: refreshToken,
 		ExpiresIn:    int64(s.jwtConfig.Expiration.Seconds()),
 	}, nil
 }
+
+// revokeFamily revokes a token family and marks its session as signed out
+func (s *authService) revokeFamily(familyID string) error {
+	if err := s.tokenRepo.RevokeFamily(familyID, s.jwtConfig.RefreshExpiration); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	if err := s.sessionRepo.Revoke(familyID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// revokeToken adds the token ID to the revocation list until the token expires
+func (s *authService) revokeToken(claims *utils.JWTClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	if err := s.tokenRepo.RevokeToken(claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/interfaces"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type AvatarService interface {
+	UploadAvatar(userID uint, file io.Reader) (*domain.Avatar, error)
+	DeleteAvatar(userID uint) error
+}
+
+var errAvatarTooLarge = errors.New("avatar file too large")
+
+type avatarService struct {
+	userRepo  repository.UserRepository
+	storage   interfaces.BlobStorage
+	avatarCfg config.AvatarConfig
+}
+
+func NewAvatarService(userRepo repository.UserRepository, storage interfaces.BlobStorage, avatarCfg config.AvatarConfig) AvatarService {
+	return &avatarService{
+		userRepo:  userRepo,
+		storage:   storage,
+		avatarCfg: avatarCfg,
+	}
+}
+
+// UploadAvatar stores resized variants of an image as the user's avatar and
+// deletes the variants of the previous one. The image is re-encoded, which drops
+// EXIF and any other metadata of the uploaded file.
+func (s *avatarService) UploadAvatar(userID uint, file io.Reader) (*domain.Avatar, error) {
+	data, err := io.ReadAll(&maxBytesReader{r: file, remaining: s.avatarCfg.MaxBytes, err: errAvatarTooLarge})
+	if err != nil {
+		if errors.Is(err, errAvatarTooLarge) {
+			return nil, errAvatarTooLarge
+		}
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	img, format, err := utils.DecodeSquareImage(data, s.avatarCfg.MaxPixels)
+	if err != nil {
+		// Both errors are meant for the client
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Every upload gets a new directory, so cached copies of an old avatar are
+	// never served for the new one
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate avatar key: %w", err)
+	}
+	dir := fmt.Sprintf("avatars/%d/%s/", user.ID, hex.EncodeToString(token))
+
+	ctx := context.Background()
+	avatar := &domain.Avatar{Variants: make(map[string]string, len(domain.AvatarSizes))}
+	var keys []string
+	for _, size := range domain.AvatarSizes {
+		var buf bytes.Buffer
+		ext, err := utils.EncodeImage(&buf, utils.ResizeSquare(img, size), format)
+		if err != nil {
+			s.deleteKeys(keys)
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+
+		key := dir + strconv.Itoa(size) + ext
+		if err := s.storage.Put(ctx, key, &buf); err != nil {
+			s.deleteKeys(keys)
+			return nil, fmt.Errorf("failed to store avatar: %w", err)
+		}
+		keys = append(keys, key)
+		avatar.Variants[strconv.Itoa(size)] = s.storage.URL(key)
+	}
+
+	previousKey := user.AvatarKey
+	user.AvatarKey = keys[0]
+	user.Avatar = s.storage.URL(keys[0])
+	avatar.URL = user.Avatar
+
+	if err := s.userRepo.Update(user); err != nil {
+		s.deleteKeys(keys)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, errors.New("version mismatch")
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if previousKey != "" {
+		s.deleteKeys(domain.AvatarVariantKeys(previousKey))
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"format":  format,
+		"event":   "avatar_uploaded",
+	}).Info("Audit Log - Avatar uploaded")
+
+	return avatar, nil
+}
+
+// DeleteAvatar removes the user's avatar and its stored variants
+func (s *avatarService) DeleteAvatar(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Avatar == "" && user.AvatarKey == "" {
+		return nil
+	}
+
+	previousKey := user.AvatarKey
+	user.Avatar = ""
+	user.AvatarKey = ""
+	if err := s.userRepo.Update(user); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return errors.New("version mismatch")
+		}
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if previousKey != "" {
+		s.deleteKeys(domain.AvatarVariantKeys(previousKey))
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "avatar_deleted",
+	}).Info("Audit Log - Avatar deleted")
+
+	return nil
+}
+
+// deleteKeys deletes stored files on a best-effort basis. A file that cannot be
+// deleted is orphaned but no longer referenced, so the request still succeeds.
+func (s *avatarService) deleteKeys(keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(context.Background(), key); err != nil {
+			logger.WithFields(map[string]interface{}{
+				"key":   key,
+				"error": err.Error(),
+			}).Warn("Failed to delete avatar file")
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+var (
+	loginFailuresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_login_failures_total",
+			Help: "Total number of failed login attempts",
+		},
+	)
+
+	loginLockoutsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_lockouts_total",
+			Help: "Total number of lockouts triggered by failed logins",
+		},
+		[]string{"scope"},
+	)
+
+	loginThrottledTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_throttled_total",
+			Help: "Total number of login attempts rejected by lockout or backoff",
+		},
+		[]string{"reason"},
+	)
+)
+
+// LoginThrottledError is returned when a login attempt is rejected before the
+// password is checked. RetryAfter tells the client when to try again.
+type LoginThrottledError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account temporarily locked"
+	}
+	return "too many login attempts"
+}
+
+type LockoutService interface {
+	Check(email, ip string) error
+	RecordFailure(email, ip string) error
+	RecordSuccess(email string) error
+	Unlock(actorID, userID uint) error
+}
+
+type lockoutService struct {
+	userRepo     repository.UserRepository
+	throttleRepo repository.ThrottleRepository
+	config       config.LockoutConfig
+}
+
+func NewLockoutService(userRepo repository.UserRepository, throttleRepo repository.ThrottleRepository, lockoutConfig config.LockoutConfig) LockoutService {
+	return &lockoutService{
+		userRepo:     userRepo,
+		throttleRepo: throttleRepo,
+		config:       lockoutConfig,
+	}
+}
+
+// Check returns a *LoginThrottledError if the account or IP may not attempt a login right now
+func (s *lockoutService) Check(email, ip string) error {
+	checks := []struct {
+		key    string
+		locked bool
+		reason string
+	}{
+		{ipLockKey(ip), true, "ip_blocked"},
+		{accountLockKey(email), true, "account_locked"},
+		{accountBackoffKey(email), false, "backoff"},
+	}
+
+	for _, check := range checks {
+		remaining, err := s.throttleRepo.BlockedFor(check.key)
+		if err != nil {
+			return fmt.Errorf("failed to check login lockout: %w", err)
+		}
+		if remaining > 0 {
+			loginThrottledTotal.WithLabelValues(check.reason).Inc()
+			return &LoginThrottledError{Locked: check.locked, RetryAfter: remaining}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login. Each failure adds an increasing delay before
+// the next attempt; reaching the threshold locks the account or blocks the IP.
+// Failures are counted for unknown emails too, so lockouts do not reveal which accounts exist.
+func (s *lockoutService) RecordFailure(email, ip string) error {
+	loginFailuresTotal.Inc()
+
+	failures, err := s.throttleRepo.Hit(accountFailureKey(email), s.config.Window)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if failures >= int64(s.config.MaxAttempts) {
+		if err := s.lock(accountLockKey(email), accountFailureKey(email)); err != nil {
+			return err
+		}
+		loginLockoutsTotal.WithLabelValues("account").Inc()
+		logger.WithFields(map[string]interface{}{
+			"email":    email,
+			"ip":       ip,
+			"failures": failures,
+			"event":    "account_locked",
+		}).Warn("Audit Log - Account locked after failed login attempts")
+	} else if backoff := s.backoff(failures); backoff > 0 {
+		if err := s.throttleRepo.Block(accountBackoffKey(email), backoff); err != nil {
+			return fmt.Errorf("failed to apply login backoff: %w", err)
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	ipFailures, err := s.throttleRepo.Hit(ipFailureKey(ip), s.config.Window)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if ipFailures >= int64(s.config.IPMaxAttempts) {
+		if err := s.lock(ipLockKey(ip), ipFailureKey(ip)); err != nil {
+			return err
+		}
+		loginLockoutsTotal.WithLabelValues("ip").Inc()
+		logger.WithFields(map[string]interface{}{
+			"ip":       ip,
+			"failures": ipFailures,
+			"event":    "ip_blocked",
+		}).Warn("Audit Log - IP blocked after failed login attempts")
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the account's failure counter after a successful login
+func (s *lockoutService) RecordSuccess(email string) error {
+	if err := s.throttleRepo.Reset(accountFailureKey(email)); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
+
+// Unlock lifts a lockout and clears the failure counter of a user
+func (s *lockoutService) Unlock(actorID, userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.throttleRepo.Unblock(accountLockKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if err := s.throttleRepo.Unblock(accountBackoffKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if err := s.throttleRepo.Reset(accountFailureKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"user_id":  user.ID,
+		"event":    "account_unlocked",
+	}).Info("Audit Log - Account unlocked")
+
+	return nil
+}
+
+// lock blocks the key and starts counting from zero once the lockout ends
+func (s *lockoutService) lock(lockKey, failureKey string) error {
+	if err := s.throttleRepo.Block(lockKey, s.config.Duration); err != nil {
+		return fmt.Errorf("failed to apply lockout: %w", err)
+	}
+	if err := s.throttleRepo.Reset(failureKey); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
+
+// backoff returns the delay after the given number of failures: base, 2*base, 4*base, ...
+func (s *lockoutService) backoff(failures int64) time.Duration {
+	if s.config.BackoffBase <= 0 || failures < 1 {
+		return 0
+	}
+
+	delay := s.config.BackoffBase
+	for i := int64(1); i < failures; i++ {
+		delay *= 2
+		if s.config.BackoffMax > 0 && delay >= s.config.BackoffMax {
+			return s.config.BackoffMax
+		}
+	}
+	return delay
+}
+
+func accountFailureKey(email string) string {
+	return "login:account:" + strings.ToLower(email)
+}
+
+func accountLockKey(email string) string {
+	return "lockout:account:" + strings.ToLower(email)
+}
+
+func accountBackoffKey(email string) string {
+	return "backoff:account:" + strings.ToLower(email)
+}
+
+func ipFailureKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+func ipLockKey(ip string) string {
+	return "lockout:ip:" + ip
+}
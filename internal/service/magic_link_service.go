@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/interfaces"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// magicLinkMaxCodeAttempts is the number of wrong codes after which a magic link is invalidated
+const magicLinkMaxCodeAttempts = 5
+
+type MagicLinkService interface {
+	Send(email string) error
+	Verify(req *domain.MagicLinkVerifyRequest, client domain.ClientInfo) (*domain.AuthResponse, error)
+}
+
+type magicLinkService struct {
+	userRepo       repository.UserRepository
+	magicLinkRepo  repository.MagicLinkRepository
+	throttleRepo   repository.ThrottleRepository
+	lockoutService LockoutService
+	authService    AuthService
+	keys           *utils.KeySet
+	mailer         interfaces.Mailer
+	authConfig     config.AuthConfig
+}
+
+func NewMagicLinkService(userRepo repository.UserRepository, magicLinkRepo repository.MagicLinkRepository, throttleRepo repository.ThrottleRepository, lockoutService LockoutService, authService AuthService, keys *utils.KeySet, mailer interfaces.Mailer, authConfig config.AuthConfig) MagicLinkService {
+	return &magicLinkService{
+		userRepo:       userRepo,
+		magicLinkRepo:  magicLinkRepo,
+		throttleRepo:   throttleRepo,
+		lockoutService: lockoutService,
+		authService:    authService,
+		keys:           keys,
+		mailer:         mailer,
+		authConfig:     authConfig,
+	}
+}
+
+// Send emails a signed login link and a 6-digit code for the account. Requests are
+// rate limited per address, and unknown or inactive addresses are silently ignored
+// so the endpoint cannot be used to discover registered emails.
+func (s *magicLinkService) Send(email string) error {
+	requests, err := s.throttleRepo.Hit("magic_link:"+strings.ToLower(email), s.authConfig.MagicLinkRequestWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check magic link throttle: %w", err)
+	}
+	if requests > int64(s.authConfig.MagicLinkRequestLimit) {
+		return errors.New("too many magic link requests")
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	// A delivery failure is logged rather than returned so the response does
+	// not reveal that the account exists
+	if err := s.sendMagicLink(user); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to send magic link email")
+	}
+
+	return nil
+}
+
+// sendMagicLink replaces any pending magic link of the user with a new one and emails it
+func (s *magicLinkService) sendMagicLink(user *domain.User) error {
+	code, err := generateLoginCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate login code: %w", err)
+	}
+
+	claims := &utils.JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		TokenUse: utils.TokenUseMagicLink,
+	}
+	token, err := s.keys.GenerateToken(claims, s.authConfig.MagicLinkExpiration)
+	if err != nil {
+		return fmt.Errorf("failed to generate magic link token: %w", err)
+	}
+
+	link := &repository.MagicLink{
+		TokenID:  claims.ID,
+		CodeHash: hashToken(code),
+	}
+	if err := s.magicLinkRepo.Save(user.ID, link, s.authConfig.MagicLinkExpiration); err != nil {
+		return fmt.Errorf("failed to store magic link: %w", err)
+	}
+
+	loginURL := fmt.Sprintf("%s?token=%s", s.authConfig.MagicLinkURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below within %s to sign in:\n\n%s\n\n"+
+		"Or enter this code: %s\n\n"+
+		"If you did not try to sign in, you can ignore this email.\n",
+		user.FirstName, s.authConfig.MagicLinkExpiration, loginURL, code)
+
+	return s.mailer.Send(context.Background(), user.Email, "Your sign-in link", body)
+}
+
+// Verify exchanges a magic link token, or the email address and code, for the usual
+// login response. Only the latest link of a user is valid and it can be used once.
+func (s *magicLinkService) Verify(req *domain.MagicLinkVerifyRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	if req.Token != "" {
+		return s.verifyToken(req.Token, client)
+	}
+	if req.Email != "" && req.Code != "" {
+		return s.verifyCode(req.Email, req.Code, client)
+	}
+	return nil, errors.New("token or email and code are required")
+}
+
+func (s *magicLinkService) verifyToken(token string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	claims, err := s.keys.ValidateToken(token)
+	if err != nil || claims.TokenUse != utils.TokenUseMagicLink || claims.ID == "" {
+		return nil, errors.New("invalid or expired magic link")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired magic link")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return nil, errors.New("invalid or expired magic link")
+	}
+
+	if err := s.lockoutService.Check(user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	link, err := s.magicLinkRepo.Get(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get magic link: %w", err)
+	}
+	if link == nil || link.TokenID != claims.ID {
+		return nil, errors.New("invalid or expired magic link")
+	}
+
+	return s.completeLogin(user, link, client)
+}
+
+func (s *magicLinkService) verifyCode(email, code string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	if err := s.lockoutService.Check(email, client.IP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, s.codeFailed(email, client)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	link, err := s.magicLinkRepo.Get(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get magic link: %w", err)
+	}
+	if link == nil {
+		return nil, s.codeFailed(email, client)
+	}
+
+	// A 6-digit code is only safe with few guesses, so the link is dropped after too many
+	attempts, err := s.throttleRepo.Hit("magic_link_code:"+link.TokenID, s.authConfig.MagicLinkExpiration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count magic link attempts: %w", err)
+	}
+	if attempts > magicLinkMaxCodeAttempts {
+		if err := s.magicLinkRepo.Delete(user.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete magic link: %w", err)
+		}
+		return nil, errors.New("too many magic link attempts")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(code)), []byte(link.CodeHash)) != 1 {
+		return nil, s.codeFailed(email, client)
+	}
+
+	return s.completeLogin(user, link, client)
+}
+
+// completeLogin consumes the magic link and logs the user in. Opening the link
+// proves ownership of the address, so the email is marked as verified.
+func (s *magicLinkService) completeLogin(user *domain.User, link *repository.MagicLink, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	fresh, err := s.magicLinkRepo.Consume(user.ID, link.TokenID, s.authConfig.MagicLinkExpiration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume magic link: %w", err)
+	}
+	if !fresh {
+		return nil, errors.New("invalid or expired magic link")
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"ip":      client.IP,
+		"event":   "magic_link_login",
+	}).Info("Audit Log - Magic link login")
+
+	return s.authService.CompleteLogin(user, client)
+}
+
+// codeFailed records a wrong code like a failed password login
+func (s *magicLinkService) codeFailed(email string, client domain.ClientInfo) error {
+	if err := s.lockoutService.RecordFailure(email, client.IP); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"email": email,
+		"ip":    client.IP,
+		"event": "magic_link_failed",
+	}).Warn("Audit Log - Invalid magic link code")
+
+	return errors.New("invalid or expired magic link")
+}
+
+// generateLoginCode returns a random 6-digit code
+func generateLoginCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	mfaRecoveryCodeCount = 10
+	mfaMaxVerifyAttempts = 5
+)
+
+type MFAService interface {
+	Enroll(userID uint) (*domain.MFAEnrollResponse, error)
+	ConfirmEnrollment(userID uint, code string, client domain.ClientInfo) error
+	Disable(userID uint, password, code string, client domain.ClientInfo) error
+	Verify(mfaToken, code string, client domain.ClientInfo) (*domain.AuthResponse, error)
+}
+
+type mfaService struct {
+	userRepo       repository.UserRepository
+	mfaRepo        repository.MFARepository
+	tokenRepo      repository.TokenRepository
+	throttleRepo   repository.ThrottleRepository
+	lockoutService LockoutService
+	authService    AuthService
+	hasher         utils.PasswordHasher
+	keys           *utils.KeySet
+	encryptionKey  []byte
+	authConfig     config.AuthConfig
+}
+
+func NewMFAService(userRepo repository.UserRepository, mfaRepo repository.MFARepository, tokenRepo repository.TokenRepository, throttleRepo repository.ThrottleRepository, lockoutService LockoutService, authService AuthService, hasher utils.PasswordHasher, keys *utils.KeySet, encryptionKey []byte, authConfig config.AuthConfig) MFAService {
+	return &mfaService{
+		userRepo:       userRepo,
+		mfaRepo:        mfaRepo,
+		tokenRepo:      tokenRepo,
+		throttleRepo:   throttleRepo,
+		lockoutService: lockoutService,
+		authService:    authService,
+		hasher:         hasher,
+		keys:           keys,
+		encryptionKey:  encryptionKey,
+		authConfig:     authConfig,
+	}
+}
+
+// Enroll generates a new TOTP secret and recovery codes. MFA is only enabled once
+// the user confirms the enrollment with a valid code.
+func (s *mfaService) Enroll(userID uint) (*domain.MFAEnrollResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return nil, errors.New("mfa already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate mfa secret: %w", err)
+	}
+
+	encrypted, err := utils.Encrypt(s.encryptionKey, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt mfa secret: %w", err)
+	}
+
+	user.MFASecret = encrypted
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return &domain.MFAEnrollResponse{
+		Secret:        secret,
+		OTPAuthURI:    utils.TOTPURI(s.authConfig.MFAIssuer, user.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmEnrollment enables MFA after the user proves the authenticator app is set up.
+// Wrong codes count toward the account lockout.
+func (s *mfaService) ConfirmEnrollment(userID uint, code string, client domain.ClientInfo) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.MFAEnabled {
+		return errors.New("mfa already enabled")
+	}
+	if user.MFASecret == "" {
+		return errors.New("mfa enrollment not started")
+	}
+
+	if err := s.lockoutService.Check(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	valid, err := s.verifyTOTP(user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return s.codeFailed(user, client)
+	}
+
+	user.MFAEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "mfa_enabled",
+	}).Info("Audit Log - MFA enabled")
+
+	return nil
+}
+
+// Disable turns MFA off. The user has to re-authenticate with their password and
+// a current TOTP or recovery code. Wrong passwords and codes count toward the
+// account lockout.
+func (s *mfaService) Disable(userID uint, password, code string, client domain.ClientInfo) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.MFAEnabled {
+		return errors.New("mfa not enabled")
+	}
+
+	if err := s.lockoutService.Check(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	if match, _, _ := s.hasher.Verify(user.Password, password); !match {
+		if err := s.lockoutService.RecordFailure(user.Email, client.IP); err != nil {
+			return err
+		}
+		return errors.New("invalid password")
+	}
+
+	valid, err := s.verifyCode(user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return s.codeFailed(user, client)
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if err := s.mfaRepo.DeleteRecoveryCodes(user.ID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "mfa_disabled",
+	}).Info("Audit Log - MFA disabled")
+
+	return nil
+}
+
+// Verify exchanges an MFA challenge token and a TOTP or recovery code for a token pair.
+// Challenge tokens are single-use and allow a limited number of attempts. Wrong codes
+// also count toward the account lockout, since every password login starts a new challenge.
+func (s *mfaService) Verify(mfaToken, code string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	claims, err := s.keys.ValidateToken(mfaToken)
+	if err != nil || claims.TokenUse != utils.TokenUseMFAChallenge || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	revoked, err := s.tokenRepo.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+
+	attempts, err := s.throttleRepo.Hit("mfa:"+claims.ID, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count mfa attempts: %w", err)
+	}
+	if attempts > mfaMaxVerifyAttempts {
+		if err := s.tokenRepo.RevokeToken(claims.ID, ttl); err != nil {
+			return nil, fmt.Errorf("failed to revoke mfa token: %w", err)
+		}
+		return nil, errors.New("too many mfa attempts")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired mfa token")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+	if !user.MFAEnabled {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	if err := s.lockoutService.Check(user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	valid, err := s.verifyCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, s.codeFailed(user, client)
+	}
+
+	// The challenge cannot be used again
+	if err := s.tokenRepo.RevokeToken(claims.ID, ttl); err != nil {
+		return nil, fmt.Errorf("failed to revoke mfa token: %w", err)
+	}
+
+	return s.authService.IssueTokens(user, client)
+}
+
+// codeFailed records a wrong MFA code like a failed password login
+func (s *mfaService) codeFailed(user *domain.User, client domain.ClientInfo) error {
+	if err := s.lockoutService.RecordFailure(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"ip":      client.IP,
+		"event":   "mfa_failed",
+	}).Warn("Audit Log - Invalid MFA code")
+
+	return errors.New("invalid mfa code")
+}
+
+func (s *mfaService) getUser(userID uint) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// verifyCode accepts either a TOTP code or an unused recovery code
+func (s *mfaService) verifyCode(user *domain.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		return s.verifyTOTP(user, code)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if used {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"event":   "mfa_recovery_code_used",
+		}).Info("Audit Log - MFA recovery code used")
+	}
+
+	return used, nil
+}
+
+// verifyTOTP checks a TOTP code and rejects codes that were already used
+func (s *mfaService) verifyTOTP(user *domain.User, code string) (bool, error) {
+	secret, err := utils.Decrypt(s.encryptionKey, user.MFASecret)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt mfa secret: %w", err)
+	}
+
+	step, ok := utils.ValidateTOTPCode(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	// A code stays valid for the whole skew window, so remember the used step
+	fresh, err := s.throttleRepo.Acquire(fmt.Sprintf("totp:%d:%d", user.ID, step), (2*utils.TOTPSkew+1)*utils.TOTPPeriod)
+	if err != nil {
+		return false, fmt.Errorf("failed to check mfa code reuse: %w", err)
+	}
+
+	return fresh, nil
+}
+
+// generateRecoveryCodes returns the codes shown to the user and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, mfaRecoveryCodeCount)
+	hashes := make([]string, mfaRecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalizes the code so it can be entered with or without the dash
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hashToken(normalized)
+}
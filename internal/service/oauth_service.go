@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/oauth"
+	"go-template-structure/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+type OAuthService interface {
+	AuthorizationURL(provider string) (authURL, state string, err error)
+	Callback(provider, state, boundState, code string, client domain.ClientInfo) (*domain.AuthResponse, error)
+}
+
+type oauthService struct {
+	providers    map[string]oauth.Provider
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	stateRepo    repository.OAuthStateRepository
+	authService  AuthService
+	hasher       utils.PasswordHasher
+	oauthConfig  config.OAuthConfig
+}
+
+func NewOAuthService(providers map[string]oauth.Provider, userRepo repository.UserRepository, identityRepo repository.UserIdentityRepository, stateRepo repository.OAuthStateRepository, authService AuthService, hasher utils.PasswordHasher, oauthConfig config.OAuthConfig) OAuthService {
+	return &oauthService{
+		providers:    providers,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		authService:  authService,
+		hasher:       hasher,
+		oauthConfig:  oauthConfig,
+	}
+}
+
+// AuthorizationURL starts the authorization code flow and returns the provider URL
+// to redirect the user to. The state, nonce and PKCE verifier are kept server-side
+// until the callback; the state is also returned so the caller can bind it to the browser.
+func (s *oauthService) AuthorizationURL(providerName string) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", errors.New("unknown provider")
+	}
+
+	state, err := oauth.RandomString()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := oauth.RandomString()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier, err := oauth.RandomString()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, oauth.CodeChallenge(verifier))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build authorization url: %w", err)
+	}
+
+	err = s.stateRepo.Save(state, &repository.OAuthState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, s.oauthConfig.StateExpiration)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to store state: %w", err)
+	}
+
+	return authURL, state, nil
+}
+
+// Callback completes the authorization code flow, finds or creates the local user
+// and logs them in through the auth service. boundState is the state held by the browser
+// that started the flow; it must match so a callback URL cannot be replayed in another session.
+func (s *oauthService) Callback(providerName, state, boundState, code string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	if state == "" || code == "" || subtle.ConstantTimeCompare([]byte(state), []byte(boundState)) != 1 {
+		return nil, errors.New("invalid oauth state")
+	}
+
+	pending, err := s.stateRepo.Consume(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	if pending == nil || pending.Provider != providerName {
+		return nil, errors.New("invalid oauth state")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	identity, err := provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"provider": providerName,
+			"error":    err.Error(),
+		}).Warn("OAuth code exchange failed")
+		return nil, errors.New("oauth authentication failed")
+	}
+
+	user, err := s.resolveUser(providerName, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":  user.ID,
+		"provider": providerName,
+		"ip":       client.IP,
+		"event":    "oauth_login",
+	}).Info("Audit Log - OAuth login")
+
+	return s.authService.CompleteLogin(user, client)
+}
+
+// resolveUser returns the user linked to the identity. An unlinked identity is linked
+// to the account with the same email only if the provider verified that email,
+// otherwise anyone could take over an account by registering its address elsewhere.
+func (s *oauthService) resolveUser(providerName string, identity *oauth.Identity) (*domain.User, error) {
+	linked, err := s.identityRepo.GetByProviderSubject(providerName, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(linked.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("user not found")
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.New("oauth email not verified")
+	}
+
+	user, err := s.userRepo.GetByEmail(identity.Email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user, err = s.createUser(identity); err != nil {
+			return nil, err
+		}
+	} else if user.EmailVerifiedAt == nil {
+		// The provider has proven ownership of the address
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	err = s.identityRepo.Create(&domain.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":  user.ID,
+		"provider": providerName,
+		"event":    "oauth_identity_linked",
+	}).Info("Audit Log - OAuth identity linked")
+
+	return user, nil
+}
+
+// createUser registers a new user from a provider identity. The account gets a random
+// password nobody knows; the user can set one through the password reset flow.
+func (s *oauthService) createUser(identity *oauth.Identity) (*domain.User, error) {
+	password, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	username, err := s.availableUsername(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		Email:           identity.Email,
+		Username:        username,
+		Password:        hashedPassword,
+		FirstName:       identity.GivenName,
+		LastName:        identity.FamilyName,
+		Role:            domain.RoleUser,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// availableUsername derives a username from the email's local part, adding a random
+// suffix when it is already taken
+func (s *oauthService) availableUsername(email string) (string, error) {
+	base := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(strings.SplitN(email, "@", 2)[0]))
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		_, err := s.userRepo.GetByUsername(candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate username: %w", err)
+		}
+		candidate = base + "_" + suffix
+	}
+
+	return "", errors.New("failed to generate a unique username")
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/interfaces"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// PasswordPolicyError is returned when a new password violates the password policy
+type PasswordPolicyError struct {
+	Violations []utils.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy"
+}
+
+// checkPasswordPolicy returns a *PasswordPolicyError if the password violates the policy
+func checkPasswordPolicy(policy config.PasswordConfig, breachedChecker utils.BreachedPasswordChecker, password string) error {
+	violations, err := passwordViolations(policy, breachedChecker, password)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// passwordViolations checks the password against the policy rules and the breach corpus
+func passwordViolations(policy config.PasswordConfig, breachedChecker utils.BreachedPasswordChecker, password string) ([]utils.PasswordViolation, error) {
+	violations := utils.CheckPasswordPolicy(policy, password)
+
+	breached, err := breachedChecker.IsBreached(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check breached passwords: %w", err)
+	}
+	if breached {
+		violations = append(violations, utils.PasswordViolation{
+			Rule:    "password_compromised",
+			Message: "has appeared in a data breach, choose a different password",
+		})
+	}
+
+	return violations, nil
+}
+
+type PasswordService interface {
+	ForgotPassword(email string) error
+	ResetPassword(token, newPassword string) error
+	ChangePassword(userID uint, currentSessionID, currentPassword, newPassword string, client domain.ClientInfo) error
+}
+
+type passwordService struct {
+	userRepo        repository.UserRepository
+	resetRepo       repository.PasswordResetRepository
+	historyRepo     repository.PasswordHistoryRepository
+	tokenRepo       repository.TokenRepository
+	sessionRepo     repository.SessionRepository
+	throttleRepo    repository.ThrottleRepository
+	lockoutService  LockoutService
+	sessionService  SessionService
+	hasher          utils.PasswordHasher
+	breachedChecker utils.BreachedPasswordChecker
+	mailer          interfaces.Mailer
+	authConfig      config.AuthConfig
+	passwordConfig  config.PasswordConfig
+	jwtConfig       config.JWTConfig
+}
+
+func NewPasswordService(userRepo repository.UserRepository, resetRepo repository.PasswordResetRepository, historyRepo repository.PasswordHistoryRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository, throttleRepo repository.ThrottleRepository, lockoutService LockoutService, sessionService SessionService, hasher utils.PasswordHasher, breachedChecker utils.BreachedPasswordChecker, mailer interfaces.Mailer, authConfig config.AuthConfig, passwordConfig config.PasswordConfig, jwtConfig config.JWTConfig) PasswordService {
+	return &passwordService{
+		userRepo:        userRepo,
+		resetRepo:       resetRepo,
+		historyRepo:     historyRepo,
+		tokenRepo:       tokenRepo,
+		sessionRepo:     sessionRepo,
+		throttleRepo:    throttleRepo,
+		lockoutService:  lockoutService,
+		sessionService:  sessionService,
+		hasher:          hasher,
+		breachedChecker: breachedChecker,
+		mailer:          mailer,
+		authConfig:      authConfig,
+		passwordConfig:  passwordConfig,
+		jwtConfig:       jwtConfig,
+	}
+}
+
+// ForgotPassword emails a reset link to the user. Requests are rate limited per
+// address, and it succeeds whether or not the account exists so the endpoint cannot
+// be used to discover registered emails.
+func (s *passwordService) ForgotPassword(email string) error {
+	requests, err := s.throttleRepo.Hit("password_reset:"+strings.ToLower(email), s.authConfig.PasswordResetRequestWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check password reset throttle: %w", err)
+	}
+	if requests > int64(s.authConfig.PasswordResetRequestLimit) {
+		return errors.New("too many password reset requests")
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	// Generate a random token; only its hash is stored
+	token, err := generateToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	resetToken := &domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.authConfig.PasswordResetExpiration),
+	}
+	if err := s.resetRepo.Create(resetToken); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s?token=%s", s.authConfig.PasswordResetURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. "+
+		"Use the link below within %s to choose a new one:\n\n%s\n\n"+
+		"If you did not request a password reset, you can ignore this email.\n",
+		user.FirstName, s.authConfig.PasswordResetExpiration, link)
+
+	// A delivery failure is logged rather than returned so the response does
+	// not reveal that the account exists
+	if err := s.mailer.Send(context.Background(), user.Email, "Reset your password", body); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to send password reset email")
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token. Tokens are single-use,
+// expire, and become invalid once the password has been changed. All existing
+// sessions of the user are revoked.
+func (s *passwordService) ResetPassword(token, newPassword string) error {
+	resetToken, err := s.resetRepo.GetByTokenHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid or expired reset token")
+		}
+		return fmt.Errorf("failed to get reset token: %w", err)
+	}
+
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.GetByID(resetToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid or expired reset token")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Tokens issued before the last password change are no longer valid
+	if user.PasswordChangedAt != nil && resetToken.CreatedAt.Before(*user.PasswordChangedAt) {
+		return errors.New("invalid or expired reset token")
+	}
+
+	// Check the policy before consuming the token so the user can retry with the same link
+	if err := s.validateNewPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// Consume the token before changing anything
+	consumed, err := s.resetRepo.MarkUsed(resetToken.ID)
+	if err != nil {
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+	if !consumed {
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// Revoke every token issued to the user
+	if err := s.tokenRepo.RevokeUserTokens(user.ID, s.jwtConfig.RefreshExpiration); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.sessionRepo.RevokeByUserID(user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "password_reset",
+	}).Info("Audit Log - Password reset completed, all sessions revoked")
+
+	return nil
+}
+
+// ChangePassword sets a new password for an authenticated user who knows the
+// current one. Wrong current passwords count toward the account lockout, and every
+// other session of the user is signed out.
+func (s *passwordService) ChangePassword(userID uint, currentSessionID, currentPassword, newPassword string, client domain.ClientInfo) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.lockoutService.Check(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	if match, _, _ := s.hasher.Verify(user.Password, currentPassword); !match {
+		if err := s.lockoutService.RecordFailure(user.Email, client.IP); err != nil {
+			return err
+		}
+		return errors.New("current password is incorrect")
+	}
+
+	if err := s.validateNewPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.sessionService.RevokeOthers(user.ID, user.ID, currentSessionID); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"event":   "password_changed",
+	}).Info("Audit Log - Password changed, other sessions revoked")
+
+	return nil
+}
+
+// validateNewPassword checks the password policy and rejects the current password
+// and the user's previous passwords kept in the history
+func (s *passwordService) validateNewPassword(user *domain.User, newPassword string) error {
+	violations, err := passwordViolations(s.passwordConfig, s.breachedChecker, newPassword)
+	if err != nil {
+		return err
+	}
+
+	if s.passwordConfig.HistoryCount > 0 {
+		reused, err := s.isRecentPassword(user, newPassword)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, utils.PasswordViolation{
+				Rule:    "history",
+				Message: fmt.Sprintf("must not match any of the last %d passwords", s.passwordConfig.HistoryCount),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isRecentPassword reports whether the password matches the current password or
+// one of the previous HistoryCount-1 passwords
+func (s *passwordService) isRecentPassword(user *domain.User, password string) (bool, error) {
+	if match, _, _ := s.hasher.Verify(user.Password, password); match {
+		return true, nil
+	}
+
+	history, err := s.historyRepo.ListRecentByUserID(user.ID, s.passwordConfig.HistoryCount-1)
+	if err != nil {
+		return false, fmt.Errorf("failed to get password history: %w", err)
+	}
+	for _, entry := range history {
+		if match, _, _ := s.hasher.Verify(entry.PasswordHash, password); match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// setPassword stores a new password hash, moves the old one into the password
+// history and invalidates outstanding reset tokens
+func (s *passwordService) setPassword(user *domain.User, newPassword string) error {
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	previousHash := user.Password
+
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// The current password counts as one of the remembered ones
+	if keep := s.passwordConfig.HistoryCount - 1; keep > 0 {
+		if err := s.historyRepo.Create(&domain.PasswordHistory{UserID: user.ID, PasswordHash: previousHash}); err != nil {
+			return fmt.Errorf("failed to store password history: %w", err)
+		}
+		if err := s.historyRepo.Prune(user.ID, keep); err != nil {
+			return fmt.Errorf("failed to prune password history: %w", err)
+		}
+	}
+
+	if err := s.resetRepo.DeleteByUserID(user.ID); err != nil {
+		return fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+
+	return nil
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type SessionService interface {
+	List(userID uint, currentSessionID string) ([]domain.Session, error)
+	Revoke(actorID, userID uint, sessionID string) error
+	RevokeOthers(actorID, userID uint, currentSessionID string) (int, error)
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	tokenRepo   repository.TokenRepository
+	jwtConfig   config.JWTConfig
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, tokenRepo repository.TokenRepository, jwtConfig config.JWTConfig) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		jwtConfig:   jwtConfig,
+	}
+}
+
+// List returns the user's active sessions and marks the one making the request
+func (s *sessionService) List(userID uint, currentSessionID string) ([]domain.Session, error) {
+	sessions, err := s.sessionRepo.ListActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// Revoke signs a session out. Its refresh token and every access token issued
+// from it stop working immediately.
+func (s *sessionService) Revoke(actorID, userID uint, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session not found")
+		}
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Do not reveal sessions of other users
+	if session.UserID != userID || session.RevokedAt != nil {
+		return errors.New("session not found")
+	}
+
+	if err := s.revoke(session.ID); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id":   actorID,
+		"user_id":    userID,
+		"session_id": session.ID,
+		"event":      "session_revoked",
+	}).Info("Audit Log - Session revoked")
+
+	return nil
+}
+
+// RevokeOthers signs out every session of the user except the current one.
+// An empty currentSessionID signs out all sessions.
+func (s *sessionService) RevokeOthers(actorID, userID uint, currentSessionID string) (int, error) {
+	sessions, err := s.sessionRepo.ListActiveByUserID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := s.revoke(session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"user_id":  userID,
+		"count":    revoked,
+		"event":    "sessions_revoked",
+	}).Info("Audit Log - Sessions revoked")
+
+	return revoked, nil
+}
+
+func (s *sessionService) revoke(sessionID string) error {
+	if err := s.tokenRepo.RevokeFamily(sessionID, s.jwtConfig.RefreshExpiration); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
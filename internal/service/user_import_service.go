@@ -0,0 +1,698 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserImportService interface {
+	Import(actorID uint, body io.Reader, opts domain.UserImportOptions) (*domain.UserImportJob, error)
+	GetJob(id string) (*domain.UserImportJob, error)
+	FailInterruptedJobs() error
+}
+
+// ImportFileError is returned when an import file cannot be read as a whole, as
+// opposed to invalid rows, which are reported in the job results
+type ImportFileError struct {
+	Message string
+}
+
+func (e *ImportFileError) Error() string {
+	return e.Message
+}
+
+var errImportTooLarge = errors.New("import file too large")
+
+// staleImportJobAge is how long a pending or running job may go without progress
+// before it is considered lost with the process that ran it
+const staleImportJobAge = 10 * time.Minute
+
+// maxImportLineLength bounds a line of an NDJSON import
+const maxImportLineLength = 64 * 1024
+
+type userImportService struct {
+	userRepo  repository.UserRepository
+	jobRepo   repository.UserImportJobRepository
+	hasher    utils.PasswordHasher
+	importCfg config.ImportConfig
+}
+
+func NewUserImportService(userRepo repository.UserRepository, jobRepo repository.UserImportJobRepository, hasher utils.PasswordHasher, importCfg config.ImportConfig) UserImportService {
+	return &userImportService{
+		userRepo:  userRepo,
+		jobRepo:   jobRepo,
+		hasher:    hasher,
+		importCfg: importCfg,
+	}
+}
+
+// importRow is a parsed row with its line in the file. Errors holds problems
+// found while parsing or validating it.
+type importRow struct {
+	line   int
+	user   domain.UserImportRow
+	errors []domain.FieldError
+}
+
+// Import reads a CSV, NDJSON or JSON file of users and imports it as a job. Imports up to
+// the async threshold finish before Import returns; larger ones, or any with
+// opts.Async, return a pending job that runs in the background.
+func (s *userImportService) Import(actorID uint, body io.Reader, opts domain.UserImportOptions) (*domain.UserImportJob, error) {
+	if !opts.Format.IsImportable() {
+		return nil, errors.New("unsupported import format")
+	}
+	if opts.DuplicatePolicy == "" {
+		opts.DuplicatePolicy = domain.DuplicateSkip
+	}
+	if !opts.DuplicatePolicy.IsValid() {
+		return nil, errors.New("invalid duplicate policy")
+	}
+
+	if s.importCfg.MaxBytes > 0 {
+		body = &maxBytesReader{r: body, remaining: s.importCfg.MaxBytes, err: errImportTooLarge}
+	}
+	rows, err := s.parse(body, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	validateImportRows(rows)
+
+	job := &domain.UserImportJob{
+		ID:              uuid.New().String(),
+		ActorID:         actorID,
+		Format:          opts.Format,
+		DuplicatePolicy: opts.DuplicatePolicy,
+		DryRun:          opts.DryRun,
+		Status:          domain.ImportJobPending,
+		TotalRows:       len(rows),
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	if opts.Async || len(rows) > s.importCfg.AsyncThreshold {
+		// The job is updated by the background run, so return a copy
+		pending := *job
+		go s.run(job, rows)
+		return &pending, nil
+	}
+
+	s.run(job, rows)
+	return job, nil
+}
+
+func (s *userImportService) GetJob(id string) (*domain.UserImportJob, error) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("import job not found")
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	return job, nil
+}
+
+// FailInterruptedJobs fails the jobs that stopped making progress, e.g. because
+// the server restarted while they ran. Their rows were only held in memory.
+func (s *userImportService) FailInterruptedJobs() error {
+	failed, err := s.jobRepo.FailStale(time.Now().Add(-staleImportJobAge), "interrupted before it finished")
+	if err != nil {
+		return fmt.Errorf("failed to fail interrupted import jobs: %w", err)
+	}
+	if failed > 0 {
+		logger.WithFields(map[string]interface{}{
+			"jobs": failed,
+		}).Warn("Failed interrupted user import jobs")
+	}
+	return nil
+}
+
+// run processes the rows and records the outcome on the job
+func (s *userImportService) run(job *domain.UserImportJob, rows []importRow) {
+	started := time.Now()
+	job.Status = domain.ImportJobRunning
+	job.StartedAt = &started
+	s.saveJob(job)
+
+	results, err := s.process(job, rows)
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Results = results
+	job.ProcessedRows = len(results)
+	job.Created, job.Updated, job.Skipped, job.Duplicates, job.Invalid, job.Failed = 0, 0, 0, 0, 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case domain.ImportRowCreated:
+			job.Created++
+		case domain.ImportRowUpdated:
+			job.Updated++
+		case domain.ImportRowSkipped:
+			job.Skipped++
+		case domain.ImportRowDuplicate:
+			job.Duplicates++
+		case domain.ImportRowInvalid:
+			job.Invalid++
+		case domain.ImportRowFailed:
+			job.Failed++
+		}
+	}
+
+	job.Status = domain.ImportJobCompleted
+	if err != nil {
+		job.Status = domain.ImportJobFailed
+		job.Error = err.Error()
+	}
+	s.saveJob(job)
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": job.ActorID,
+		"job_id":   job.ID,
+		"dry_run":  job.DryRun,
+		"status":   job.Status,
+		"created":  job.Created,
+		"updated":  job.Updated,
+		"event":    "users_imported",
+	}).Info("Audit Log - Users imported from file")
+}
+
+// process plans every row against the existing users, then saves the planned
+// users batch by batch unless this is a dry run. Rows without an outcome when an
+// error stops the import are reported as skipped.
+func (s *userImportService) process(job *domain.UserImportJob, rows []importRow) ([]domain.UserImportRowResult, error) {
+	results := make([]domain.UserImportRowResult, len(rows))
+	planned := make([]domain.UserImportRowStatus, len(rows))
+	users := make([]*domain.User, len(rows))
+	for i, row := range rows {
+		results[i] = domain.UserImportRowResult{Line: row.line, Email: row.user.Email}
+		if len(row.errors) > 0 {
+			results[i].Status = domain.ImportRowInvalid
+			results[i].Errors = row.errors
+		}
+	}
+
+	skipUnfinished := func() {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = domain.ImportRowSkipped
+			}
+		}
+	}
+
+	batchSize := s.batchSize()
+	duplicates := false
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+
+		var emails, usernames []string
+		for i := start; i < end; i++ {
+			if results[i].Status == "" {
+				emails = append(emails, rows[i].user.Email)
+				usernames = append(usernames, rows[i].user.Username)
+			}
+		}
+		if len(emails) == 0 {
+			continue
+		}
+
+		existing, err := s.userRepo.FindByEmailsOrUsernames(emails, usernames)
+		if err != nil {
+			skipUnfinished()
+			return results, fmt.Errorf("failed to look up existing users: %w", err)
+		}
+		byEmail := make(map[string]*domain.User, len(existing))
+		byUsername := make(map[string]*domain.User, len(existing))
+		for j := range existing {
+			byEmail[existing[j].Email] = &existing[j]
+			byUsername[existing[j].Username] = &existing[j]
+		}
+
+		for i := start; i < end; i++ {
+			if results[i].Status != "" {
+				continue
+			}
+			planned[i], users[i] = planImportRow(&results[i], rows[i].user, byEmail, byUsername, job.DuplicatePolicy)
+			duplicates = duplicates || results[i].Status == domain.ImportRowDuplicate
+		}
+	}
+
+	if duplicates && job.DuplicatePolicy == domain.DuplicateFail {
+		skipUnfinished()
+		return results, errors.New("import contains duplicates")
+	}
+
+	if job.DryRun {
+		for i := range results {
+			if planned[i] != "" {
+				results[i].Status = planned[i]
+			}
+		}
+		return results, nil
+	}
+
+	// Users imported without a password hash share one hash of a random password
+	// nobody knows, rather than hashing thousands of random passwords
+	var unusablePassword string
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+
+		var creates, updates []*domain.User
+		var batch []int
+		for i := start; i < end; i++ {
+			if planned[i] == "" {
+				continue
+			}
+			if users[i].Password == "" {
+				if unusablePassword == "" {
+					hash, err := s.unusablePassword()
+					if err != nil {
+						skipUnfinished()
+						return results, err
+					}
+					unusablePassword = hash
+				}
+				users[i].Password = unusablePassword
+			}
+			if planned[i] == domain.ImportRowCreated {
+				creates = append(creates, users[i])
+			} else {
+				updates = append(updates, users[i])
+			}
+			batch = append(batch, i)
+		}
+
+		if len(batch) > 0 {
+			if err := s.userRepo.SaveBatch(creates, updates); err != nil {
+				logger.WithFields(map[string]interface{}{
+					"job_id": job.ID,
+					"error":  err.Error(),
+				}).Error("Failed to save user import batch")
+				for _, i := range batch {
+					results[i].Status = domain.ImportRowFailed
+					results[i].Errors = []domain.FieldError{{Field: "row", Message: "could not be saved, retry the row"}}
+				}
+			} else {
+				for _, i := range batch {
+					results[i].Status = planned[i]
+					results[i].UserID = users[i].ID
+				}
+			}
+		}
+
+		job.ProcessedRows = end
+		s.saveJob(job)
+	}
+
+	return results, nil
+}
+
+// planImportRow decides what happens to a valid row. It returns the status the row
+// gets once saved and the user to save, or sets the final status on result if
+// nothing is saved.
+func planImportRow(result *domain.UserImportRowResult, row domain.UserImportRow, byEmail, byUsername map[string]*domain.User, policy domain.DuplicatePolicy) (domain.UserImportRowStatus, *domain.User) {
+	existing, other := byEmail[row.Email], byUsername[row.Username]
+
+	if existing == nil && other == nil {
+		user := &domain.User{
+			Email:     row.Email,
+			Username:  row.Username,
+			Password:  row.PasswordHash,
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Role:      domain.RoleUser,
+			IsActive:  true,
+		}
+		if row.EmailVerified {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		return domain.ImportRowCreated, user
+	}
+
+	var conflicts []domain.FieldError
+	if existing != nil {
+		conflicts = append(conflicts, domain.FieldError{Field: "email", Message: "already exists"})
+	}
+	if other != nil && other != existing {
+		conflicts = append(conflicts, domain.FieldError{Field: "username", Message: "already exists"})
+	}
+
+	switch {
+	case policy == domain.DuplicateSkip:
+		result.Status = domain.ImportRowSkipped
+		result.Errors = conflicts
+	case policy == domain.DuplicateUpdate && existing != nil && (other == nil || other == existing):
+		// A password change has to revoke sessions and notify the user, which an
+		// import does not do, so existing passwords are never replaced
+		if row.PasswordHash != "" {
+			result.Status = domain.ImportRowInvalid
+			result.Errors = []domain.FieldError{{Field: "password_hash", Message: "cannot be changed for an existing user"}}
+			return "", nil
+		}
+
+		// Empty values keep the existing value
+		if row.Username != "" {
+			existing.Username = row.Username
+		}
+		if row.FirstName != "" {
+			existing.FirstName = row.FirstName
+		}
+		if row.LastName != "" {
+			existing.LastName = row.LastName
+		}
+		if row.EmailVerified && existing.EmailVerifiedAt == nil {
+			now := time.Now()
+			existing.EmailVerifiedAt = &now
+		}
+		return domain.ImportRowUpdated, existing
+	default:
+		// The fail policy, or an update whose username belongs to another user
+		result.Status = domain.ImportRowDuplicate
+		result.Errors = conflicts
+	}
+	return "", nil
+}
+
+// validateImportRows checks every row that parsed, including that no email or
+// username appears twice in the file
+func validateImportRows(rows []importRow) {
+	emailLines := make(map[string]int)
+	usernameLines := make(map[string]int)
+
+	for i := range rows {
+		row := &rows[i]
+		if len(row.errors) > 0 {
+			continue
+		}
+		u := &row.user
+		u.Email = strings.TrimSpace(u.Email)
+		u.Username = strings.TrimSpace(u.Username)
+		u.FirstName = strings.TrimSpace(u.FirstName)
+		u.LastName = strings.TrimSpace(u.LastName)
+		u.PasswordHash = strings.TrimSpace(u.PasswordHash)
+
+		invalid := func(field, message string) {
+			row.errors = append(row.errors, domain.FieldError{Field: field, Message: message})
+		}
+
+		if !utils.ValidateEmail(u.Email) {
+			invalid("email", "must be a valid email address")
+		} else if line, ok := emailLines[u.Email]; ok {
+			invalid("email", fmt.Sprintf("already appears on line %d", line))
+		}
+		if len(u.Username) < 3 || len(u.Username) > 50 {
+			invalid("username", "must be between 3 and 50 characters")
+		} else if line, ok := usernameLines[u.Username]; ok {
+			invalid("username", fmt.Sprintf("already appears on line %d", line))
+		}
+		if len(u.FirstName) > maxNameLength {
+			invalid("first_name", fmt.Sprintf("must be at most %d characters", maxNameLength))
+		}
+		if len(u.LastName) > maxNameLength {
+			invalid("last_name", fmt.Sprintf("must be at most %d characters", maxNameLength))
+		}
+		if u.PasswordHash != "" {
+			if err := utils.ValidatePasswordHash(u.PasswordHash); err != nil {
+				invalid("password_hash", err.Error())
+			}
+		}
+
+		if len(row.errors) == 0 {
+			emailLines[u.Email] = row.line
+			usernameLines[u.Username] = row.line
+		}
+	}
+}
+
+func (s *userImportService) parse(body io.Reader, format domain.UserFileFormat) ([]importRow, error) {
+	var rows []importRow
+	var err error
+	switch format {
+	case domain.UserFileCSV:
+		rows, err = s.parseCSV(body)
+	case domain.UserFileJSON:
+		rows, err = s.parseJSON(body)
+	default:
+		rows, err = s.parseNDJSON(body)
+	}
+	if errors.Is(err, errImportTooLarge) {
+		return nil, errImportTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, &ImportFileError{Message: "import file has no rows"}
+	}
+	return rows, nil
+}
+
+// parseCSV reads a CSV file whose header row names the columns. Rows with the
+// wrong number of fields are reported as invalid; other syntax errors reject the file.
+func (s *userImportService) parseCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &ImportFileError{Message: "import file is empty"}
+	}
+	if err != nil {
+		return nil, csvFileError(err)
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !domain.UserImportColumns[name] {
+			return nil, &ImportFileError{Message: fmt.Sprintf("unknown column: %s", name)}
+		}
+		for _, seen := range columns[:i] {
+			if seen == name {
+				return nil, &ImportFileError{Message: fmt.Sprintf("duplicate column: %s", name)}
+			}
+		}
+		columns[i] = name
+	}
+	for _, required := range []string{"email", "username"} {
+		found := false
+		for _, name := range columns {
+			found = found || name == required
+		}
+		if !found {
+			return nil, &ImportFileError{Message: fmt.Sprintf("missing column: %s", required)}
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if err != nil && !(errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount)) {
+			return nil, csvFileError(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line}
+		if err != nil {
+			row.errors = []domain.FieldError{{Field: "row", Message: fmt.Sprintf("has %d fields, expected %d", len(record), len(columns))}}
+		} else {
+			for i, value := range record {
+				setImportField(&row, columns[i], value)
+			}
+		}
+
+		rows = append(rows, row)
+		if s.importCfg.MaxRows > 0 && len(rows) > s.importCfg.MaxRows {
+			return nil, errors.New("import has too many rows")
+		}
+	}
+
+	return rows, nil
+}
+
+func csvFileError(err error) error {
+	if errors.Is(err, errImportTooLarge) {
+		return err
+	}
+	return &ImportFileError{Message: fmt.Sprintf("malformed CSV: %v", err)}
+}
+
+func setImportField(row *importRow, column, value string) {
+	switch column {
+	case "email":
+		row.user.Email = value
+	case "username":
+		row.user.Username = value
+	case "first_name":
+		row.user.FirstName = value
+	case "last_name":
+		row.user.LastName = value
+	case "password_hash":
+		row.user.PasswordHash = value
+	case "email_verified":
+		if value = strings.TrimSpace(value); value != "" {
+			verified, err := strconv.ParseBool(value)
+			if err != nil {
+				row.errors = append(row.errors, domain.FieldError{Field: "email_verified", Message: "must be true or false"})
+			}
+			row.user.EmailVerified = verified
+		}
+	}
+}
+
+// parseNDJSON reads one JSON object per line. Blank lines are ignored and lines
+// that are not a valid object are reported as invalid.
+func (s *userImportService) parseNDJSON(body io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxImportLineLength)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if line == 1 {
+			text = bytes.TrimPrefix(text, []byte("\ufeff"))
+		}
+		if len(text) == 0 {
+			continue
+		}
+
+		row := importRow{line: line}
+		decodeImportRow(text, &row)
+
+		rows = append(rows, row)
+		if s.importCfg.MaxRows > 0 && len(rows) > s.importCfg.MaxRows {
+			return nil, errors.New("import has too many rows")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &ImportFileError{Message: fmt.Sprintf("line %d is longer than %d bytes", line+1, maxImportLineLength)}
+		}
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// parseJSON reads a {"users": [...]} document. Users that are not a valid object
+// are reported as invalid rows, numbered by their position in the array.
+func (s *userImportService) parseJSON(body io.Reader) ([]importRow, error) {
+	var file struct {
+		Users []json.RawMessage `json:"users"`
+	}
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, errImportTooLarge) {
+			return nil, err
+		}
+		return nil, &ImportFileError{Message: fmt.Sprintf("malformed JSON: %v", err)}
+	}
+	if decoder.More() {
+		return nil, &ImportFileError{Message: "unexpected data after the users object"}
+	}
+	if s.importCfg.MaxRows > 0 && len(file.Users) > s.importCfg.MaxRows {
+		return nil, errors.New("import has too many rows")
+	}
+
+	rows := make([]importRow, len(file.Users))
+	for i, raw := range file.Users {
+		rows[i].line = i + 1
+		decodeImportRow(raw, &rows[i])
+	}
+	return rows, nil
+}
+
+// decodeImportRow decodes a user object of an NDJSON or JSON import into row,
+// recording a value that is not a valid object as a row error
+func decodeImportRow(text []byte, row *importRow) {
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&row.user); err != nil || decoder.More() || text[0] != '{' {
+		message := "must be a JSON object"
+		if err != nil {
+			message = err.Error()
+		}
+		row.user = domain.UserImportRow{}
+		row.errors = []domain.FieldError{{Field: "row", Message: message}}
+	}
+}
+
+// unusablePassword hashes a random password that is thrown away
+func (s *userImportService) unusablePassword() (string, error) {
+	password, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hash, nil
+}
+
+func (s *userImportService) batchSize() int {
+	if s.importCfg.BatchSize < 1 {
+		return 500
+	}
+	return s.importCfg.BatchSize
+}
+
+// saveJob records the job's progress. A failure is logged rather than stopping
+// the import, whose outcome is saved again when it finishes.
+func (s *userImportService) saveJob(job *domain.UserImportJob) {
+	if err := s.jobRepo.Update(job); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"job_id": job.ID,
+			"error":  err.Error(),
+		}).Error("Failed to save user import job")
+	}
+}
+
+// maxBytesReader fails with err once more than remaining bytes are read
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, m.err
+	}
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, m.err
+	}
+	return n, err
+}
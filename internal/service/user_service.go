@@ -2,41 +2,85 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"go-template-structure/internal/config"
 	"go-template-structure/internal/domain"
 	"go-template-structure/internal/interfaces"
 	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/utils"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserService interface {
 	CreateUser(req *domain.CreateUserRequest) (*domain.User, error)
 	GetUser(id uint) (*domain.User, error)
-	GetUsers(page, limit int) ([]domain.User, *domain.PaginationResponse, error)
-	UpdateUser(id uint, req *domain.UpdateUserRequest) (*domain.User, error)
-	DeleteUser(id uint) error
+	GetUsers(query *domain.UserQuery, page, limit int) ([]domain.User, *domain.PaginationResponse, error)
+	GetUsersByCursor(query *domain.UserQuery, cursor string, limit int, includeTotal bool) ([]domain.User, *domain.CursorPaginationResponse, error)
+	ExportUsers(actorID uint, query *domain.UserQuery, write func([]domain.User) error) error
+	UpdateUser(id uint, req *domain.UpdateUserRequest, ifMatch []uint) (*domain.User, error)
+	DeleteUser(id uint, ifMatch []uint) error
+	RestoreUser(actorID, id uint) (*domain.User, error)
+	PurgeUser(actorID, id uint) error
 	GetProfile(userID uint) (*domain.User, error)
-	UpdateProfile(userID uint, req *domain.UpdateUserRequest) (*domain.User, error)
+	UpdateProfile(userID uint, req *domain.UpdateUserRequest, ifMatch []uint) (*domain.User, error)
+	PatchUser(actorID, id uint, patch *domain.PatchUserRequest, ifMatch []uint) (*domain.User, error)
+	PatchProfile(userID uint, patch *domain.PatchUserRequest, ifMatch []uint) (*domain.User, error)
+	ChangeRole(actorID, userID uint, role domain.Role) (*domain.User, error)
+}
+
+// ValidationError is returned when fields of a request are invalid
+type ValidationError struct {
+	Fields []domain.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed"
+}
+
+// maxNameLength bounds first and last names
+const maxNameLength = 100
+
+// exportBatchSize is the number of users read per query during an export
+const exportBatchSize = 500
+
+// EmailVerificationSender emails a verification link to a user. AuthService
+// implements it.
+type EmailVerificationSender interface {
+	SendVerificationEmail(user *domain.User) error
 }
 
 type userService struct {
-	userRepo    repository.UserRepository
-	redisClient interfaces.RedisInterface
-	jwtConfig   config.JWTConfig
+	userRepo     repository.UserRepository
+	tokenRepo    repository.TokenRepository
+	sessionRepo  repository.SessionRepository
+	apiKeyRepo   repository.APIKeyRepository
+	redisClient  interfaces.RedisInterface
+	hasher       utils.PasswordHasher
+	verifier     EmailVerificationSender
+	jwtConfig    config.JWTConfig
+	cursorSecret string // Signs pagination cursors; never the JWT secret
 }
 
-func NewUserService(userRepo repository.UserRepository, redisClient interfaces.RedisInterface, jwtConfig config.JWTConfig) UserService {
+func NewUserService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository, apiKeyRepo repository.APIKeyRepository, redisClient interfaces.RedisInterface, hasher utils.PasswordHasher, verifier EmailVerificationSender, jwtConfig config.JWTConfig, cursorSecret string) UserService {
 	return &userService{
-		userRepo:    userRepo,
-		redisClient: redisClient,
-		jwtConfig:   jwtConfig,
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		sessionRepo:  sessionRepo,
+		apiKeyRepo:   apiKeyRepo,
+		redisClient:  redisClient,
+		hasher:       hasher,
+		verifier:     verifier,
+		jwtConfig:    jwtConfig,
+		cursorSecret: cursorSecret,
 	}
 }
 
@@ -52,7 +96,7 @@ func (s *userService) CreateUser(req *domain.CreateUserRequest) (*domain.User, e
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -61,9 +105,10 @@ func (s *userService) CreateUser(req *domain.CreateUserRequest) (*domain.User, e
 	user := &domain.User{
 		Email:     req.Email,
 		Username:  req.Username,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Role:      domain.RoleUser,
 		IsActive:  true,
 	}
 
@@ -98,7 +143,7 @@ func (s *userService) GetUser(id uint) (*domain.User, error) {
 	return user, nil
 }
 
-func (s *userService) GetUsers(page, limit int) ([]domain.User, *domain.PaginationResponse, error) {
+func (s *userService) GetUsers(query *domain.UserQuery, page, limit int) ([]domain.User, *domain.PaginationResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -108,7 +153,7 @@ func (s *userService) GetUsers(page, limit int) ([]domain.User, *domain.Paginati
 
 	offset := (page - 1) * limit
 
-	users, total, err := s.userRepo.List(offset, limit)
+	users, total, err := s.userRepo.List(query, offset, limit)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -125,7 +170,138 @@ func (s *userService) GetUsers(page, limit int) ([]domain.User, *domain.Paginati
 	return users, pagination, nil
 }
 
-func (s *userService) UpdateUser(id uint, req *domain.UpdateUserRequest) (*domain.User, error) {
+// userCursor is the signed content of a user list cursor. Filter ties it to the
+// filters it was issued for, so it cannot be replayed against a different list.
+type userCursor struct {
+	domain.UserKeyset
+	Filter string `json:"f"`
+}
+
+// GetUsersByCursor pages through users by (created_at, id) without counting or
+// skipping rows. An empty cursor starts at the beginning of the list.
+func (s *userService) GetUsersByCursor(query *domain.UserQuery, cursor string, limit int, includeTotal bool) ([]domain.User, *domain.CursorPaginationResponse, error) {
+	if query == nil {
+		query = &domain.UserQuery{}
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	if len(query.Sort) > 1 || (len(query.Sort) == 1 && query.Sort[0].Field != "created_at") {
+		return nil, nil, errors.New("cursor pagination only supports sorting by created_at")
+	}
+
+	filter, err := userQueryHash(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var position *domain.UserKeyset
+	if cursor != "" {
+		var decoded userCursor
+		if err := utils.DecodeCursor(s.cursorSecret, cursor, &decoded); err != nil || decoded.Filter != filter {
+			return nil, nil, errors.New("invalid cursor")
+		}
+		position = &decoded.UserKeyset
+	}
+
+	users, more, err := s.userRepo.ListByKeyset(query, position, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	// A page has a next page if reading forward found more users, or if it was
+	// reached by reading backwards; and the other way round for the previous page.
+	backward := position != nil && position.Before
+	hasNext := more || backward
+	hasPrev := (backward && more) || (!backward && position != nil)
+
+	// Empty pages continue from the requested position
+	var first, last *domain.UserKeyset
+	if len(users) > 0 {
+		first = &domain.UserKeyset{CreatedAt: users[0].CreatedAt, ID: users[0].ID}
+		last = &domain.UserKeyset{CreatedAt: users[len(users)-1].CreatedAt, ID: users[len(users)-1].ID}
+	} else if position != nil {
+		first = &domain.UserKeyset{CreatedAt: position.CreatedAt, ID: position.ID}
+		last = first
+	}
+
+	pagination := &domain.CursorPaginationResponse{Limit: limit}
+	if hasNext && last != nil {
+		if pagination.NextCursor, err = utils.EncodeCursor(s.cursorSecret, userCursor{UserKeyset: *last, Filter: filter}); err != nil {
+			return nil, nil, err
+		}
+	}
+	if hasPrev && first != nil {
+		before := *first
+		before.Before = true
+		if pagination.PrevCursor, err = utils.EncodeCursor(s.cursorSecret, userCursor{UserKeyset: before, Filter: filter}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if includeTotal {
+		total, err := s.userRepo.Count(query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to count users: %w", err)
+		}
+		pagination.Total = &total
+	}
+
+	return users, pagination, nil
+}
+
+// ExportUsers hands every user matching the query to write, a page at a time in
+// (created_at, id) order. Pages are read by keyset, so the export neither counts
+// nor skips rows and memory use does not grow with the number of users.
+func (s *userService) ExportUsers(actorID uint, query *domain.UserQuery, write func([]domain.User) error) error {
+	filter := domain.UserQuery{}
+	if query != nil {
+		filter = *query
+	}
+	filter.Sort = nil
+
+	var position *domain.UserKeyset
+	exported := 0
+	for {
+		users, more, err := s.userRepo.ListByKeyset(&filter, position, exportBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to export users: %w", err)
+		}
+		if len(users) > 0 {
+			if err := write(users); err != nil {
+				return err
+			}
+		}
+		exported += len(users)
+		if !more {
+			break
+		}
+		last := users[len(users)-1]
+		position = &domain.UserKeyset{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"exported": exported,
+		"event":    "users_exported",
+	}).Info("Audit Log - Users exported")
+
+	return nil
+}
+
+// userQueryHash identifies the filters and sort of a user list
+func userQueryHash(query *domain.UserQuery) (string, error) {
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode query: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// UpdateUser updates the provided fields. ifMatch holds the versions from an If-Match
+// header; nil places no condition on the update.
+func (s *userService) UpdateUser(id uint, req *domain.UpdateUserRequest, ifMatch []uint) (*domain.User, error) {
 	user, err := s.userRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -133,12 +309,22 @@ func (s *userService) UpdateUser(id uint, req *domain.UpdateUserRequest) (*domai
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	if !matchesVersion(user, ifMatch) {
+		return nil, errors.New("version mismatch")
+	}
 
 	// Update fields if provided
-	if req.Email != "" {
-		user.Email = req.Email
+	emailChanged := req.Email != "" && req.Email != user.Email
+	if emailChanged {
+		if err := s.checkAvailable(s.userRepo.GetByEmail, req.Email, user.ID, "email already in use"); err != nil {
+			return nil, err
+		}
+		setEmail(user, req.Email)
 	}
-	if req.Username != "" {
+	if req.Username != "" && req.Username != user.Username {
+		if err := s.checkAvailable(s.userRepo.GetByUsername, req.Username, user.ID, "username already in use"); err != nil {
+			return nil, err
+		}
 		user.Username = req.Username
 	}
 	if req.FirstName != "" {
@@ -147,35 +333,117 @@ func (s *userService) UpdateUser(id uint, req *domain.UpdateUserRequest) (*domai
 	if req.LastName != "" {
 		user.LastName = req.LastName
 	}
-	if req.Avatar != "" {
-		user.Avatar = req.Avatar
-	}
 
 	if err := s.userRepo.Update(user); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, errors.New("version mismatch")
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
 	// Update cache
 	s.cacheUser(user)
 
+	if emailChanged {
+		s.sendVerificationEmail(user)
+	}
+
 	return user, nil
 }
 
-func (s *userService) DeleteUser(id uint) error {
-	_, err := s.userRepo.GetByID(id)
+// DeleteUser soft deletes a user and revokes everything that lets the user in, so
+// a restore does not bring old sessions or API keys back
+func (s *userService) DeleteUser(id uint, ifMatch []uint) error {
+	user, err := s.userRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user not found")
 		}
 		return fmt.Errorf("failed to get user: %w", err)
 	}
+	if !matchesVersion(user, ifMatch) {
+		return errors.New("version mismatch")
+	}
 
 	if err := s.userRepo.Delete(id); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
-	// Remove from cache
-	s.removeUserFromCache(id)
+	if err := s.revokeAccess(id, true); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RestoreUser undoes the soft delete of a user. It fails if the user's email or
+// username was taken by another user since the user was deleted.
+func (s *userService) RestoreUser(actorID, id uint) (*domain.User, error) {
+	user, err := s.userRepo.GetByIDWithDeleted(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.DeletedAt.Valid {
+		return nil, errors.New("user is not deleted")
+	}
+
+	exists, err := s.userRepo.Exists(user.Email, user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if exists {
+		return nil, errors.New("email or username already in use")
+	}
+
+	if err := s.userRepo.Restore(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user is not deleted")
+		}
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	restored, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Update cache
+	s.cacheUser(restored)
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"user_id":  id,
+		"event":    "user_restored",
+	}).Info("Audit Log - User restored")
+
+	return restored, nil
+}
+
+// PurgeUser permanently deletes a user, whether or not it is soft-deleted
+func (s *userService) PurgeUser(actorID, id uint) error {
+	if actorID == id {
+		return errors.New("cannot purge your own account")
+	}
+
+	if _, err := s.userRepo.GetByIDWithDeleted(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.userRepo.HardDelete(id); err != nil {
+		return fmt.Errorf("failed to purge user: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"user_id":  id,
+		"event":    "user_purged",
+	}).Info("Audit Log - User purged")
 
 	return nil
 }
@@ -184,8 +452,224 @@ func (s *userService) GetProfile(userID uint) (*domain.User, error) {
 	return s.GetUser(userID)
 }
 
-func (s *userService) UpdateProfile(userID uint, req *domain.UpdateUserRequest) (*domain.User, error) {
-	return s.UpdateUser(userID, req)
+func (s *userService) UpdateProfile(userID uint, req *domain.UpdateUserRequest, ifMatch []uint) (*domain.User, error) {
+	return s.UpdateUser(userID, req, ifMatch)
+}
+
+// PatchUser applies a JSON Merge Patch to a user. Every field is validated before
+// anything is changed, and users cannot change privileged fields of their own account.
+func (s *userService) PatchUser(actorID, id uint, patch *domain.PatchUserRequest, ifMatch []uint) (*domain.User, error) {
+	if patch.IsActive.Set && actorID == id {
+		return nil, errors.New("cannot change is_active of your own account")
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !matchesVersion(user, ifMatch) {
+		return nil, errors.New("version mismatch")
+	}
+
+	var fields []domain.FieldError
+	invalid := func(field, message string) {
+		fields = append(fields, domain.FieldError{Field: field, Message: message})
+	}
+
+	if patch.Email.Set {
+		switch {
+		case patch.Email.Null:
+			invalid("email", "cannot be null")
+		case !utils.ValidateEmail(patch.Email.Value):
+			invalid("email", "must be a valid email address")
+		}
+	}
+	if patch.Username.Set {
+		switch {
+		case patch.Username.Null:
+			invalid("username", "cannot be null")
+		case len(patch.Username.Value) < 3 || len(patch.Username.Value) > 50:
+			invalid("username", "must be between 3 and 50 characters")
+		}
+	}
+	for field, value := range map[string]domain.PatchString{"first_name": patch.FirstName, "last_name": patch.LastName} {
+		if value.Set && len(value.Value) > maxNameLength {
+			invalid(field, fmt.Sprintf("must be at most %d characters", maxNameLength))
+		}
+	}
+	if patch.IsActive.Set && patch.IsActive.Null {
+		invalid("is_active", "cannot be null")
+	}
+
+	if len(fields) > 0 {
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	emailChanged := patch.Email.Set && patch.Email.Value != user.Email
+	if emailChanged {
+		if err := s.checkAvailable(s.userRepo.GetByEmail, patch.Email.Value, user.ID, "email already in use"); err != nil {
+			return nil, err
+		}
+		setEmail(user, patch.Email.Value)
+	}
+	if patch.Username.Set && patch.Username.Value != user.Username {
+		if err := s.checkAvailable(s.userRepo.GetByUsername, patch.Username.Value, user.ID, "username already in use"); err != nil {
+			return nil, err
+		}
+		user.Username = patch.Username.Value
+	}
+	// A null value leaves Value empty, which clears the field
+	if patch.FirstName.Set {
+		user.FirstName = patch.FirstName.Value
+	}
+	if patch.LastName.Set {
+		user.LastName = patch.LastName.Value
+	}
+	deactivated := patch.IsActive.Set && user.IsActive && !patch.IsActive.Value
+	if patch.IsActive.Set {
+		user.IsActive = patch.IsActive.Value
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, errors.New("version mismatch")
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if deactivated {
+		if err := s.revokeAccess(user.ID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update cache
+	s.cacheUser(user)
+
+	if emailChanged {
+		s.sendVerificationEmail(user)
+	}
+
+	if patch.IsActive.Set {
+		logger.WithFields(map[string]interface{}{
+			"actor_id":  actorID,
+			"user_id":   user.ID,
+			"is_active": user.IsActive,
+			"event":     "user_active_changed",
+		}).Info("Audit Log - User active status changed")
+	}
+
+	return user, nil
+}
+
+// PatchProfile applies a JSON Merge Patch to the current user
+func (s *userService) PatchProfile(userID uint, patch *domain.PatchUserRequest, ifMatch []uint) (*domain.User, error) {
+	return s.PatchUser(userID, userID, patch, ifMatch)
+}
+
+// setEmail changes the user's email. The new address has not been verified, so
+// the user must verify it again before RequireEmailVerification lets them log in.
+func setEmail(user *domain.User, email string) {
+	user.Email = email
+	user.EmailVerifiedAt = nil
+}
+
+// sendVerificationEmail sends a verification link for a changed email. The update
+// has been saved either way; the user can request a new link.
+func (s *userService) sendVerificationEmail(user *domain.User) {
+	if s.verifier == nil {
+		return
+	}
+	if err := s.verifier.SendVerificationEmail(user); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to send verification email")
+	}
+}
+
+// matchesVersion reports whether the user's version is one of the If-Match
+// versions. A nil ifMatch matches any version.
+func matchesVersion(user *domain.User, ifMatch []uint) bool {
+	if ifMatch == nil {
+		return true
+	}
+	for _, version := range ifMatch {
+		if version == user.Version {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAvailable returns conflictMessage if another user already has the value
+func (s *userService) checkAvailable(lookup func(string) (*domain.User, error), value string, userID uint, conflictMessage string) error {
+	existing, err := lookup(value)
+	if err == nil && existing.ID != userID {
+		return errors.New(conflictMessage)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check user existence: %w", err)
+	}
+	return nil
+}
+
+// ChangeRole grants a role to a user. Revoking a role is done by granting RoleUser.
+// Every token of the user is revoked, so the old role's permissions do not outlive
+// the change; the user signs in again to get the new role.
+func (s *userService) ChangeRole(actorID, userID uint, role domain.Role) (*domain.User, error) {
+	if !role.IsValid() {
+		return nil, errors.New("invalid role")
+	}
+
+	// Prevent admins from locking themselves out
+	if actorID == userID {
+		return nil, errors.New("cannot change your own role")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.Role = role
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	// Revoke every token issued to the user
+	if err := s.revokeAccess(user.ID, false); err != nil {
+		return nil, err
+	}
+
+	// Update cache
+	s.cacheUser(user)
+
+	return user, nil
+}
+
+// revokeAccess revokes every token and session of a user, and with apiKeys also
+// every API key, which outlive a role change but not a deactivation or deletion
+func (s *userService) revokeAccess(userID uint, apiKeys bool) error {
+	if err := s.tokenRepo.RevokeUserTokens(userID, s.jwtConfig.RefreshExpiration); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.sessionRepo.RevokeByUserID(userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if apiKeys {
+		if err := s.apiKeyRepo.RevokeByUserID(userID); err != nil {
+			return fmt.Errorf("failed to revoke api keys: %w", err)
+		}
+	}
+	return nil
 }
 
 // Cache operations
@@ -195,7 +679,7 @@ func (s *userService) cacheUser(user *domain.User) {
 	}
 
 	ctx := context.Background()
-	key := fmt.Sprintf("user:%d", user.ID)
+	key := repository.UserCacheKey(user.ID)
 
 	data, err := json.Marshal(user)
 	if err != nil {
@@ -211,7 +695,7 @@ func (s *userService) getUserFromCache(id uint) *domain.User {
 	}
 
 	ctx := context.Background()
-	key := fmt.Sprintf("user:%d", id)
+	key := repository.UserCacheKey(id)
 
 	data, err := s.redisClient.Get(ctx, key)
 	if err != nil {
@@ -225,13 +709,3 @@ func (s *userService) getUserFromCache(id uint) *domain.User {
 
 	return &user
 }
-
-func (s *userService) removeUserFromCache(id uint) {
-	if s.redisClient == nil {
-		return
-	}
-
-	ctx := context.Background()
-	key := fmt.Sprintf("user:%d", id)
-	s.redisClient.Del(ctx, key)
-}
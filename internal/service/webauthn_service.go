@@ -0,0 +1,315 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/logger"
+	"go-template-structure/pkg/webauthn"
+
+	"gorm.io/gorm"
+)
+
+type WebAuthnService interface {
+	BeginRegistration(userID uint) (*webauthn.CreationOptions, error)
+	FinishRegistration(userID uint, req *domain.RegisterPasskeyRequest) (*domain.WebAuthnCredential, error)
+	BeginLogin() (*webauthn.RequestOptions, error)
+	FinishLogin(resp *webauthn.AssertionResponse, client domain.ClientInfo) (*domain.AuthResponse, error)
+	ListCredentials(userID uint) ([]domain.WebAuthnCredential, error)
+	DeleteCredential(userID, id uint) error
+}
+
+type webAuthnService struct {
+	rp             *webauthn.RelyingParty
+	userRepo       repository.UserRepository
+	credentialRepo repository.WebAuthnCredentialRepository
+	challengeRepo  repository.WebAuthnChallengeRepository
+	lockoutService LockoutService
+	authService    AuthService
+}
+
+func NewWebAuthnService(rp *webauthn.RelyingParty, userRepo repository.UserRepository, credentialRepo repository.WebAuthnCredentialRepository, challengeRepo repository.WebAuthnChallengeRepository, lockoutService LockoutService, authService AuthService) WebAuthnService {
+	return &webAuthnService{
+		rp:             rp,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		challengeRepo:  challengeRepo,
+		lockoutService: lockoutService,
+		authService:    authService,
+	}
+}
+
+// BeginRegistration starts registering a passkey for the user. The challenge is
+// kept server-side until FinishRegistration.
+func (s *webAuthnService) BeginRegistration(userID uint) (*webauthn.CreationOptions, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	credentials, err := s.credentialRepo.ListByUserID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	// Authenticators already holding a passkey for the user should not create another
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		exclude = append(exclude, credentialDescriptor(credential))
+	}
+
+	displayName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	options, err := s.rp.BeginRegistration(webauthn.UserEntity{
+		ID:          webauthn.EncodeBase64URL(userHandle(user.ID)),
+		Name:        user.Email,
+		DisplayName: displayName,
+	}, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &repository.WebAuthnChallenge{Ceremony: repository.WebAuthnCeremonyRegistration, UserID: user.ID}
+	if err := s.challengeRepo.Save(options.Challenge, challenge, s.rp.Timeout()); err != nil {
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return options, nil
+}
+
+// FinishRegistration verifies the authenticator's response and stores the new passkey
+func (s *webAuthnService) FinishRegistration(userID uint, req *domain.RegisterPasskeyRequest) (*domain.WebAuthnCredential, error) {
+	challenge, err := s.consumeChallenge(req.Credential.Response.ClientDataJSON, repository.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.data.UserID != userID {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	verified, err := s.rp.FinishRegistration(challenge.value, &req.Credential)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Passkey registration failed verification")
+		return nil, errors.New("invalid passkey")
+	}
+
+	credentialID := webauthn.EncodeBase64URL(verified.ID)
+	if _, err := s.credentialRepo.GetByCredentialID(credentialID); err == nil {
+		return nil, errors.New("passkey already registered")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check passkey: %w", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	credential := &domain.WebAuthnCredential{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    verified.PublicKey,
+		SignCount:    verified.SignCount,
+		AAGUID:       formatAAGUID(verified.AAGUID),
+		Transports:   strings.Join(verified.Transports, ","),
+	}
+	if err := s.credentialRepo.Create(credential); err != nil {
+		return nil, fmt.Errorf("failed to store passkey: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":    userID,
+		"passkey_id": credential.ID,
+		"event":      "passkey_registered",
+	}).Info("Audit Log - Passkey registered")
+
+	return credential, nil
+}
+
+// BeginLogin starts a passkey login. No account is named up front: the
+// authenticator offers the user's discoverable passkeys for the site.
+func (s *webAuthnService) BeginLogin() (*webauthn.RequestOptions, error) {
+	options, err := s.rp.BeginLogin(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &repository.WebAuthnChallenge{Ceremony: repository.WebAuthnCeremonyLogin}
+	if err := s.challengeRepo.Save(options.Challenge, challenge, s.rp.Timeout()); err != nil {
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return options, nil
+}
+
+// FinishLogin verifies a passkey assertion and completes the login like a password would
+func (s *webAuthnService) FinishLogin(resp *webauthn.AssertionResponse, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	challenge, err := s.consumeChallenge(resp.Response.ClientDataJSON, repository.WebAuthnCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.credentialRepo.GetByCredentialID(resp.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid passkey")
+		}
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+
+	// Discoverable credentials report the user handle they were created for
+	if resp.Response.UserHandle != "" && resp.Response.UserHandle != webauthn.EncodeBase64URL(userHandle(credential.UserID)) {
+		return nil, errors.New("invalid passkey")
+	}
+
+	user, err := s.userRepo.GetByID(credential.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid passkey")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.lockoutService.Check(user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	signCount, err := s.rp.FinishLogin(challenge.value, resp, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"user_id":    user.ID,
+			"passkey_id": credential.ID,
+			"ip":         client.IP,
+			"error":      err.Error(),
+			"event":      "passkey_login_failed",
+		}).Warn("Audit Log - Passkey login failed")
+
+		if err := s.lockoutService.RecordFailure(user.Email, client.IP); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid passkey")
+	}
+
+	if err := s.credentialRepo.UpdateSignCount(credential.ID, signCount); err != nil {
+		return nil, fmt.Errorf("failed to update passkey: %w", err)
+	}
+
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":    user.ID,
+		"passkey_id": credential.ID,
+		"ip":         client.IP,
+		"event":      "passkey_login",
+	}).Info("Audit Log - Passkey login")
+
+	return s.authService.CompleteLogin(user, client)
+}
+
+// ListCredentials returns the user's passkeys
+func (s *webAuthnService) ListCredentials(userID uint) ([]domain.WebAuthnCredential, error) {
+	credentials, err := s.credentialRepo.ListByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	return credentials, nil
+}
+
+// DeleteCredential removes one of the user's passkeys
+func (s *webAuthnService) DeleteCredential(userID, id uint) error {
+	credential, err := s.credentialRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("passkey not found")
+		}
+		return fmt.Errorf("failed to get passkey: %w", err)
+	}
+
+	// Do not reveal passkeys of other users
+	if credential.UserID != userID {
+		return errors.New("passkey not found")
+	}
+
+	if err := s.credentialRepo.Delete(credential.ID); err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"user_id":    userID,
+		"passkey_id": credential.ID,
+		"event":      "passkey_removed",
+	}).Info("Audit Log - Passkey removed")
+
+	return nil
+}
+
+// pendingChallenge is a consumed challenge together with its value
+type pendingChallenge struct {
+	value string
+	data  *repository.WebAuthnChallenge
+}
+
+// consumeChallenge looks up the ceremony a response was made for. Each challenge
+// can only be answered once.
+func (s *webAuthnService) consumeChallenge(clientDataJSON, ceremony string) (*pendingChallenge, error) {
+	value, err := webauthn.Challenge(clientDataJSON)
+	if err != nil || value == "" {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	data, err := s.challengeRepo.Consume(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	if data == nil || data.Ceremony != ceremony {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	return &pendingChallenge{value: value, data: data}, nil
+}
+
+// userHandle is the opaque WebAuthn user ID of a user
+func userHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+func credentialDescriptor(credential domain.WebAuthnCredential) webauthn.CredentialDescriptor {
+	var transports []string
+	if credential.Transports != "" {
+		transports = strings.Split(credential.Transports, ",")
+	}
+
+	return webauthn.CredentialDescriptor{
+		Type:       webauthn.CredentialTypePublicKey,
+		ID:         credential.CredentialID,
+		Transports: transports,
+	}
+}
+
+// formatAAGUID formats an authenticator model ID as a UUID
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	s := hex.EncodeToString(aaguid)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", s[0:8], s[8:12], s[12:16], s[16:20], s[20:32])
+}
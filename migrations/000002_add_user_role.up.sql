@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+-- Only known roles are allowed
+ALTER TABLE users ADD CONSTRAINT chk_users_role CHECK (role IN ('user', 'support', 'admin'));
@@ -0,0 +1,2 @@
+-- Row version for optimistic concurrency control, exposed as the ETag of a user
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
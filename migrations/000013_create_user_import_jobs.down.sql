@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_import_jobs_status;
+DROP INDEX IF EXISTS idx_user_import_jobs_actor_id;
+DROP TABLE IF EXISTS user_import_jobs;
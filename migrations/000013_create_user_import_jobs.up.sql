@@ -0,0 +1,26 @@
+-- actor_id has no foreign key so a job outlives a purged admin, like the audit log
+CREATE TABLE IF NOT EXISTS user_import_jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    actor_id INTEGER NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    duplicate_policy VARCHAR(10) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    invalid INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    results JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_import_jobs_actor_id ON user_import_jobs(actor_id);
+CREATE INDEX IF NOT EXISTS idx_user_import_jobs_status ON user_import_jobs(status);
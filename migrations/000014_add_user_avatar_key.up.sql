@@ -0,0 +1,2 @@
+-- Storage key of an uploaded avatar, so its files can be deleted when it is replaced
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key VARCHAR(255) NOT NULL DEFAULT '';
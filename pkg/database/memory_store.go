@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-template-structure/internal/interfaces"
+)
+
+// memoryEntry is a single value held by MemoryStore
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStore is an in-process implementation of RedisInterface.
+// It is used as a fallback when Redis is disabled or unreachable, so state
+// such as revoked tokens only lives as long as the process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry := memoryEntry{value: fmt.Sprint(value)}
+	if expiration > 0 {
+		entry.expiresAt = now.Add(expiration)
+	}
+	s.entries[key] = entry
+
+	// Drop expired entries once a minute to keep memory bounded
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.entries {
+			if e.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := s.entries[key]; ok && !entry.expired(now) {
+		return false, nil
+	}
+
+	entry := memoryEntry{value: fmt.Sprint(value)}
+	if expiration > 0 {
+		entry.expiresAt = now.Add(expiration)
+	}
+	s.entries[key] = entry
+
+	return true, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return "", interfaces.ErrKeyNotFound
+	}
+	if entry.expired(time.Now()) {
+		delete(s.entries, key)
+		return "", interfaces.ErrKeyNotFound
+	}
+
+	return entry.value, nil
+}
+
+func (s *MemoryStore) Del(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Incr increments the integer stored at key, keeping its expiration like Redis does
+func (s *MemoryStore) Incr(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.expired(time.Now()) {
+		entry = memoryEntry{value: "0"}
+	}
+
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value is not an integer: %w", err)
+	}
+
+	n++
+	entry.value = strconv.FormatInt(n, 10)
+	s.entries[key] = entry
+
+	return n, nil
+}
+
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || entry.expired(now) || entry.value != old {
+		return false, nil
+	}
+
+	entry = memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = now.Add(expiration)
+	}
+	s.entries[key] = entry
+
+	return true, nil
+}
+
+func (s *MemoryStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	entry.expiresAt = time.Now().Add(expiration)
+	s.entries[key] = entry
+
+	return nil
+}
@@ -53,6 +53,14 @@ func autoMigrate(db *gorm.DB) error {
 
 	err := db.AutoMigrate(
 		&domain.User{},
+		&domain.PasswordResetToken{},
+		&domain.PasswordHistory{},
+		&domain.MFARecoveryCode{},
+		&domain.Session{},
+		&domain.APIKey{},
+		&domain.UserIdentity{},
+		&domain.WebAuthnCredential{},
+		&domain.UserImportJob{},
 		// Add more models here
 	)
 
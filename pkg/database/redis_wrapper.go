@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"go-template-structure/internal/interfaces"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -20,10 +23,48 @@ func (w *RedisClientWrapper) Set(ctx context.Context, key string, value interfac
 	return w.client.Set(ctx, key, value, expiration).Err()
 }
 
+func (w *RedisClientWrapper) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return w.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (w *RedisClientWrapper) Get(ctx context.Context, key string) (string, error) {
-	return w.client.Get(ctx, key).Result()
+	value, err := w.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", interfaces.ErrKeyNotFound
+	}
+	return value, err
 }
 
 func (w *RedisClientWrapper) Del(ctx context.Context, keys ...string) error {
 	return w.client.Del(ctx, keys...).Err()
 }
+
+func (w *RedisClientWrapper) Incr(ctx context.Context, key string) (int64, error) {
+	return w.client.Incr(ctx, key).Result()
+}
+
+// compareAndSwapScript sets KEYS[1] to ARGV[2] with a TTL of ARGV[3] milliseconds
+// (none if 0) only if it holds ARGV[1]. Scripts run atomically in Redis.
+var compareAndSwapScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+func (w *RedisClientWrapper) CompareAndSwap(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	swapped, err := compareAndSwapScript.Run(ctx, w.client, []string{key}, old, value, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return swapped == 1, nil
+}
+
+func (w *RedisClientWrapper) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return w.client.Expire(ctx, key, expiration).Err()
+}
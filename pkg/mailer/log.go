@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go-template-structure/pkg/logger"
+)
+
+// LogMailer is a development mailer. It writes emails to a file when a path is
+// configured, otherwise to the application log. Nothing is actually delivered.
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	if m.path == "" {
+		logger.WithFields(map[string]interface{}{
+			"to":      to,
+			"subject": subject,
+			"body":    body,
+		}).Info("Email (log mailer)")
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n---\n",
+		time.Now().Format(time.RFC3339), sanitizeHeader(to), sanitizeHeader(subject), body)
+	if err != nil {
+		return fmt.Errorf("failed to write mail log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/interfaces"
+)
+
+// New creates the mailer selected by the configured driver
+func New(cfg config.MailConfig) (interfaces.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "log", "":
+		return NewLogMailer(cfg.LogFile), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver: %s", cfg.Driver)
+	}
+}
+
+// sanitizeHeader strips line breaks to prevent email header injection
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,100 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go-template-structure/internal/config"
+)
+
+// GitHubProvider implements GitHub's OAuth2 flow. GitHub does not issue ID tokens,
+// so the identity is read from the REST API.
+type GitHubProvider struct {
+	cfg         config.OAuthProviderConfig
+	redirectURL string
+	httpClient  *http.Client
+}
+
+func NewGitHubProvider(cfg config.OAuthProviderConfig, redirectURL string, httpClient *http.Client) *GitHubProvider {
+	return &GitHubProvider{
+		cfg:         cfg,
+		redirectURL: redirectURL,
+		httpClient:  httpClient,
+	}
+}
+
+func (p *GitHubProvider) Name() string {
+	return ProviderGitHub
+}
+
+// AuthCodeURL ignores the nonce because GitHub has no ID token to carry it
+func (p *GitHubProvider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	query := url.Values{}
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	return p.cfg.AuthURL + "?" + query.Encode(), nil
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.httpClient, p.cfg.TokenURL, p.cfg, p.redirectURL, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+
+	apiURL := strings.TrimRight(p.cfg.APIURL, "/")
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(ctx, p.httpClient, apiURL+"/user", token.AccessToken, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, errors.New("github user has no id")
+	}
+
+	// The profile email may be unset or unverified; use the verified primary address
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.httpClient, apiURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject: strconv.FormatInt(user.ID, 10),
+		Name:    user.Name,
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	if name := strings.Fields(user.Name); len(name) > 0 {
+		identity.GivenName = name[0]
+		identity.FamilyName = strings.Join(name[1:], " ")
+	} else {
+		identity.GivenName = user.Login
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,75 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+)
+
+// Provider names
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+	ProviderOIDC   = "oidc"
+)
+
+// Identity is the user information returned by an identity provider
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+}
+
+// Provider implements the authorization code flow with PKCE for one identity provider
+type Provider interface {
+	Name() string
+	AuthCodeURL(state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// NewProviders creates the providers that have a client ID configured
+func NewProviders(cfg config.OAuthConfig) map[string]Provider {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	providers := make(map[string]Provider)
+
+	if cfg.Google.ClientID != "" {
+		providers[ProviderGoogle] = NewOIDCProvider(ProviderGoogle, cfg.Google, redirectURL(cfg, ProviderGoogle), httpClient)
+	}
+	if cfg.GitHub.ClientID != "" {
+		providers[ProviderGitHub] = NewGitHubProvider(cfg.GitHub, redirectURL(cfg, ProviderGitHub), httpClient)
+	}
+	if cfg.OIDC.ClientID != "" {
+		providers[ProviderOIDC] = NewOIDCProvider(ProviderOIDC, cfg.OIDC, redirectURL(cfg, ProviderOIDC), httpClient)
+	}
+
+	return providers
+}
+
+func redirectURL(cfg config.OAuthConfig, provider string) string {
+	return fmt.Sprintf("%s/%s/callback", strings.TrimRight(cfg.RedirectURL, "/"), provider)
+}
+
+// RandomString returns a URL-safe random string, used for state, nonce and PKCE verifiers
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge for a verifier (RFC 7636)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
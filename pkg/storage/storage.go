@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/interfaces"
+)
+
+// New creates the blob storage selected by the configured driver
+func New(cfg config.StorageConfig) (interfaces.BlobStorage, error) {
+	switch cfg.Driver {
+	case "local", "":
+		return NewLocalStorage(cfg.LocalDir, cfg.PublicURL)
+	case "memory":
+		return NewMemoryStorage(cfg.PublicURL), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
+	}
+}
+
+// checkKey rejects keys that are not clean relative paths, so a key can never
+// point outside the storage
+func checkKey(key string) error {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key: %q", key)
+	}
+	return nil
+}
+
+// publicURL joins the base URL and a key
+func publicURL(baseURL, key string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + key
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go-template-structure/internal/config"
+)
+
+// breachedLineMaxLength bounds a "<SHA-1>:<count>" line, including CRLF
+const breachedLineMaxLength = 128
+
+// BreachedPasswordChecker reports whether a password appears in a corpus of
+// passwords exposed in data breaches
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// NewBreachedPasswordChecker creates the checker for the configured breach corpus,
+// or a no-op checker when none is configured
+func NewBreachedPasswordChecker(cfg config.PasswordConfig) (BreachedPasswordChecker, error) {
+	if cfg.BreachedPasswordFile == "" {
+		return NewNoopBreachedPasswordChecker(), nil
+	}
+	return NewFileBreachedPasswordChecker(cfg.BreachedPasswordFile)
+}
+
+type noopBreachedPasswordChecker struct{}
+
+// NewNoopBreachedPasswordChecker creates a checker that accepts every password
+func NewNoopBreachedPasswordChecker() BreachedPasswordChecker {
+	return noopBreachedPasswordChecker{}
+}
+
+func (noopBreachedPasswordChecker) IsBreached(password string) (bool, error) {
+	return false, nil
+}
+
+// fileBreachedPasswordChecker looks passwords up in a local copy of the Have I Been
+// Pwned corpus: one "<SHA-1 hex>:<count>" line per hash, sorted by hash, which is the
+// range API format with the 5-character prefix kept on every line. The file is
+// binary searched in place, so the multi-gigabyte corpus is never loaded into memory.
+type fileBreachedPasswordChecker struct {
+	file *os.File
+	size int64
+}
+
+// NewFileBreachedPasswordChecker opens a sorted SHA-1 breach corpus
+func NewFileBreachedPasswordChecker(path string) (BreachedPasswordChecker, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat breached password file: %w", err)
+	}
+
+	checker := &fileBreachedPasswordChecker{file: file, size: info.Size()}
+
+	// Fail at startup rather than on the first registration
+	if checker.size > 0 {
+		line, err := checker.lineAt(0)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if len(line) < sha1.Size*2 {
+			file.Close()
+			return nil, errors.New("breached password file is not in SHA-1 format")
+		}
+	}
+
+	return checker, nil
+}
+
+func (c *fileBreachedPasswordChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	// Find the first line whose hash is not less than the target. The invariant is
+	// that the line starting at or after lo sorts before the target (or lo is 0),
+	// and the line starting at or after hi does not.
+	lo, hi := int64(0), c.size
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		line, err := c.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+		if line == nil || compareHash(line, target) >= 0 {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	for _, offset := range []int64{lo, hi} {
+		line, err := c.lineAt(offset)
+		if err != nil {
+			return false, err
+		}
+		if line != nil && compareHash(line, target) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lineAt returns the first complete line starting at or after offset, or nil at
+// the end of the file. A line starts at offset 0 or right after a newline.
+func (c *fileBreachedPasswordChecker) lineAt(offset int64) ([]byte, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+
+	buf := make([]byte, 2*breachedLineMaxLength)
+	n, err := c.file.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read breached password file: %w", err)
+	}
+	buf = buf[:n]
+	atEOF := start+int64(n) >= c.size
+
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline < 0 {
+			if atEOF {
+				return nil, nil
+			}
+			return nil, errors.New("breached password file has an overlong line")
+		}
+		buf = buf[newline+1:]
+	}
+
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	if end := bytes.IndexByte(buf, '\n'); end >= 0 {
+		buf = buf[:end]
+	} else if !atEOF {
+		return nil, errors.New("breached password file has an overlong line")
+	}
+
+	return bytes.TrimRight(buf, "\r"), nil
+}
+
+// compareHash compares the hash at the start of a corpus line with an uppercase hex hash
+func compareHash(line, target []byte) int {
+	hash := line
+	if colon := bytes.IndexByte(line, ':'); colon >= 0 {
+		hash = line[:colon]
+	}
+	return bytes.Compare(bytes.ToUpper(hash), target)
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errInvalidCursor is returned for cursors that were altered or not issued by us
+var errInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor serializes value as an opaque pagination cursor signed with
+// HMAC-SHA256, so clients cannot forge or alter a position
+func EncodeCursor(secret string, value interface{}) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	cursor := append(payload, cursorMAC(secret, payload)...)
+	return base64.RawURLEncoding.EncodeToString(cursor), nil
+}
+
+// DecodeCursor verifies a cursor created by EncodeCursor and decodes it into value
+func DecodeCursor(secret, cursor string, value interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) <= sha256.Size {
+		return errInvalidCursor
+	}
+
+	payload, mac := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if !hmac.Equal(mac, cursorMAC(secret, payload)) {
+		return errInvalidCursor
+	}
+	if err := json.Unmarshal(payload, value); err != nil {
+		return errInvalidCursor
+	}
+	return nil
+}
+
+func cursorMAC(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte("cursor:"+secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type")
+	ErrImageTooLarge    = errors.New("image dimensions too large")
+)
+
+// imageDecoders are the accepted image types, by sniffed content type
+var imageDecoders = map[string]struct {
+	format string
+	decode func(io.Reader) (image.Image, error)
+	config func(io.Reader) (image.Config, error)
+}{
+	"image/jpeg": {"jpeg", jpeg.Decode, jpeg.DecodeConfig},
+	"image/png":  {"png", png.Decode, png.DecodeConfig},
+	"image/gif":  {"gif", gif.Decode, gif.DecodeConfig}, // First frame only
+}
+
+// DecodeSquareImage decodes a JPEG, PNG or GIF image, crops it to its centered
+// square and applies the EXIF orientation of a JPEG. The type is sniffed from the
+// data, never taken from the client. It returns the format ("jpeg", "png" or "gif").
+// Images larger than maxPixels are rejected before they are decoded.
+func DecodeSquareImage(data []byte, maxPixels int) (*image.RGBA, string, error) {
+	decoder, ok := imageDecoders[http.DetectContentType(data)]
+	if !ok {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	cfg, err := decoder.config(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, "", ErrUnsupportedImage
+	}
+	if maxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, "", ErrImageTooLarge
+	}
+
+	img, err := decoder.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	// The centered square is the same region whichever way the image is oriented
+	bounds := img.Bounds()
+	size := min(bounds.Dx(), bounds.Dy())
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-size)/2, bounds.Min.Y+(bounds.Dy()-size)/2)
+	square := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+
+	if decoder.format == "jpeg" {
+		square = orient(square, jpegOrientation(data))
+	}
+	return square, decoder.format, nil
+}
+
+// ResizeSquare scales a square image to size x size by averaging the source
+// pixels that fall into each target pixel
+func ResizeSquare(src *image.RGBA, size int) *image.RGBA {
+	n := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for dy := 0; dy < size; dy++ {
+		y0, y1 := dy*n/size, max((dy+1)*n/size, dy*n/size+1)
+		for dx := 0; dx < size; dx++ {
+			x0, x1 := dx*n/size, max((dx+1)*n/size, dx*n/size+1)
+
+			// RGBA is premultiplied, so transparent pixels do not bleed their color
+			var r, g, b, a, count uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					count++
+				}
+			}
+
+			i := dy*dst.Stride + dx*4
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = uint8(a / count)
+		}
+	}
+
+	return dst
+}
+
+// EncodeImage writes the image as JPEG if the source format was JPEG and as PNG
+// otherwise, keeping transparency. Only pixels are written, so metadata such as
+// EXIF (camera, GPS location) does not survive. It returns the file extension.
+func EncodeImage(w io.Writer, img image.Image, format string) (string, error) {
+	if format == "jpeg" {
+		return ".jpg", jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return ".png", png.Encode(w, img)
+}
+
+// orient transforms a square image according to an EXIF orientation (1-8)
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	n := src.Bounds().Dx()
+	last := n - 1
+	dst := image.NewRGBA(src.Bounds())
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			// Source pixel shown at (x, y)
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				sx, sy = last-x, y
+			case 3: // Rotated 180°
+				sx, sy = last-x, last-y
+			case 4: // Mirrored vertically
+				sx, sy = x, last-y
+			case 5: // Transposed
+				sx, sy = y, x
+			case 6: // Needs a 90° clockwise rotation
+				sx, sy = y, last-x
+			case 7: // Transversed
+				sx, sy = last-y, last-x
+			case 8: // Needs a 90° counter-clockwise rotation
+				sx, sy = last-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:sy*src.Stride+sx*4+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the orientation tag from the EXIF segment of a JPEG.
+// It returns 1, the normal orientation, if there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the marker segments up to the start of the image data
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads tag 0x0112 from the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// A SHORT value is stored in the first two bytes of the value field
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 && order.Uint16(tiff[entry+2:entry+4]) == 3 {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+	return 1
+}
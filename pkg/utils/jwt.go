@@ -1,53 +1,73 @@
 package utils
 
 import (
-	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// JWTClaims represents the JWT claims
+// Token use values carried in the "token_use" claim
+const (
+	TokenUseAccess            = "access"
+	TokenUseRefresh           = "refresh"
+	TokenUseEmailVerification = "email_verification"
+	TokenUseMFAChallenge      = "mfa_challenge"
+	TokenUseMagicLink         = "magic_link"
+)
+
+func init() {
+	// Timestamps keep milliseconds, so a token issued right after its user's
+	// tokens were revoked is not mistaken for one issued before
+	jwt.TimePrecision = time.Millisecond
+}
+
+// JWTClaims represents the JWT claims.
+// Every token carries a unique ID (the "jti" claim) so it can be revoked server-side.
 type JWTClaims struct {
+	UserID   uint         `json:"user_id"`
+	Email    string       `json:"email"`
+	Role     string       `json:"role,omitempty"`
+	TokenUse string       `json:"token_use"`
+	FamilyID string       `json:"fid,omitempty"` // Refresh token family the token was issued from
+	Actor    *ActorClaims `json:"act,omitempty"` // Set when an admin is impersonating the user (RFC 8693)
+	jwt.RegisteredClaims
+}
+
+// ActorClaims identifies the user acting on behalf of the token's user
+type ActorClaims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
-	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a new JWT token
+// GenerateJWT generates a new access token
 func GenerateJWT(userID uint, email string, secretKey string, expiration time.Duration) (string, error) {
-	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "go-template-structure",
-			Subject:   "user_auth",
-		},
-	}
+	return GenerateToken(&JWTClaims{
+		UserID:   userID,
+		Email:    email,
+		TokenUse: TokenUseAccess,
+	}, secretKey, expiration)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+// GenerateToken signs the given claims with an HMAC secret after filling in the
+// registered claims (token ID, issue time and expiration)
+func GenerateToken(claims *JWTClaims, secretKey string, expiration time.Duration) (string, error) {
+	return NewHMACKeySet(secretKey).GenerateToken(claims, expiration)
 }
 
-// ValidateJWT validates a JWT token and returns the claims
+// ValidateJWT validates an HMAC-signed JWT token and returns the claims
 func ValidateJWT(tokenString string, secretKey string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secretKey), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
+	return NewHMACKeySet(secretKey).ValidateToken(tokenString)
+}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+func setRegisteredClaims(claims *JWTClaims, expiration time.Duration) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "go-template-structure",
+		Subject:   "user_auth",
 	}
-
-	return nil, errors.New("invalid token")
 }
@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// jwtKey is a single key known to a KeySet
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{} // nil for verification-only keys
+	verifyKey interface{}
+}
+
+// KeySet holds the key used to sign new tokens and every key accepted when
+// validating tokens. Keeping the previous public keys in the set allows keys to be
+// rotated without invalidating tokens that were already issued.
+type KeySet struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet creates a key set that signs and validates tokens with a shared secret
+func NewHMACKeySet(secret string) *KeySet {
+	sum := sha256.Sum256([]byte(secret))
+	key := &jwtKey{
+		id:        "hs-" + hex.EncodeToString(sum[:8]),
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*jwtKey{key.id: key},
+	}
+}
+
+// LoadKeySet builds the key set described by the JWT configuration.
+// HS256 uses the shared secret; RS256 and EdDSA load the signing key and any
+// additional verification keys from PEM files.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmHS256
+	}
+
+	if algorithm == AlgorithmHS256 {
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		return NewHMACKeySet(cfg.Secret), nil
+	}
+
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s", algorithm)
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("jwt private key file is required for %s", algorithm)
+	}
+
+	signing, err := loadPrivateKey(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.method.Alg() != algorithm {
+		return nil, fmt.Errorf("jwt private key does not match algorithm %s", algorithm)
+	}
+
+	keySet := &KeySet{
+		signing: signing,
+		keys:    map[string]*jwtKey{signing.id: signing},
+	}
+
+	// Previous keys stay valid for verification until their tokens expire
+	for _, path := range cfg.PublicKeyFiles {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keySet.keys[key.id]; !exists {
+			keySet.keys[key.id] = key
+		}
+	}
+
+	return keySet, nil
+}
+
+// GenerateToken signs the given claims after filling in the registered claims
+func (k *KeySet) GenerateToken(claims *JWTClaims, expiration time.Duration) (string, error) {
+	setRegisteredClaims(claims, expiration)
+
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signing.signKey)
+}
+
+// ValidateToken validates a token against the keys in the set and returns its claims
+func (k *KeySet) ValidateToken(tokenString string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, k.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+// JWKS returns the public keys of the set. HMAC secrets are never published.
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range k.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	// Keep the order stable for clients caching the document
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+// keyFunc selects the verification key by the "kid" header and refuses tokens
+// whose algorithm does not match the key (prevents algorithm confusion attacks)
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := k.signing
+
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok = k.keys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+	} else if _, isHMAC := k.signing.method.(*jwt.SigningMethodHMAC); !isHMAC {
+		// Only HMAC tokens issued before key IDs were introduced may omit the kid
+		return nil, errors.New("token is missing kid header")
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.verifyKey, nil
+}
+
+func loadPrivateKey(path string) (*jwtKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+
+	switch priv := parsed.(type) {
+	case *rsa.PrivateKey:
+		return newPublicJWTKey(priv, &priv.PublicKey)
+	case ed25519.PrivateKey:
+		return newPublicJWTKey(priv, priv.Public())
+	default:
+		return nil, fmt.Errorf("unsupported private key algorithm in %s", path)
+	}
+}
+
+func loadPublicKey(path string) (*jwtKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+
+	return newPublicJWTKey(nil, parsed)
+}
+
+// newPublicJWTKey creates a key whose ID is the RFC 7638 thumbprint of the public key
+func newPublicJWTKey(signKey interface{}, publicKey crypto.PublicKey) (*jwtKey, error) {
+	var (
+		method     jwt.SigningMethod
+		thumbprint string
+	)
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+		thumbprint = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			base64.RawURLEncoding.EncodeToString(pub.N.Bytes()))
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+		thumbprint = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`,
+			base64.RawURLEncoding.EncodeToString(pub))
+	default:
+		return nil, errors.New("unsupported public key algorithm")
+	}
+
+	sum := sha256.Sum256([]byte(thumbprint))
+	return &jwtKey{
+		id:        base64.RawURLEncoding.EncodeToString(sum[:]),
+		method:    method,
+		signKey:   signKey,
+		verifyKey: publicKey,
+	}, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"go-template-structure/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Password hashing algorithms
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+
+	// Bound the work an imported hash can cause on every login attempt
+	legacyPBKDF2MaxIterations = 10_000_000
+	argon2MaxMemory           = 1 << 20 // KiB (1 GiB)
+	argon2MaxIterations       = 100
+)
+
+// Legacy hash formats imported from other systems. They can be verified but are
+// never produced; a successful login always replaces them.
+//
+//	sha256$<salt>$<hex(sha256(salt + password))>
+//	pbkdf2_<sha1|sha256|sha512>$<iterations>$<salt>$<base64(key)>
+var legacyPBKDF2Hashes = map[string]func() hash.Hash{
+	"pbkdf2_sha1":   sha1.New,
+	"pbkdf2_sha256": sha256.New,
+	"pbkdf2_sha512": sha512.New,
+}
+
+// PasswordHasher hashes passwords with the configured algorithm and verifies hashes
+// of any supported algorithm. Hashes are self-describing: bcrypt hashes use the
+// $2a$ modular crypt format, argon2id hashes the PHC string format and legacy
+// hashes are identified by their prefix.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the hash, and whether the hash
+	// should be replaced because it uses an outdated algorithm or parameters
+	Verify(hash, password string) (match bool, needsRehash bool, err error)
+}
+
+type passwordHasher struct {
+	cfg config.PasswordConfig
+}
+
+// NewPasswordHasher creates a hasher for the configured algorithm and parameters
+func NewPasswordHasher(cfg config.PasswordConfig) (PasswordHasher, error) {
+	switch cfg.HashAlgorithm {
+	case HashAlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case HashAlgorithmArgon2id:
+		if !validArgon2Params(cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism) {
+			return nil, fmt.Errorf("invalid argon2id parameters: memory must be between 8*parallelism and %d KiB, iterations between 1 and %d", argon2MaxMemory, argon2MaxIterations)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", cfg.HashAlgorithm)
+	}
+
+	return &passwordHasher{cfg: cfg}, nil
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.cfg.HashAlgorithm == HashAlgorithmArgon2id {
+		return h.hashArgon2id(password)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *passwordHasher) Verify(hash, password string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false, err
+		}
+
+		computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+
+		needsRehash := h.cfg.HashAlgorithm != HashAlgorithmArgon2id ||
+			params.memory != h.cfg.Argon2Memory ||
+			params.iterations != h.cfg.Argon2Iterations ||
+			params.parallelism != h.cfg.Argon2Parallelism
+		return true, needsRehash, nil
+
+	case strings.HasPrefix(hash, "$2"):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.cfg.HashAlgorithm != HashAlgorithmBcrypt || cost != h.cfg.BcryptCost, nil
+	}
+
+	verify, err := parseLegacyHash(hash)
+	if err != nil {
+		return false, false, err
+	}
+	match := verify(password)
+	return match, match, nil
+}
+
+// ValidatePasswordHash checks that a hash is in a supported format without
+// verifying any password, e.g. before importing it
+func ValidatePasswordHash(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		_, _, _, err := decodeArgon2id(hash)
+		return err
+	case strings.HasPrefix(hash, "$2"):
+		_, err := bcrypt.Cost([]byte(hash))
+		return err
+	}
+
+	_, err := parseLegacyHash(hash)
+	return err
+}
+
+// parseLegacyHash decodes a salted SHA-256 or PBKDF2 hash and returns a function
+// verifying a password against it
+func parseLegacyHash(encoded string) (func(password string) bool, error) {
+	parts := strings.Split(encoded, "$")
+
+	if parts[0] == "sha256" {
+		if len(parts) != 3 || parts[1] == "" {
+			return nil, errors.New("invalid sha256 hash")
+		}
+		salt := parts[1]
+		expected, err := hex.DecodeString(parts[2])
+		if err != nil || len(expected) != sha256.Size {
+			return nil, errors.New("invalid sha256 hash")
+		}
+
+		return func(password string) bool {
+			sum := sha256.Sum256([]byte(salt + password))
+			return subtle.ConstantTimeCompare(sum[:], expected) == 1
+		}, nil
+	}
+
+	if newHash, ok := legacyPBKDF2Hashes[parts[0]]; ok {
+		if len(parts) != 4 || parts[2] == "" {
+			return nil, errors.New("invalid pbkdf2 hash")
+		}
+		iterations, err := strconv.Atoi(parts[1])
+		if err != nil || iterations < 1 || iterations > legacyPBKDF2MaxIterations {
+			return nil, errors.New("invalid pbkdf2 iterations")
+		}
+		salt := parts[2]
+		expected, err := base64.StdEncoding.DecodeString(parts[3])
+		if err != nil || len(expected) == 0 {
+			return nil, errors.New("invalid pbkdf2 hash")
+		}
+
+		return func(password string) bool {
+			key := pbkdf2.Key([]byte(password), []byte(salt), iterations, len(expected), newHash)
+			return subtle.ConstantTimeCompare(key, expected) == 1
+		}, nil
+	}
+
+	return nil, errors.New("unknown password hash format")
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// hashArgon2id returns a PHC string: $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func (h *passwordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.cfg.Argon2Iterations, h.cfg.Argon2Memory, h.cfg.Argon2Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.cfg.Argon2Memory, h.cfg.Argon2Iterations, h.cfg.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// validArgon2Params checks the argon2id parameters are usable and within the work bounds
+func validArgon2Params(memory, iterations uint32, parallelism uint8) bool {
+	return parallelism >= 1 && memory >= 8*uint32(parallelism) && memory <= argon2MaxMemory &&
+		iterations >= 1 && iterations <= argon2MaxIterations
+}
+
+func decodeArgon2id(hash string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil ||
+		!validArgon2Params(params.memory, params.iterations, params.parallelism) {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errors.New("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id key")
+	}
+
+	return params, salt, key, nil
+}
@@ -53,3 +53,31 @@ func GetUserEmailFromContext(c *gin.Context) string {
 
 	return ""
 }
+
+// GetUserRoleFromContext extracts user role from gin context
+func GetUserRoleFromContext(c *gin.Context) domain.Role {
+	role, exists := c.Get("user_role")
+	if !exists {
+		return ""
+	}
+
+	if r, ok := role.(domain.Role); ok {
+		return r
+	}
+
+	return ""
+}
+
+// GetTokenClaimsFromContext extracts the validated JWT claims from gin context
+func GetTokenClaimsFromContext(c *gin.Context) *JWTClaims {
+	claims, exists := c.Get("token_claims")
+	if !exists {
+		return nil
+	}
+
+	if tokenClaims, ok := claims.(*JWTClaims); ok {
+		return tokenClaims
+	}
+
+	return nil
+}
@@ -1,12 +1,21 @@
 package utils
 
 import (
+	"fmt"
 	"html"
 	"regexp"
 	"strings"
 	"unicode"
+
+	"go-template-structure/internal/config"
 )
 
+// PasswordViolation describes a password policy rule that a password does not satisfy
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
 // SanitizeString removes dangerous characters and patterns
 func SanitizeString(input string) string {
 	// Escape HTML to prevent XSS
@@ -71,6 +80,52 @@ func ValidatePassword(password string) bool {
 	return hasUpper && hasLower && hasNumber && hasSpecial
 }
 
+// CheckPasswordPolicy returns every rule of the policy that the password violates.
+// An empty result means the password is acceptable.
+func CheckPasswordPolicy(policy config.PasswordConfig, password string) []PasswordViolation {
+	var violations []PasswordViolation
+
+	length := len([]rune(password))
+	if length < policy.MinLength {
+		violations = append(violations, PasswordViolation{"min_length", fmt.Sprintf("must be at least %d characters", policy.MinLength)})
+	}
+	if policy.MaxLength > 0 && len(password) > policy.MaxLength {
+		violations = append(violations, PasswordViolation{"max_length", fmt.Sprintf("must be at most %d bytes", policy.MaxLength)})
+	}
+
+	var hasUpper, hasLower, hasNumber, hasSpecial bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsNumber(char):
+			hasNumber = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char):
+			hasSpecial = true
+		}
+	}
+
+	if policy.RequireUpper && !hasUpper {
+		violations = append(violations, PasswordViolation{"upper", "must contain an uppercase letter"})
+	}
+	if policy.RequireLower && !hasLower {
+		violations = append(violations, PasswordViolation{"lower", "must contain a lowercase letter"})
+	}
+	if policy.RequireNumber && !hasNumber {
+		violations = append(violations, PasswordViolation{"number", "must contain a number"})
+	}
+	if policy.RequireSpecial && !hasSpecial {
+		violations = append(violations, PasswordViolation{"special", "must contain a special character"})
+	}
+	if policy.MinStrength > 0 && GetPasswordStrength(password) < policy.MinStrength {
+		violations = append(violations, PasswordViolation{"strength", fmt.Sprintf("must have a strength of at least %d out of 5", policy.MinStrength)})
+	}
+
+	return violations
+}
+
 // GetPasswordStrength returns password strength level (1-5)
 func GetPasswordStrength(password string) int {
 	strength := 0
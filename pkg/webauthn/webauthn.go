@@ -0,0 +1,471 @@
+package webauthn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template-structure/internal/config"
+)
+
+// Credential type and ceremony types used in the WebAuthn JSON structures
+const (
+	CredentialTypePublicKey = "public-key"
+
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+// User verification requirements
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent       = 0x01
+	flagUserVerified      = 0x04
+	flagAttestedData      = 0x40
+	flagExtensionDataIncl = 0x80
+)
+
+// maxCredentialIDLength is the longest credential ID allowed by the specification
+const maxCredentialIDLength = 1023
+
+// RelyingPartyEntity identifies the site to the authenticator
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity identifies the account a credential is created for. ID is the
+// base64url encoded user handle, which must not contain personal information.
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter is a credential key algorithm the relying party accepts
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor refers to an existing credential by its base64url encoded ID
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection states the requirements for the authenticator
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create() as publicKey.
+// Binary values are base64url encoded, as in PublicKeyCredentialCreationOptionsJSON.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get() as publicKey.
+// Binary values are base64url encoded, as in PublicKeyCredentialRequestOptionsJSON.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the JSON form of the credential returned by
+// navigator.credentials.create(). Binary values are base64url encoded.
+type AttestationResponse struct {
+	ID       string                           `json:"id" binding:"required"`
+	Type     string                           `json:"type" binding:"required"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+	AttestationObject string   `json:"attestationObject" binding:"required"`
+	Transports        []string `json:"transports"`
+}
+
+// AssertionResponse is the JSON form of the credential returned by
+// navigator.credentials.get(). Binary values are base64url encoded.
+type AssertionResponse struct {
+	ID       string                         `json:"id" binding:"required"`
+	Type     string                         `json:"type" binding:"required"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"userHandle"`
+}
+
+// Credential is a verified new credential to be stored for the user
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key encoding
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+// clientData is the JSON the browser signs over (CollectedClientData)
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// authenticatorData is the binary structure produced by the authenticator
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// RelyingParty runs the WebAuthn registration and authentication ceremonies for one site
+type RelyingParty struct {
+	id               string
+	name             string
+	origins          []string
+	timeout          time.Duration
+	userVerification string
+}
+
+func NewRelyingParty(cfg config.WebAuthnConfig) *RelyingParty {
+	userVerification := cfg.UserVerification
+	if userVerification == "" {
+		userVerification = UserVerificationRequired
+	}
+
+	return &RelyingParty{
+		id:               cfg.RPID,
+		name:             cfg.RPName,
+		origins:          cfg.Origins,
+		timeout:          cfg.Timeout,
+		userVerification: userVerification,
+	}
+}
+
+// Timeout is how long a ceremony may take; challenges should be kept that long
+func (rp *RelyingParty) Timeout() time.Duration {
+	return rp.timeout
+}
+
+// BeginRegistration returns the options for creating a passkey for the user.
+// Credentials in exclude are already registered and are not created again.
+func (rp *RelyingParty) BeginRegistration(user UserEntity, exclude []CredentialDescriptor) (*CreationOptions, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingPartyEntity{ID: rp.id, Name: rp.name},
+		User:      user,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: CredentialTypePublicKey, Alg: AlgES256},
+			{Type: CredentialTypePublicKey, Alg: AlgEdDSA},
+			{Type: CredentialTypePublicKey, Alg: AlgRS256},
+		},
+		Timeout:            rp.timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		// Passkeys are discoverable so users can sign in without typing their email
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   rp.userVerification,
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// BeginLogin returns the options for signing in. With no allowed credentials the
+// authenticator offers the discoverable credentials it holds for the site.
+func (rp *RelyingParty) BeginLogin(allow []CredentialDescriptor) (*RequestOptions, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.timeout.Milliseconds(),
+		RPID:             rp.id,
+		AllowCredentials: allow,
+		UserVerification: rp.userVerification,
+	}, nil
+}
+
+// Challenge returns the challenge a ceremony response was made for, so the
+// pending ceremony can be looked up before the response is verified
+func Challenge(clientDataJSON string) (string, error) {
+	_, data, err := parseClientData(clientDataJSON)
+	if err != nil {
+		return "", err
+	}
+	return data.Challenge, nil
+}
+
+// FinishRegistration verifies the response to a registration ceremony started
+// with the given challenge (WebAuthn Level 2, section 7.1) and returns the new
+// credential. Attestation statements are not verified because "none"
+// attestation is requested and no authenticator model is trusted over another.
+func (rp *RelyingParty) FinishRegistration(challenge string, resp *AttestationResponse) (*Credential, error) {
+	if resp.Type != CredentialTypePublicKey {
+		return nil, errors.New("unsupported credential type")
+	}
+
+	_, data, err := parseClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkClientData(data, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := DecodeBase64URL(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	item, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	attestation, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object is missing authenticator data")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedData == 0 {
+		return nil, errors.New("authenticator data is missing the credential")
+	}
+
+	if resp.ID != base64.RawURLEncoding.EncodeToString(authData.credentialID) {
+		return nil, errors.New("credential ID does not match authenticator data")
+	}
+	if _, err := parseCOSEKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		AAGUID:     authData.aaguid,
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// FinishLogin verifies the response to an authentication ceremony started with
+// the given challenge (WebAuthn Level 2, section 7.2) against the stored public
+// key and signature counter. It returns the new signature counter.
+func (rp *RelyingParty) FinishLogin(challenge string, resp *AssertionResponse, publicKey []byte, signCount uint32) (uint32, error) {
+	if resp.Type != CredentialTypePublicKey {
+		return 0, errors.New("unsupported credential type")
+	}
+
+	rawClientData, data, err := parseClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.checkClientData(data, clientDataTypeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := DecodeBase64URL(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("invalid authenticator data: %w", err)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.checkAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+
+	signature, err := DecodeBase64URL(resp.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature: %w", err)
+	}
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	// The signature covers the authenticator data followed by the client data hash
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := make([]byte, 0, len(rawAuthData)+len(clientDataHash))
+	signed = append(signed, rawAuthData...)
+	signed = append(signed, clientDataHash[:]...)
+	if err := key.verify(signed, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that does not increase means the credential may have been cloned.
+	// Authenticators without a counter always report zero.
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return 0, errors.New("signature counter did not increase")
+	}
+
+	return authData.signCount, nil
+}
+
+func (rp *RelyingParty) checkClientData(data *clientData, ceremony, challenge string) error {
+	if data.Type != ceremony {
+		return fmt.Errorf("unexpected client data type %q", data.Type)
+	}
+	if challenge == "" || subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return errors.New("challenge mismatch")
+	}
+	if data.CrossOrigin {
+		return errors.New("cross-origin ceremonies are not allowed")
+	}
+	for _, origin := range rp.origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed", data.Origin)
+}
+
+func (rp *RelyingParty) checkAuthenticatorData(data *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.id))
+	if subtle.ConstantTimeCompare(data.rpIDHash, rpIDHash[:]) != 1 {
+		return errors.New("relying party ID mismatch")
+	}
+	if data.flags&flagUserPresent == 0 {
+		return errors.New("user was not present")
+	}
+	if rp.userVerification == UserVerificationRequired && data.flags&flagUserVerified == 0 {
+		return errors.New("user was not verified")
+	}
+	return nil
+}
+
+func parseClientData(encoded string) ([]byte, *clientData, error) {
+	raw, err := DecodeBase64URL(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid client data: %w", err)
+	}
+
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	return raw, &data, nil
+}
+
+// parseAuthenticatorData decodes authenticator data, including the attested
+// credential data when present. Extensions are ignored.
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	result := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if result.flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		result.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+
+		if idLength > maxCredentialIDLength || len(rest) < idLength {
+			return nil, errors.New("invalid credential ID length")
+		}
+		result.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// The key is a CBOR item; its length is only known after decoding it
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		result.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if result.flags&flagExtensionDataIncl == 0 && len(rest) != 0 {
+		return nil, errors.New("trailing data after authenticator data")
+	}
+
+	return result, nil
+}
+
+func newChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return EncodeBase64URL(b), nil
+}
+
+// EncodeBase64URL encodes binary values such as credential IDs and user handles for JSON
+func EncodeBase64URL(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// DecodeBase64URL decodes a base64url value with or without padding
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,216 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/middleware"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// MockAPIKeyRepository is a mock implementation of APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(key *domain.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetByID(id uint) (*domain.APIKey, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(keyHash string) (*domain.APIKey, error) {
+	args := m.Called(keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListByUserID(userID uint) ([]domain.APIKey, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) RevokeByUserID(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// TestAPIKeyAuth tests API key authentication and scope enforcement
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockKeyRepo := new(MockAPIKeyRepository)
+	apiKeyService := service.NewAPIKeyService(mockKeyRepo, mockRepo)
+
+	admin := &domain.User{ID: 1, Email: "admin@example.com", Role: domain.RoleAdmin, IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(admin, nil)
+	user := &domain.User{ID: 3, Email: "user@example.com", Role: domain.RoleUser, IsActive: true}
+	mockRepo.On("GetByID", uint(3)).Return(user, nil)
+
+	var stored *domain.APIKey
+	mockKeyRepo.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*domain.APIKey)
+		stored.ID = 7
+	})
+	mockKeyRepo.On("TouchLastUsed", uint(7)).Return(nil)
+
+	t.Run("Scope Beyond Role", func(t *testing.T) {
+		support := &domain.User{ID: 2, Role: domain.RoleSupport, IsActive: true}
+		mockRepo.On("GetByID", uint(2)).Return(support, nil).Once()
+
+		_, err := apiKeyService.Create(2, 2, &domain.CreateAPIKeyRequest{
+			Name:   "cron",
+			Scopes: []domain.Permission{domain.PermissionUsersDelete},
+		})
+		assert.EqualError(t, err, "invalid scope: users:delete")
+	})
+
+	t.Run("Regular User Scope Beyond Profile", func(t *testing.T) {
+		_, err := apiKeyService.Create(3, 3, &domain.CreateAPIKeyRequest{
+			Name:   "script",
+			Scopes: []domain.Permission{domain.PermissionUsersRead},
+		})
+		assert.EqualError(t, err, "invalid scope: users:read")
+	})
+
+	// A regular user can scope a personal key to their own profile
+	personal, err := apiKeyService.Create(3, 3, &domain.CreateAPIKeyRequest{
+		Name:   "personal",
+		Scopes: []domain.Permission{domain.PermissionProfileRead, domain.PermissionProfileWrite},
+	})
+	require.NoError(t, err)
+	mockKeyRepo.On("GetByHash", stored.KeyHash).Return(stored, nil)
+
+	created, err := apiKeyService.Create(1, 1, &domain.CreateAPIKeyRequest{
+		Name:   "reporting",
+		Scopes: []domain.Permission{domain.PermissionUsersRead, domain.PermissionProfileRead},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, created.Key[:12], stored.Prefix)
+	assert.NotContains(t, stored.KeyHash, created.Key)
+
+	mockKeyRepo.On("GetByHash", stored.KeyHash).Return(stored, nil)
+	mockKeyRepo.On("GetByHash", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	auth := middleware.APIKeyAuth(apiKeyService, middleware.JWTAuth(utils.NewHMACKeySet("test-secret"), tokenRepo))
+
+	router := gin.New()
+	router.GET("/profile", auth, middleware.RequireAPIKeyScope(domain.PermissionProfileRead), func(c *gin.Context) {
+		c.String(http.StatusOK, "%d", utils.GetUserIDFromContext(c))
+	})
+	router.GET("/users", auth, middleware.RequirePermission(domain.PermissionUsersRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.DELETE("/users/:id", auth, middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersDelete), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/me/api-keys", auth, middleware.RejectAPIKey(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	for _, route := range []struct {
+		method, path string
+		guard        gin.HandlerFunc
+	}{
+		{"GET", "/users/:id", middleware.RequireSelfOrPermission(domain.PermissionProfileRead, domain.PermissionUsersRead)},
+		{"PATCH", "/users/:id", middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersWrite)},
+		{"PUT", "/users/:id", middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersWrite)},
+		{"PATCH", "/profile", middleware.RequireAPIKeyScope(domain.PermissionProfileWrite)},
+	} {
+		router.Handle(route.method, route.path, auth, route.guard, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	}
+
+	request := func(method, path, header, value string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("X-API-Key Header", func(t *testing.T) {
+		w := request("GET", "/profile", "X-API-Key", created.Key)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Body.String())
+	})
+
+	t.Run("Authorization ApiKey Header", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("GET", "/users", "Authorization", "ApiKey "+created.Key).Code)
+	})
+
+	t.Run("Scope Limits Role", func(t *testing.T) {
+		// The owner is an admin, but the key is only scoped to users:read
+		assert.Equal(t, http.StatusForbidden, request("DELETE", "/users/2", "X-API-Key", created.Key).Code)
+	})
+
+	t.Run("Scope Applies To Own Account", func(t *testing.T) {
+		// A read-only key can read its owner but not change the email or delete the account
+		assert.Equal(t, http.StatusOK, request("GET", "/users/1", "X-API-Key", created.Key).Code)
+		assert.Equal(t, http.StatusForbidden, request("PATCH", "/users/1", "X-API-Key", created.Key).Code)
+		assert.Equal(t, http.StatusForbidden, request("PUT", "/users/1", "X-API-Key", created.Key).Code)
+		assert.Equal(t, http.StatusForbidden, request("DELETE", "/users/1", "X-API-Key", created.Key).Code)
+		assert.Equal(t, http.StatusForbidden, request("PATCH", "/profile", "X-API-Key", created.Key).Code)
+	})
+
+	t.Run("Personal Key", func(t *testing.T) {
+		// Profile scopes cover every route on the owner's own account, and nothing else
+		assert.Equal(t, http.StatusOK, request("GET", "/profile", "X-API-Key", personal.Key).Code)
+		assert.Equal(t, http.StatusOK, request("PATCH", "/profile", "X-API-Key", personal.Key).Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/users/3", "X-API-Key", personal.Key).Code)
+		assert.Equal(t, http.StatusOK, request("PUT", "/users/3", "X-API-Key", personal.Key).Code)
+		assert.Equal(t, http.StatusOK, request("PATCH", "/users/3", "X-API-Key", personal.Key).Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/users/1", "X-API-Key", personal.Key).Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/users", "X-API-Key", personal.Key).Code)
+	})
+
+	t.Run("Credential Management Requires Login", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("GET", "/me/api-keys", "X-API-Key", created.Key).Code)
+	})
+
+	t.Run("Unknown Key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/profile", "X-API-Key", "gts_unknown").Code)
+
+		_, _, err := apiKeyService.Authenticate("gts_unknown")
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	})
+
+	t.Run("Revoked Key", func(t *testing.T) {
+		now := time.Now()
+		stored.RevokedAt = &now
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/profile", "X-API-Key", created.Key).Code)
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"go-template-structure/internal/domain"
 	"go-template-structure/internal/handler"
+	"go-template-structure/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -20,18 +21,56 @@ type MockAuthService struct {
 	mock.Mock
 }
 
-func (m *MockAuthService) Register(req *domain.CreateUserRequest) (*domain.AuthResponse, error) {
-	args := m.Called(req)
+func (m *MockAuthService) Register(req *domain.CreateUserRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	args := m.Called(req, client)
 	return args.Get(0).(*domain.AuthResponse), args.Error(1)
 }
 
-func (m *MockAuthService) Login(req *domain.LoginRequest) (*domain.AuthResponse, error) {
-	args := m.Called(req)
+func (m *MockAuthService) Login(req *domain.LoginRequest, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	args := m.Called(req, client)
 	return args.Get(0).(*domain.AuthResponse), args.Error(1)
 }
 
-func (m *MockAuthService) RefreshToken(refreshToken string) (*domain.AuthResponse, error) {
-	args := m.Called(refreshToken)
+func (m *MockAuthService) RefreshToken(refreshToken string, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	args := m.Called(refreshToken, client)
+	return args.Get(0).(*domain.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(claims *utils.JWTClaims, refreshToken string) error {
+	args := m.Called(claims, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) VerifyEmail(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResendVerification(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) SendVerificationEmail(user *domain.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) IssueTokens(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	args := m.Called(user, client)
+	return args.Get(0).(*domain.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) CompleteLogin(user *domain.User, client domain.ClientInfo) (*domain.AuthResponse, error) {
+	args := m.Called(user, client)
+	return args.Get(0).(*domain.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Impersonate(actorID, userID uint) (*domain.AuthResponse, error) {
+	args := m.Called(actorID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(*domain.AuthResponse), args.Error(1)
 }
 
@@ -65,7 +104,7 @@ func TestAuthHandler_Register(t *testing.T) {
 	}
 
 	// Mock service call
-	mockService.On("Register", request).Return(expectedResponse, nil)
+	mockService.On("Register", request, mock.Anything).Return(expectedResponse, nil)
 
 	// Create request
 	body, _ := json.Marshal(request)
@@ -120,7 +159,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	}
 
 	// Mock service call
-	mockService.On("Login", request).Return(expectedResponse, nil)
+	mockService.On("Login", request, mock.Anything).Return(expectedResponse, nil)
 
 	// Create request
 	body, _ := json.Marshal(request)
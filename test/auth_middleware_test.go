@@ -0,0 +1,118 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/middleware"
+	"go-template-structure/internal/repository"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTAuth_RevokedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secretKey := "test-secret-key"
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+
+	router := gin.New()
+	router.GET("/protected", middleware.JWTAuth(utils.NewHMACKeySet(secretKey), tokenRepo), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	token, err := utils.GenerateJWT(1, "test@example.com", secretKey, time.Hour)
+	assert.NoError(t, err)
+
+	request := func() int {
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Valid token is accepted
+	assert.Equal(t, http.StatusOK, request())
+
+	// Revoke the token by its jti
+	claims, err := utils.ValidateJWT(token, secretKey)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+	assert.NoError(t, tokenRepo.RevokeToken(claims.ID, time.Hour))
+
+	// Revoked token is rejected
+	assert.Equal(t, http.StatusUnauthorized, request())
+}
+
+func TestJWTAuth_RejectsRefreshToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secretKey := "test-secret-key"
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+
+	router := gin.New()
+	router.GET("/protected", middleware.JWTAuth(utils.NewHMACKeySet(secretKey), tokenRepo), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	token, err := utils.GenerateToken(&utils.JWTClaims{
+		UserID:   1,
+		Email:    "test@example.com",
+		TokenUse: utils.TokenUseRefresh,
+		FamilyID: "family-1",
+	}, secretKey, time.Hour)
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRBAC_UserRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secretKey := "test-secret-key"
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+
+	router := gin.New()
+	router.Use(middleware.JWTAuth(utils.NewHMACKeySet(secretKey), tokenRepo))
+	router.PUT("/users/:id", middleware.RequireSelfOrPermission(domain.PermissionProfileWrite, domain.PermissionUsersWrite), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.PUT("/admin/users/:id/role", middleware.RequirePermission(domain.PermissionRolesManage), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(path string, role domain.Role) int {
+		token, err := utils.GenerateToken(&utils.JWTClaims{
+			UserID:   1,
+			Email:    "test@example.com",
+			Role:     string(role),
+			TokenUse: utils.TokenUseAccess,
+		}, secretKey, time.Hour)
+		assert.NoError(t, err)
+
+		req, _ := http.NewRequest("PUT", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/users/1", domain.RoleUser))
+	assert.Equal(t, http.StatusForbidden, request("/users/2", domain.RoleUser))
+	assert.Equal(t, http.StatusForbidden, request("/users/2", domain.RoleSupport))
+	assert.Equal(t, http.StatusOK, request("/users/2", domain.RoleAdmin))
+	assert.Equal(t, http.StatusForbidden, request("/admin/users/2/role", domain.RoleUser))
+	assert.Equal(t, http.StatusOK, request("/admin/users/2/role", domain.RoleAdmin))
+}
@@ -0,0 +1,337 @@
+package test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Secret:            "test-secret",
+		Expiration:        time.Hour,
+		RefreshExpiration: 24 * time.Hour,
+	}
+}
+
+// newTestLockoutService returns a lockout service without backoff so tests can retry immediately
+// newTestPasswordHasher returns a fast bcrypt hasher matching the bcrypt.MinCost fixtures
+func newTestPasswordHasher() utils.PasswordHasher {
+	hasher, _ := utils.NewPasswordHasher(config.PasswordConfig{HashAlgorithm: utils.HashAlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	return hasher
+}
+
+func newTestLockoutService(userRepo repository.UserRepository) service.LockoutService {
+	return service.NewLockoutService(userRepo, repository.NewThrottleRepository(database.NewMemoryStore()),
+		config.LockoutConfig{MaxAttempts: 5, IPMaxAttempts: 20, Window: 15 * time.Minute, Duration: 15 * time.Minute})
+}
+
+// TestAuthService_RefreshTokenRotation tests refresh token rotation and reuse detection
+func TestAuthService_RefreshTokenRotation(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	authService := service.NewAuthService(mockRepo, tokenRepo, newTestSessionRepository(), repository.NewThrottleRepository(database.NewMemoryStore()), newTestLockoutService(mockRepo), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+		utils.NewHMACKeySet("test-secret"), &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+
+	testUser := &domain.User{ID: 1, Email: "test@example.com", IsActive: true}
+	mockRepo.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.User).ID = testUser.ID
+	})
+	mockRepo.On("Exists", "test@example.com", "testuser").Return(false, nil)
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+
+	initial, err := authService.Register(&domain.CreateUserRequest{
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "password123",
+	}, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	t.Run("Access Token Rejected", func(t *testing.T) {
+		_, err := authService.RefreshToken(initial.AccessToken, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid refresh token")
+	})
+
+	rotated, err := authService.RefreshToken(initial.RefreshToken, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, initial.RefreshToken, rotated.RefreshToken)
+
+	t.Run("Reuse Revokes Family", func(t *testing.T) {
+		_, err := authService.RefreshToken(initial.RefreshToken, domain.ClientInfo{})
+		assert.EqualError(t, err, "refresh token reuse detected")
+
+		// The legitimately rotated token is now unusable too
+		_, err = authService.RefreshToken(rotated.RefreshToken, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid refresh token")
+	})
+}
+
+// TestAuthService_ConcurrentRefresh tests that a refresh token replayed concurrently
+// is only rotated once and that the race revokes its family
+func TestAuthService_ConcurrentRefresh(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	authService := service.NewAuthService(mockRepo, tokenRepo, newTestSessionRepository(), repository.NewThrottleRepository(database.NewMemoryStore()), newTestLockoutService(mockRepo), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+		utils.NewHMACKeySet("test-secret"), &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+
+	testUser := &domain.User{ID: 1, Email: "test@example.com", IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+
+	initial, err := authService.IssueTokens(testUser, domain.ClientInfo{})
+	require.NoError(t, err)
+
+	const attempts = 10
+	results := make(chan *domain.AuthResponse, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := authService.RefreshToken(initial.RefreshToken, domain.ClientInfo{})
+			if err == nil {
+				results <- resp
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var rotated []*domain.AuthResponse
+	for resp := range results {
+		rotated = append(rotated, resp)
+	}
+	require.Len(t, rotated, 1)
+
+	// The losing attempts were reuse, so the winner's token is revoked with its family
+	_, err = authService.RefreshToken(rotated[0].RefreshToken, domain.ClientInfo{})
+	assert.EqualError(t, err, "invalid refresh token")
+}
+
+// TestTokenRepository_RevokeUserTokens tests that revocation only hits tokens
+// issued before it, even within the same second
+func TestTokenRepository_RevokeUserTokens(t *testing.T) {
+	keys := utils.NewHMACKeySet("test-secret")
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	issue := func() *utils.JWTClaims {
+		token, err := keys.GenerateToken(&utils.JWTClaims{UserID: 1, TokenUse: utils.TokenUseAccess}, time.Hour)
+		require.NoError(t, err)
+		claims, err := keys.ValidateToken(token)
+		require.NoError(t, err)
+		return claims
+	}
+
+	before := issue()
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, tokenRepo.RevokeUserTokens(1, time.Hour))
+	time.Sleep(2 * time.Millisecond)
+	after := issue()
+
+	revoked, err := tokenRepo.IsUserTokenRevoked(1, before.IssuedAt.Time)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = tokenRepo.IsUserTokenRevoked(1, after.IssuedAt.Time)
+	require.NoError(t, err)
+	assert.False(t, revoked, "a token issued right after the revocation stays valid")
+
+	t.Run("Marker In Seconds", func(t *testing.T) {
+		store := database.NewMemoryStore()
+		require.NoError(t, store.Set(context.Background(), "revoked_user:1", strconv.FormatInt(time.Now().Unix(), 10), time.Hour))
+		legacyRepo := repository.NewTokenRepository(store)
+
+		revoked, err := legacyRepo.IsUserTokenRevoked(1, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = legacyRepo.IsUserTokenRevoked(1, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
+
+// TestMemoryStore_CompareAndSwap tests the compare-and-swap used for refresh token rotation
+func TestMemoryStore_CompareAndSwap(t *testing.T) {
+	store := database.NewMemoryStore()
+	ctx := context.Background()
+
+	swapped, err := store.CompareAndSwap(ctx, "key", "a", "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, swapped, "a missing key never matches")
+
+	require.NoError(t, store.Set(ctx, "key", "a", time.Minute))
+
+	swapped, err = store.CompareAndSwap(ctx, "key", "x", "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = store.CompareAndSwap(ctx, "key", "a", "b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	value, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	swapped, err = store.CompareAndSwap(ctx, "key", "a", "c", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, swapped, "the old value was already replaced")
+}
+
+// TestThrottleRepository_Acquire tests that of concurrent callers only one acquires
+// a cooldown, which TOTP replay protection and resend cooldowns rely on
+func TestThrottleRepository_Acquire(t *testing.T) {
+	throttleRepo := repository.NewThrottleRepository(database.NewMemoryStore())
+
+	const callers = 20
+	var acquired atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := throttleRepo.Acquire("totp:1:42", time.Minute)
+			assert.NoError(t, err)
+			if ok {
+				acquired.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), acquired.Load())
+}
+
+// TestAuthService_EmailVerification tests login gating, verification links and resend throttling
+func TestAuthService_EmailVerification(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	mailer := &capturingMailer{}
+	authService := service.NewAuthService(mockRepo, tokenRepo, newTestSessionRepository(), repository.NewThrottleRepository(database.NewMemoryStore()), newTestLockoutService(mockRepo), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+		utils.NewHMACKeySet("test-secret"), mailer, newTestJWTConfig(),
+		config.AuthConfig{
+			RequireEmailVerification:    true,
+			EmailVerificationURL:        "http://app/verify",
+			EmailVerificationExpiration: time.Hour,
+			VerificationResendCooldown:  time.Minute,
+		}, config.PasswordConfig{})
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(hashed), IsActive: true}
+	mockRepo.On("Exists", "test@example.com", "testuser").Return(false, nil)
+	mockRepo.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.User).ID = testUser.ID
+	})
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil)
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+	mockRepo.On("Update", testUser).Return(nil)
+
+	registered, err := authService.Register(&domain.CreateUserRequest{
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "password123",
+	}, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.Empty(t, registered.AccessToken)
+	require.Len(t, mailer.sent, 1)
+
+	_, err = authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+	assert.EqualError(t, err, "email_not_verified")
+
+	t.Run("Resend Is Throttled", func(t *testing.T) {
+		assert.NoError(t, authService.ResendVerification("test@example.com"))
+		assert.EqualError(t, authService.ResendVerification("TEST@example.com"), "verification email recently sent")
+		assert.Len(t, mailer.sent, 2)
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		assert.EqualError(t, authService.VerifyEmail("not-a-token"), "invalid or expired verification token")
+	})
+
+	match := regexp.MustCompile(`http://app/verify\?token=(\S+)`).FindStringSubmatch(mailer.sent[0])
+	require.Len(t, match, 2)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+
+	require.NoError(t, authService.VerifyEmail(token))
+	assert.NotNil(t, testUser.EmailVerifiedAt)
+
+	loggedIn, err := authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, loggedIn.AccessToken)
+}
+
+// TestAuthService_LoginLockout tests backoff, account lockout and admin unlock
+func TestAuthService_LoginLockout(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	store := database.NewMemoryStore()
+	throttleRepo := repository.NewThrottleRepository(store)
+	lockoutService := service.NewLockoutService(mockRepo, throttleRepo, config.LockoutConfig{
+		MaxAttempts:   3,
+		IPMaxAttempts: 100,
+		Window:        15 * time.Minute,
+		Duration:      15 * time.Minute,
+		BackoffBase:   time.Millisecond,
+		BackoffMax:    time.Millisecond,
+	})
+	authService := service.NewAuthService(mockRepo, repository.NewTokenRepository(store), newTestSessionRepository(), throttleRepo, lockoutService, newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+		utils.NewHMACKeySet("test-secret"), &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(hashed), IsActive: true}
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil)
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+
+	client := domain.ClientInfo{IP: "203.0.113.7"}
+	wrong := &domain.LoginRequest{Email: "test@example.com", Password: "wrong"}
+	right := &domain.LoginRequest{Email: "test@example.com", Password: "password123"}
+
+	_, err := authService.Login(wrong, client)
+	assert.EqualError(t, err, "invalid email or password")
+
+	t.Run("Backoff After Failure", func(t *testing.T) {
+		_, err := authService.Login(right, client)
+		var throttled *service.LoginThrottledError
+		require.ErrorAs(t, err, &throttled)
+		assert.False(t, throttled.Locked)
+		time.Sleep(2 * time.Millisecond)
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err = authService.Login(wrong, client)
+		assert.EqualError(t, err, "invalid email or password")
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	t.Run("Locked After Threshold", func(t *testing.T) {
+		_, err := authService.Login(right, client)
+		var throttled *service.LoginThrottledError
+		require.ErrorAs(t, err, &throttled)
+		assert.True(t, throttled.Locked)
+		assert.Greater(t, throttled.RetryAfter, 14*time.Minute)
+	})
+
+	t.Run("Admin Unlock", func(t *testing.T) {
+		require.NoError(t, lockoutService.Unlock(99, 1))
+
+		resp, err := authService.Login(right, client)
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+	})
+}
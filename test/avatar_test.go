@@ -0,0 +1,341 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/storage"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeTestPNG encodes a width x height image whose left half is red and right half blue
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, halfAndHalf(width, height)))
+	return buf.Bytes()
+}
+
+func halfAndHalf(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+// encodeTestJPEG encodes halfAndHalf as a JPEG with an EXIF orientation tag
+func encodeTestJPEG(t *testing.T, size int, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, halfAndHalf(size, size), &jpeg.Options{Quality: 95}))
+
+	// Big-endian TIFF header and an IFD0 with the single orientation entry
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r>>8 > 200 && g>>8 < 60 && b>>8 < 60
+}
+
+func isBlue(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return b>>8 > 200 && r>>8 < 60 && g>>8 < 60
+}
+
+func TestDecodeSquareImage(t *testing.T) {
+	t.Run("Crops To Centered Square", func(t *testing.T) {
+		img, format, err := utils.DecodeSquareImage(encodeTestPNG(t, 40, 20), 0)
+		require.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, image.Rect(0, 0, 20, 20), img.Bounds())
+
+		// Columns 10-29 of the source: red then blue
+		assert.True(t, isRed(img.At(0, 0)))
+		assert.True(t, isBlue(img.At(19, 0)))
+	})
+
+	t.Run("Applies EXIF Orientation", func(t *testing.T) {
+		data := encodeTestJPEG(t, 32, 6)
+		img, format, err := utils.DecodeSquareImage(data, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+
+		// Rotated 90° clockwise, the red left half is now on top
+		assert.True(t, isRed(img.At(16, 4)))
+		assert.True(t, isBlue(img.At(16, 27)))
+
+		var out bytes.Buffer
+		ext, err := utils.EncodeImage(&out, img, format)
+		require.NoError(t, err)
+		assert.Equal(t, ".jpg", ext)
+		assert.True(t, bytes.Contains(data, []byte("Exif")))
+		assert.False(t, bytes.Contains(out.Bytes(), []byte("Exif")))
+	})
+
+	t.Run("Content Type Is Sniffed", func(t *testing.T) {
+		for _, data := range [][]byte{
+			[]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+			[]byte("GIF89a but not really"),
+			append([]byte("\x89PNG\r\n\x1a\n"), "truncated"...),
+		} {
+			_, _, err := utils.DecodeSquareImage(data, 0)
+			assert.ErrorIs(t, err, utils.ErrUnsupportedImage)
+		}
+	})
+
+	t.Run("Pixel Limit Checked Before Decoding", func(t *testing.T) {
+		_, _, err := utils.DecodeSquareImage(encodeTestPNG(t, 100, 100), 5000)
+		assert.ErrorIs(t, err, utils.ErrImageTooLarge)
+	})
+}
+
+func TestResizeSquare(t *testing.T) {
+	img, _, err := utils.DecodeSquareImage(encodeTestPNG(t, 64, 64), 0)
+	require.NoError(t, err)
+
+	small := utils.ResizeSquare(img, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), small.Bounds())
+	assert.True(t, isRed(small.At(0, 1)))
+	assert.True(t, isBlue(small.At(1, 1)))
+
+	// Smaller images are scaled up
+	assert.True(t, isBlue(utils.ResizeSquare(small, 8).At(7, 7)))
+}
+
+func TestLocalStorage(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir(), "https://cdn.example.com/media/")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(t.Context(), "avatars/1/abc/64.png", strings.NewReader("image")))
+	file, err := store.Open(t.Context(), "avatars/1/abc/64.png")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "image", string(data))
+	assert.Equal(t, "https://cdn.example.com/media/avatars/1/abc/64.png", store.URL("avatars/1/abc/64.png"))
+
+	require.NoError(t, store.Delete(t.Context(), "avatars/1/abc/64.png"))
+	_, err = store.Open(t.Context(), "avatars/1/abc/64.png")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, store.Delete(t.Context(), "avatars/1/abc/64.png"))
+
+	// Directories are not files
+	_, err = store.Open(t.Context(), "avatars/1")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	for _, key := range []string{"../escape.png", "/etc/passwd", "avatars/../../x.png", "avatars//x.png", ""} {
+		assert.Error(t, store.Put(t.Context(), key, strings.NewReader("x")), key)
+		_, err := store.Open(t.Context(), key)
+		assert.ErrorIs(t, err, fs.ErrNotExist, key)
+	}
+}
+
+func TestAvatarService_UploadAvatar(t *testing.T) {
+	avatarCfg := config.AvatarConfig{MaxBytes: 1 << 20, MaxPixels: 1_000_000}
+
+	newService := func() (*MockUserRepository, *storage.MemoryStorage, service.AvatarService) {
+		mockRepo := new(MockUserRepository)
+		store := storage.NewMemoryStorage("https://cdn.example.com/media")
+		return mockRepo, store, service.NewAvatarService(mockRepo, store, avatarCfg)
+	}
+
+	t.Run("Replaces Previous Avatar", func(t *testing.T) {
+		mockRepo, store, avatarService := newService()
+		for _, key := range domain.AvatarVariantKeys("avatars/1/old/256.png") {
+			require.NoError(t, store.Put(t.Context(), key, strings.NewReader("old")))
+		}
+		require.NoError(t, store.Put(t.Context(), "avatars/2/other/256.png", strings.NewReader("other user")))
+
+		mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1, Avatar: "https://cdn.example.com/media/avatars/1/old/256.png", AvatarKey: "avatars/1/old/256.png"}, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool {
+			return strings.HasPrefix(u.AvatarKey, "avatars/1/") && strings.HasSuffix(u.AvatarKey, "/256.png") &&
+				u.Avatar == "https://cdn.example.com/media/"+u.AvatarKey
+		})).Return(nil).Once()
+
+		avatar, err := avatarService.UploadAvatar(1, bytes.NewReader(encodeTestPNG(t, 300, 200)))
+		require.NoError(t, err)
+		assert.Len(t, avatar.Variants, 3)
+		assert.Equal(t, avatar.Variants["256"], avatar.URL)
+
+		keys := store.Keys()
+		sort.Strings(keys)
+		require.Len(t, keys, 4)
+		assert.Equal(t, "avatars/2/other/256.png", keys[3])
+
+		file, err := store.Open(t.Context(), strings.TrimPrefix(avatar.Variants["64"], "https://cdn.example.com/media/"))
+		require.NoError(t, err)
+		defer file.Close()
+		variant, err := png.Decode(file)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 64, 64), variant.Bounds())
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Too Large", func(t *testing.T) {
+		mockRepo, _, avatarService := newService()
+
+		_, err := avatarService.UploadAvatar(1, bytes.NewReader(make([]byte, avatarCfg.MaxBytes+1)))
+		assert.EqualError(t, err, "avatar file too large")
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("Not An Image", func(t *testing.T) {
+		mockRepo, store, avatarService := newService()
+
+		_, err := avatarService.UploadAvatar(1, strings.NewReader("<html><body>hi</body></html>"))
+		assert.ErrorIs(t, err, utils.ErrUnsupportedImage)
+		assert.Empty(t, store.Keys())
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("Files Removed When Update Fails", func(t *testing.T) {
+		mockRepo, store, avatarService := newService()
+		mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1}, nil).Once()
+		mockRepo.On("Update", mock.Anything).Return(repository.ErrVersionConflict).Once()
+
+		_, err := avatarService.UploadAvatar(1, bytes.NewReader(encodeTestPNG(t, 10, 10)))
+		assert.EqualError(t, err, "version mismatch")
+		assert.Empty(t, store.Keys())
+	})
+}
+
+func TestAvatarHandler_Upload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	store := storage.NewMemoryStorage("http://localhost:8080/media")
+	avatarHandler := handler.NewAvatarHandler(service.NewAvatarService(mockRepo, store, config.AvatarConfig{MaxBytes: 1 << 20, MaxPixels: 1_000_000}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.POST("/users/me/avatar", avatarHandler.UploadAvatar)
+	router.DELETE("/users/me/avatar", avatarHandler.DeleteAvatar)
+	router.GET("/media/*key", handler.NewMediaHandler(store).GetMedia)
+
+	upload := func(field, filename string, data []byte) (*httptest.ResponseRecorder, domain.APIResponse) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		_ = form.WriteField("note", "ignored")
+		part, _ := form.CreateFormFile(field, filename)
+		_, _ = part.Write(data)
+		_ = form.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/avatar", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response domain.APIResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	t.Run("Upload And Serve", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1}, nil).Once()
+		mockRepo.On("Update", mock.Anything).Return(nil).Once()
+
+		// The file name and declared type are not trusted
+		w, response := upload("avatar", "avatar.exe", encodeTestPNG(t, 50, 50))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		encoded, _ := json.Marshal(response.Data)
+		var avatar domain.Avatar
+		require.NoError(t, json.Unmarshal(encoded, &avatar))
+		require.True(t, strings.HasPrefix(avatar.URL, "http://localhost:8080/media/avatars/1/"))
+
+		req, _ := http.NewRequest(http.MethodGet, strings.TrimPrefix(avatar.URL, "http://localhost:8080"), nil)
+		served := httptest.NewRecorder()
+		router.ServeHTTP(served, req)
+		assert.Equal(t, http.StatusOK, served.Code)
+		assert.Equal(t, "image/png", served.Header().Get("Content-Type"))
+		assert.Contains(t, served.Header().Get("Cache-Control"), "immutable")
+
+		req, _ = http.NewRequest(http.MethodGet, "/media/avatars/1/missing/256.png", nil)
+		missing := httptest.NewRecorder()
+		router.ServeHTTP(missing, req)
+		assert.Equal(t, http.StatusNotFound, missing.Code)
+	})
+
+	t.Run("Rejected Uploads", func(t *testing.T) {
+		w, _ := upload("avatar", "avatar.png", []byte("<?php echo 'hi'; ?>"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+		w, _ = upload("photo", "avatar.png", encodeTestPNG(t, 10, 10))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = upload("avatar", "avatar.png", make([]byte, 1<<20+1))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/avatar", bytes.NewReader(encodeTestPNG(t, 10, 10)))
+		req.Header.Set("Content-Type", "image/png")
+		raw := httptest.NewRecorder()
+		router.ServeHTTP(raw, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, raw.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		uploaded := store.Keys()
+		require.Len(t, uploaded, 3)
+		sort.Strings(uploaded)
+
+		mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1, Avatar: "x", AvatarKey: uploaded[0]}, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool {
+			return u.Avatar == "" && u.AvatarKey == ""
+		})).Return(nil).Once()
+
+		req, _ := http.NewRequest(http.MethodDelete, "/users/me/avatar", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, store.Keys())
+	})
+
+	mockRepo.AssertExpectations(t)
+}
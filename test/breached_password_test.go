@@ -0,0 +1,131 @@
+package test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBreachedCorpus writes a sorted HIBP style corpus containing the given
+// passwords plus filler hashes, and returns its path
+func writeBreachedCorpus(t *testing.T, lineEnding string, filler int, passwords ...string) string {
+	lines := make([]string, 0, len(passwords)+filler)
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	for i := 0; i < filler; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("filler-%d", i)))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, lineEnding)+lineEnding), 0o600))
+	return path
+}
+
+func TestBreachedPasswordChecker(t *testing.T) {
+	// SHA-1 order: 123456 (7C4A...), monkey (AB87...), dragon (AF89...), trustno1 (E68E...)
+	breached := []string{"123456", "monkey", "dragon", "trustno1"}
+	// password (5BAA...) sorts first, sunshine (8D6E...) in between, hunter2 (F3BB...) last
+	clean := []string{"password", "sunshine", "hunter2"}
+
+	cases := []struct {
+		name       string
+		lineEnding string
+		filler     int
+	}{
+		{"Small Corpus", "\n", 0},
+		{"Large Corpus", "\n", 5000},
+		{"CRLF Line Endings", "\r\n", 500},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := utils.NewFileBreachedPasswordChecker(writeBreachedCorpus(t, tc.lineEnding, tc.filler, breached...))
+			require.NoError(t, err)
+
+			for _, password := range breached {
+				found, err := checker.IsBreached(password)
+				require.NoError(t, err)
+				assert.True(t, found, password)
+			}
+			for _, password := range clean {
+				found, err := checker.IsBreached(password)
+				require.NoError(t, err)
+				assert.False(t, found, password)
+			}
+		})
+	}
+
+	t.Run("Empty Corpus", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "empty.txt")
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+		checker, err := utils.NewFileBreachedPasswordChecker(path)
+		require.NoError(t, err)
+		found, err := checker.IsBreached("123456")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Invalid Corpus", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "invalid.txt")
+		require.NoError(t, os.WriteFile(path, []byte("not a hash\n"), 0o600))
+
+		_, err := utils.NewFileBreachedPasswordChecker(path)
+		assert.Error(t, err)
+
+		_, err = utils.NewFileBreachedPasswordChecker(filepath.Join(t.TempDir(), "missing.txt"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Disabled By Default", func(t *testing.T) {
+		checker, err := utils.NewBreachedPasswordChecker(config.PasswordConfig{})
+		require.NoError(t, err)
+		found, err := checker.IsBreached("123456")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestAuthService_RegisterBreachedPassword(t *testing.T) {
+	checker, err := utils.NewFileBreachedPasswordChecker(writeBreachedCorpus(t, "\n", 100, "Password123"))
+	require.NoError(t, err)
+
+	mockRepo := new(MockUserRepository)
+	store := database.NewMemoryStore()
+	authService := service.NewAuthService(mockRepo, repository.NewTokenRepository(store), newTestSessionRepository(), repository.NewThrottleRepository(store), newTestLockoutService(mockRepo), newTestPasswordHasher(), checker,
+		utils.NewHMACKeySet("test-secret"), &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+
+	_, err = authService.Register(&domain.CreateUserRequest{
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "Password123",
+	}, domain.ClientInfo{})
+
+	var policyErr *service.PasswordPolicyError
+	require.ErrorAs(t, err, &policyErr)
+	require.Len(t, policyErr.Violations, 1)
+	assert.Equal(t, "password_compromised", policyErr.Violations[0].Rule)
+
+	// The account is never looked up or created
+	mockRepo.AssertNotCalled(t, "Exists")
+	mockRepo.AssertNotCalled(t, "Create")
+}
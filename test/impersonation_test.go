@@ -0,0 +1,122 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/middleware"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_Impersonate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	keys := utils.NewHMACKeySet("test-secret")
+	authService := service.NewAuthService(mockRepo, tokenRepo, newTestSessionRepository(), repository.NewThrottleRepository(database.NewMemoryStore()), newTestLockoutService(mockRepo), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+		keys, &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{ImpersonationExpiration: 15 * time.Minute}, config.PasswordConfig{})
+
+	admin := &domain.User{ID: 1, Email: "admin@example.com", Role: domain.RoleAdmin, IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(admin, nil)
+	mockRepo.On("GetByID", uint(2)).Return(&domain.User{ID: 2, Email: "user@example.com", Role: domain.RoleUser, IsActive: true}, nil)
+	mockRepo.On("GetByID", uint(3)).Return(&domain.User{ID: 3, Email: "other-admin@example.com", Role: domain.RoleAdmin, IsActive: true}, nil)
+	mockRepo.On("GetByID", uint(4)).Return(&domain.User{ID: 4, Email: "inactive@example.com", Role: domain.RoleUser}, nil)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuth(keys, tokenRepo))
+	router.GET("/whoami", func(c *gin.Context) {
+		actorID, _ := c.Get("actor_id")
+		c.JSON(http.StatusOK, gin.H{"user_id": utils.GetUserIDFromContext(c), "actor_id": actorID})
+	})
+	router.PUT("/users/me/password", middleware.RejectImpersonation(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("issues a short-lived token naming the actor", func(t *testing.T) {
+		resp, err := authService.Impersonate(1, 2)
+		require.NoError(t, err)
+		assert.Empty(t, resp.RefreshToken)
+		assert.Equal(t, int64(900), resp.ExpiresIn)
+
+		claims, err := keys.ValidateToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), claims.UserID)
+		require.NotNil(t, claims.Actor)
+		assert.Equal(t, uint(1), claims.Actor.UserID)
+		assert.Equal(t, "admin@example.com", claims.Actor.Email)
+
+		w := request("GET", "/whoami", resp.AccessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"user_id":2,"actor_id":1}`, w.Body.String())
+
+		// Sensitive operations are blocked for the impersonated session
+		assert.Equal(t, http.StatusForbidden, request("PUT", "/users/me/password", resp.AccessToken).Code)
+	})
+
+	t.Run("rejects self, admin and inactive targets", func(t *testing.T) {
+		_, err := authService.Impersonate(1, 1)
+		assert.EqualError(t, err, "cannot impersonate yourself")
+
+		_, err = authService.Impersonate(1, 3)
+		assert.EqualError(t, err, "cannot impersonate an admin")
+
+		_, err = authService.Impersonate(1, 4)
+		assert.EqualError(t, err, "user account is inactive")
+	})
+
+	t.Run("revoking the actor ends the impersonation", func(t *testing.T) {
+		resp, err := authService.Impersonate(1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, request("GET", "/whoami", resp.AccessToken).Code)
+
+		require.NoError(t, tokenRepo.RevokeUserTokens(1, time.Hour))
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/whoami", resp.AccessToken).Code)
+	})
+}
+
+func TestRBAC_ImpersonateRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := utils.NewHMACKeySet("test-secret")
+	router := gin.New()
+	router.Use(middleware.JWTAuth(keys, repository.NewTokenRepository(database.NewMemoryStore())))
+	router.POST("/admin/users/:id/impersonate", middleware.RequirePermission(domain.PermissionUsersImpersonate), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(role domain.Role) int {
+		token, err := keys.GenerateToken(&utils.JWTClaims{UserID: 1, Role: string(role), TokenUse: utils.TokenUseAccess}, time.Hour)
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest(http.MethodPost, "/admin/users/2/impersonate", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Impersonation is admin-only; support staff cannot mint tokens for customers
+	assert.Equal(t, http.StatusForbidden, request(domain.RoleSupport))
+	assert.Equal(t, http.StatusForbidden, request(domain.RoleUser))
+	assert.Equal(t, http.StatusOK, request(domain.RoleAdmin))
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes a PKCS#8 private key and its PKIX public key as PEM files
+func writeKeyPair(t *testing.T, dir, name string, priv interface{}, pub interface{}) (string, string) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	privPath := filepath.Join(dir, name+".pem")
+	pubPath := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0600))
+	return privPath, pubPath
+}
+
+func TestKeySet_RS256Rotation(t *testing.T) {
+	dir := t.TempDir()
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldPriv, oldPub := writeKeyPair(t, dir, "old", oldKey, &oldKey.PublicKey)
+	newPriv, _ := writeKeyPair(t, dir, "new", newKey, &newKey.PublicKey)
+
+	oldSet, err := utils.LoadKeySet(config.JWTConfig{Algorithm: utils.AlgorithmRS256, PrivateKeyFile: oldPriv})
+	require.NoError(t, err)
+	oldToken, err := oldSet.GenerateToken(&utils.JWTClaims{UserID: 1, TokenUse: utils.TokenUseAccess}, time.Hour)
+	require.NoError(t, err)
+
+	// After rotation the new key signs and the old public key is still accepted
+	rotatedSet, err := utils.LoadKeySet(config.JWTConfig{
+		Algorithm:      utils.AlgorithmRS256,
+		PrivateKeyFile: newPriv,
+		PublicKeyFiles: []string{oldPub},
+	})
+	require.NoError(t, err)
+
+	claims, err := rotatedSet.ValidateToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	newToken, err := rotatedSet.GenerateToken(&utils.JWTClaims{UserID: 2, TokenUse: utils.TokenUseAccess}, time.Hour)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &utils.JWTClaims{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, parsed.Header["kid"])
+	assert.Equal(t, "RS256", parsed.Header["alg"])
+
+	// Both public keys are published, without private material
+	jwks := rotatedSet.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	for _, key := range jwks.Keys {
+		assert.Equal(t, "RSA", key.Kty)
+		assert.NotEmpty(t, key.N)
+	}
+
+	// Tokens signed with the new key are rejected by the old key set
+	_, err = oldSet.ValidateToken(newToken)
+	assert.Error(t, err)
+}
+
+func TestKeySet_EdDSA(t *testing.T) {
+	dir := t.TempDir()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privPath, _ := writeKeyPair(t, dir, "ed", priv, pub)
+
+	keySet, err := utils.LoadKeySet(config.JWTConfig{Algorithm: utils.AlgorithmEdDSA, PrivateKeyFile: privPath})
+	require.NoError(t, err)
+
+	token, err := keySet.GenerateToken(&utils.JWTClaims{UserID: 1, TokenUse: utils.TokenUseAccess}, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := keySet.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	jwks := keySet.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+
+	// Algorithm mismatch between config and key is rejected
+	_, err = utils.LoadKeySet(config.JWTConfig{Algorithm: utils.AlgorithmRS256, PrivateKeyFile: privPath})
+	assert.Error(t, err)
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privPath, _ := writeKeyPair(t, dir, "rsa", key, &key.PublicKey)
+
+	keySet, err := utils.LoadKeySet(config.JWTConfig{Algorithm: utils.AlgorithmRS256, PrivateKeyFile: privPath})
+	require.NoError(t, err)
+	kid := keySet.JWKS().Keys[0].Kid
+
+	// An HS256 token using the public key as the HMAC secret must not validate
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &utils.JWTClaims{UserID: 1, TokenUse: utils.TokenUseAccess})
+	forged.Header["kid"] = kid
+	forgedToken, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	require.NoError(t, err)
+
+	_, err = keySet.ValidateToken(forgedToken)
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var (
+	magicLinkPattern = regexp.MustCompile(`http://app/magic\?token=(\S+)`)
+	magicCodePattern = regexp.MustCompile(`code: (\d{6})`)
+)
+
+func TestMagicLinkService(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	store := database.NewMemoryStore()
+	keys := utils.NewHMACKeySet("test-secret")
+	mailer := &capturingMailer{}
+	authConfig := config.AuthConfig{
+		MagicLinkURL:           "http://app/magic",
+		MagicLinkExpiration:    10 * time.Minute,
+		MagicLinkRequestLimit:  4,
+		MagicLinkRequestWindow: 15 * time.Minute,
+	}
+
+	lockoutService := newTestLockoutService(mockRepo)
+	authService := service.NewAuthService(mockRepo, repository.NewTokenRepository(store), newTestSessionRepository(), repository.NewThrottleRepository(store), lockoutService, newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+		keys, mailer, newTestJWTConfig(), authConfig, config.PasswordConfig{})
+	magicLinkService := service.NewMagicLinkService(mockRepo, repository.NewMagicLinkRepository(store), repository.NewThrottleRepository(store), lockoutService, authService, keys, mailer, authConfig)
+
+	testUser := &domain.User{ID: 1, Email: "test@example.com", FirstName: "Test", IsActive: true}
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil)
+	mockRepo.On("GetByEmail", "missing@example.com").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*domain.User")).Return(nil)
+
+	// send requests a link and returns the token and code from the email
+	send := func(t *testing.T) (string, string) {
+		require.NoError(t, magicLinkService.Send("test@example.com"))
+		require.NotEmpty(t, mailer.sent)
+		body := mailer.sent[len(mailer.sent)-1]
+
+		link := magicLinkPattern.FindStringSubmatch(body)
+		require.Len(t, link, 2)
+		token, err := url.QueryUnescape(link[1])
+		require.NoError(t, err)
+
+		code := magicCodePattern.FindStringSubmatch(body)
+		require.Len(t, code, 2)
+		return token, code[1]
+	}
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		assert.NoError(t, magicLinkService.Send("missing@example.com"))
+		assert.Empty(t, mailer.sent)
+	})
+
+	t.Run("Link Login Is Single Use", func(t *testing.T) {
+		token, _ := send(t)
+
+		resp, err := magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Token: token}, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		assert.NotNil(t, testUser.EmailVerifiedAt)
+
+		_, err = magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Token: token}, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid or expired magic link")
+	})
+
+	t.Run("Code Login", func(t *testing.T) {
+		_, code := send(t)
+
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		_, err := magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Email: "test@example.com", Code: wrong}, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid or expired magic link")
+
+		resp, err := magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Email: "test@example.com", Code: code}, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+	})
+
+	t.Run("New Link Replaces Previous", func(t *testing.T) {
+		oldToken, _ := send(t)
+		newToken, _ := send(t)
+
+		_, err := magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Token: oldToken}, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid or expired magic link")
+
+		_, err = magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Token: newToken}, domain.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Requests Are Rate Limited Per Email", func(t *testing.T) {
+		// Earlier subtests used up the limit of the address; unknown addresses are limited the same way
+		assert.EqualError(t, magicLinkService.Send("test@example.com"), "too many magic link requests")
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, magicLinkService.Send("missing@example.com"))
+		}
+		assert.EqualError(t, magicLinkService.Send("missing@example.com"), "too many magic link requests")
+	})
+
+	t.Run("Rejects Other Tokens", func(t *testing.T) {
+		accessToken, err := keys.GenerateToken(&utils.JWTClaims{UserID: 1, Email: "test@example.com", TokenUse: utils.TokenUseAccess}, time.Minute)
+		require.NoError(t, err)
+
+		_, err = magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Token: accessToken}, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid or expired magic link")
+
+		_, err = magicLinkService.Verify(&domain.MagicLinkVerifyRequest{Email: "test@example.com"}, domain.ClientInfo{})
+		assert.EqualError(t, err, "token or email and code are required")
+	})
+}
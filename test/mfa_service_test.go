@@ -0,0 +1,211 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockMFARepository is a mock implementation of MFARepository
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	args := m.Called(userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	args := m.Called(userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) DeleteRecoveryCodes(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// testEncryptionKey is a base64 encoded 32-byte key for TOTP secrets
+const testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+// TestParseEncryptionKey tests that a missing or malformed key is rejected
+func TestParseEncryptionKey(t *testing.T) {
+	key, err := utils.ParseEncryptionKey(testEncryptionKey)
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	_, err = utils.ParseEncryptionKey("")
+	assert.EqualError(t, err, "encryption key is required")
+
+	_, err = utils.ParseEncryptionKey("c2hvcnQ=")
+	assert.EqualError(t, err, "encryption key must be 32 bytes")
+}
+
+// TestTOTP_RFC6238Vectors checks the SHA-1 test vectors from RFC 6238 Appendix B (last 6 digits)
+func TestTOTP_RFC6238Vectors(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := utils.GenerateTOTPCode(secret, utils.TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestMFAService_EnrollAndLogin(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMFARepo := new(MockMFARepository)
+	store := database.NewMemoryStore()
+	tokenRepo := repository.NewTokenRepository(store)
+	throttleRepo := repository.NewThrottleRepository(store)
+	keys := utils.NewHMACKeySet("test-secret")
+	authConfig := config.AuthConfig{MFAIssuer: "Test", MFAChallengeExpiration: 5 * time.Minute}
+
+	lockoutService := newTestLockoutService(mockRepo)
+	authService := service.NewAuthService(mockRepo, tokenRepo, newTestSessionRepository(), throttleRepo, lockoutService, newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), keys, &capturingMailer{}, newTestJWTConfig(), authConfig, config.PasswordConfig{})
+	encryptionKey, err := utils.ParseEncryptionKey(testEncryptionKey)
+	require.NoError(t, err)
+	mfaService := service.NewMFAService(mockRepo, mockMFARepo, tokenRepo, throttleRepo, lockoutService, authService, newTestPasswordHasher(), keys, encryptionKey, authConfig)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(hashed), IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil)
+	mockRepo.On("Update", testUser).Return(nil)
+	mockMFARepo.On("ReplaceRecoveryCodes", uint(1), mock.Anything).Return(nil)
+
+	enrollment, err := mfaService.Enroll(1)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/Test:test@example.com")
+	assert.Len(t, enrollment.RecoveryCodes, 10)
+	assert.NotContains(t, testUser.MFASecret, enrollment.Secret, "secret must be encrypted at rest")
+	assert.False(t, testUser.MFAEnabled)
+
+	code, err := utils.GenerateTOTPCode(enrollment.Secret, utils.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	require.NoError(t, mfaService.ConfirmEnrollment(1, code, domain.ClientInfo{}))
+	assert.True(t, testUser.MFAEnabled)
+
+	t.Run("Login Returns Challenge", func(t *testing.T) {
+		resp, err := authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.True(t, resp.MFARequired)
+		assert.Empty(t, resp.AccessToken)
+		assert.Nil(t, resp.User)
+
+		// The code used for confirmation cannot be replayed
+		_, err = mfaService.Verify(resp.MFAToken, code, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid mfa code")
+	})
+
+	t.Run("Recovery Code Completes Login", func(t *testing.T) {
+		mockMFARepo.On("UseRecoveryCode", uint(1), mock.Anything).Return(true, nil).Once()
+
+		resp, err := authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		tokens, err := mfaService.Verify(resp.MFAToken, enrollment.RecoveryCodes[0], domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+
+		// Challenge tokens are single-use
+		_, err = mfaService.Verify(resp.MFAToken, enrollment.RecoveryCodes[1], domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid or expired mfa token")
+	})
+
+	t.Run("Attempts Are Limited", func(t *testing.T) {
+		mockMFARepo.On("UseRecoveryCode", uint(1), mock.Anything).Return(false, nil)
+
+		resp, err := authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			_, err = mfaService.Verify(resp.MFAToken, "wrong-code", domain.ClientInfo{})
+			assert.EqualError(t, err, "invalid mfa code")
+		}
+		_, err = mfaService.Verify(resp.MFAToken, "wrong-code", domain.ClientInfo{})
+		assert.EqualError(t, err, "too many mfa attempts")
+	})
+}
+
+// TestMFAService_FailuresCountTowardLockout tests that wrong codes lock the account
+// even though every password login starts a new challenge
+func TestMFAService_FailuresCountTowardLockout(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMFARepo := new(MockMFARepository)
+	store := database.NewMemoryStore()
+	tokenRepo := repository.NewTokenRepository(store)
+	throttleRepo := repository.NewThrottleRepository(store)
+	keys := utils.NewHMACKeySet("test-secret")
+	authConfig := config.AuthConfig{MFAIssuer: "Test", MFAChallengeExpiration: 5 * time.Minute}
+
+	lockoutService := newTestLockoutService(mockRepo)
+	authService := service.NewAuthService(mockRepo, tokenRepo, newTestSessionRepository(), throttleRepo, lockoutService, newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), keys, &capturingMailer{}, newTestJWTConfig(), authConfig, config.PasswordConfig{})
+	encryptionKey, err := utils.ParseEncryptionKey(testEncryptionKey)
+	require.NoError(t, err)
+	mfaService := service.NewMFAService(mockRepo, mockMFARepo, tokenRepo, throttleRepo, lockoutService, authService, newTestPasswordHasher(), keys, encryptionKey, authConfig)
+
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(t, err)
+	encrypted, err := utils.Encrypt(encryptionKey, secret)
+	require.NoError(t, err)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(hashed), IsActive: true, MFAEnabled: true, MFASecret: encrypted}
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil)
+	mockMFARepo.On("UseRecoveryCode", uint(1), mock.Anything).Return(false, nil)
+
+	t.Run("Verify", func(t *testing.T) {
+		// One wrong code per challenge, up to the limit of five failures
+		for i := 0; i < 5; i++ {
+			resp, err := authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+			require.NoError(t, err)
+
+			_, err = mfaService.Verify(resp.MFAToken, "wrong-code", domain.ClientInfo{})
+			assert.EqualError(t, err, "invalid mfa code")
+		}
+
+		var throttled *service.LoginThrottledError
+		_, err := authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+		require.ErrorAs(t, err, &throttled)
+		assert.True(t, throttled.Locked)
+
+		err = mfaService.Disable(1, "password123", "wrong-code", domain.ClientInfo{})
+		require.ErrorAs(t, err, &throttled)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		require.NoError(t, lockoutService.Unlock(99, 1))
+
+		for i := 0; i < 5; i++ {
+			err := mfaService.Disable(1, "wrong-password", "", domain.ClientInfo{})
+			assert.EqualError(t, err, "invalid password")
+		}
+
+		var throttled *service.LoginThrottledError
+		err := mfaService.Disable(1, "password123", "wrong-code", domain.ClientInfo{})
+		require.ErrorAs(t, err, &throttled)
+		assert.True(t, testUser.MFAEnabled)
+	})
+}
@@ -0,0 +1,294 @@
+package test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/oauth"
+	"go-template-structure/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// MockUserIdentityRepository is a mock implementation of UserIdentityRepository
+type MockUserIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserIdentityRepository) Create(identity *domain.UserIdentity) error {
+	args := m.Called(identity)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityRepository) GetByProviderSubject(provider, subject string) (*domain.UserIdentity, error) {
+	args := m.Called(provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserIdentity), args.Error(1)
+}
+
+// fakeOIDCProvider is an in-process OpenID Connect provider implementing discovery,
+// the authorization endpoint, a PKCE-checking token endpoint and JWKS
+type fakeOIDCProvider struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	clientID      string
+	subject       string
+	email         string
+	emailVerified bool
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthRequest
+}
+
+type fakeAuthRequest struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &fakeOIDCProvider{
+		key:           key,
+		clientID:      "test-client",
+		subject:       "subject-1",
+		email:         "jane@example.com",
+		emailVerified: true,
+		codes:         make(map[string]fakeAuthRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != p.clientID || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+
+		code, _ := oauth.RandomString()
+		p.mu.Lock()
+		p.codes[code] = fakeAuthRequest{redirectURI: q.Get("redirect_uri"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+		p.mu.Unlock()
+
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		req, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		if !ok || r.PostForm.Get("redirect_uri") != req.redirectURI || oauth.CodeChallenge(r.PostForm.Get("code_verifier")) != req.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            p.server.URL,
+			"aud":            p.clientID,
+			"sub":            p.subject,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          req.nonce,
+			"email":          p.email,
+			"email_verified": p.emailVerified,
+			"given_name":     "Jane",
+			"family_name":    "Doe",
+		})
+		idToken.Header["kid"] = "test-key"
+		signed, _ := idToken.SignedString(p.key)
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     signed,
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize follows the authorization URL like a browser and returns the callback query
+func (p *fakeOIDCProvider) authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestOAuthService_OIDCLogin(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+
+	oauthConfig := config.OAuthConfig{
+		RedirectURL:     "http://localhost:8080/api/v1/auth/oauth",
+		StateExpiration: 10 * time.Minute,
+		OIDC: config.OAuthProviderConfig{
+			ClientID:     provider.clientID,
+			ClientSecret: "test-secret",
+			IssuerURL:    provider.server.URL,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+	}
+
+	newService := func(userRepo *MockUserRepository, identityRepo *MockUserIdentityRepository) service.OAuthService {
+		store := database.NewMemoryStore()
+		keys := utils.NewHMACKeySet("test-secret")
+		authService := service.NewAuthService(userRepo, repository.NewTokenRepository(store), newTestSessionRepository(), repository.NewThrottleRepository(store), newTestLockoutService(userRepo), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), keys, &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+		return service.NewOAuthService(oauth.NewProviders(oauthConfig), userRepo, identityRepo, repository.NewOAuthStateRepository(store), authService, newTestPasswordHasher(), oauthConfig)
+	}
+
+	t.Run("creates user and links identity", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		identityRepo := new(MockUserIdentityRepository)
+		oauthService := newService(userRepo, identityRepo)
+
+		identityRepo.On("GetByProviderSubject", "oidc", "subject-1").Return(nil, gorm.ErrRecordNotFound)
+		userRepo.On("GetByEmail", "jane@example.com").Return(nil, gorm.ErrRecordNotFound)
+		userRepo.On("GetByUsername", "jane").Return(nil, gorm.ErrRecordNotFound)
+		userRepo.On("Create", mock.AnythingOfType("*domain.User")).Run(func(args mock.Arguments) {
+			args.Get(0).(*domain.User).ID = 7
+		}).Return(nil)
+		identityRepo.On("Create", mock.MatchedBy(func(identity *domain.UserIdentity) bool {
+			return identity.UserID == 7 && identity.Provider == "oidc" && identity.Subject == "subject-1"
+		})).Return(nil)
+
+		authURL, state, err := oauthService.AuthorizationURL("oidc")
+		require.NoError(t, err)
+		callback := provider.authorize(t, authURL)
+
+		resp, err := oauthService.Callback("oidc", callback.Get("state"), state, callback.Get("code"), domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		assert.Equal(t, "jane", resp.User.Username)
+		assert.Equal(t, "Jane", resp.User.FirstName)
+		assert.NotNil(t, resp.User.EmailVerifiedAt)
+		identityRepo.AssertExpectations(t)
+
+		// The state is single-use
+		_, err = oauthService.Callback("oidc", callback.Get("state"), state, callback.Get("code"), domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid oauth state")
+	})
+
+	t.Run("logs in a linked identity", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		identityRepo := new(MockUserIdentityRepository)
+		oauthService := newService(userRepo, identityRepo)
+
+		existing := &domain.User{ID: 3, Email: "other@example.com", IsActive: true}
+		identityRepo.On("GetByProviderSubject", "oidc", "subject-1").Return(&domain.UserIdentity{UserID: 3, Provider: "oidc", Subject: "subject-1"}, nil)
+		userRepo.On("GetByID", uint(3)).Return(existing, nil)
+
+		authURL, state, err := oauthService.AuthorizationURL("oidc")
+		require.NoError(t, err)
+		callback := provider.authorize(t, authURL)
+
+		resp, err := oauthService.Callback("oidc", callback.Get("state"), state, callback.Get("code"), domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, uint(3), resp.User.ID)
+		userRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("does not link an unverified email", func(t *testing.T) {
+		provider.emailVerified = false
+		defer func() { provider.emailVerified = true }()
+
+		userRepo := new(MockUserRepository)
+		identityRepo := new(MockUserIdentityRepository)
+		oauthService := newService(userRepo, identityRepo)
+
+		identityRepo.On("GetByProviderSubject", "oidc", "subject-1").Return(nil, gorm.ErrRecordNotFound)
+
+		authURL, state, err := oauthService.AuthorizationURL("oidc")
+		require.NoError(t, err)
+		callback := provider.authorize(t, authURL)
+
+		_, err = oauthService.Callback("oidc", callback.Get("state"), state, callback.Get("code"), domain.ClientInfo{})
+		assert.EqualError(t, err, "oauth email not verified")
+		userRepo.AssertNotCalled(t, "GetByEmail", mock.Anything)
+		identityRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("rejects a state not bound to the browser", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		identityRepo := new(MockUserIdentityRepository)
+		oauthService := newService(userRepo, identityRepo)
+
+		authURL, state, err := oauthService.AuthorizationURL("oidc")
+		require.NoError(t, err)
+		callback := provider.authorize(t, authURL)
+		assert.Equal(t, state, callback.Get("state"))
+
+		_, err = oauthService.Callback("oidc", callback.Get("state"), "", callback.Get("code"), domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid oauth state")
+
+		_, otherState, err := oauthService.AuthorizationURL("oidc")
+		require.NoError(t, err)
+		_, err = oauthService.Callback("oidc", callback.Get("state"), otherState, callback.Get("code"), domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid oauth state")
+		identityRepo.AssertNotCalled(t, "GetByProviderSubject", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects unknown state and provider", func(t *testing.T) {
+		oauthService := newService(new(MockUserRepository), new(MockUserIdentityRepository))
+
+		_, err := oauthService.Callback("oidc", "forged", "forged", "code", domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid oauth state")
+
+		_, _, err = oauthService.AuthorizationURL("google")
+		assert.EqualError(t, err, "unknown provider")
+	})
+}
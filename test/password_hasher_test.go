@@ -0,0 +1,203 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newArgon2Config(memory uint32) config.PasswordConfig {
+	return config.PasswordConfig{
+		HashAlgorithm:     utils.HashAlgorithmArgon2id,
+		BcryptCost:        bcrypt.MinCost,
+		Argon2Memory:      memory,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	argon, err := utils.NewPasswordHasher(newArgon2Config(1024))
+	require.NoError(t, err)
+
+	t.Run("Argon2id Round Trip", func(t *testing.T) {
+		hash, err := argon.Hash("secret-password")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+		match, needsRehash, err := argon.Verify(hash, "secret-password")
+		require.NoError(t, err)
+		assert.True(t, match)
+		assert.False(t, needsRehash)
+
+		match, _, err = argon.Verify(hash, "wrong-password")
+		require.NoError(t, err)
+		assert.False(t, match)
+
+		// Each hash uses a new salt
+		other, _ := argon.Hash("secret-password")
+		assert.NotEqual(t, hash, other)
+	})
+
+	t.Run("Rehash On Parameter Change", func(t *testing.T) {
+		hash, _ := argon.Hash("secret-password")
+
+		stronger, err := utils.NewPasswordHasher(newArgon2Config(2048))
+		require.NoError(t, err)
+		match, needsRehash, err := stronger.Verify(hash, "secret-password")
+		require.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("Bcrypt Upgraded To Argon2id", func(t *testing.T) {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+
+		match, needsRehash, err := argon.Verify(string(hash), "secret-password")
+		require.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("Bcrypt Cost", func(t *testing.T) {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+
+		same, _ := utils.NewPasswordHasher(config.PasswordConfig{HashAlgorithm: utils.HashAlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+		_, needsRehash, _ := same.Verify(string(hash), "secret-password")
+		assert.False(t, needsRehash)
+
+		higher, _ := utils.NewPasswordHasher(config.PasswordConfig{HashAlgorithm: utils.HashAlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+		_, needsRehash, _ = higher.Verify(string(hash), "secret-password")
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("Unknown Format", func(t *testing.T) {
+		match, _, err := argon.Verify("plaintext", "plaintext")
+		assert.Error(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		_, err := utils.NewPasswordHasher(config.PasswordConfig{HashAlgorithm: "md5"})
+		assert.Error(t, err)
+		_, err = utils.NewPasswordHasher(config.PasswordConfig{HashAlgorithm: utils.HashAlgorithmBcrypt, BcryptCost: 2})
+		assert.Error(t, err)
+		_, err = utils.NewPasswordHasher(newArgon2Config(1 << 21))
+		assert.Error(t, err)
+	})
+
+	t.Run("Argon2id Parameter Bounds", func(t *testing.T) {
+		hash, _ := argon.Hash("secret-password")
+		require.NoError(t, utils.ValidatePasswordHash(hash))
+
+		for _, params := range []string{
+			"m=1024,t=0,p=1",
+			"m=1024,t=1,p=0",
+			"m=4,t=1,p=1",
+			"m=2097152,t=1,p=1",
+			"m=1024,t=101,p=1",
+		} {
+			invalid := strings.Replace(hash, "m=1024,t=1,p=1", params, 1)
+			assert.Error(t, utils.ValidatePasswordHash(invalid), params)
+
+			match, _, err := argon.Verify(invalid, "secret-password")
+			assert.EqualError(t, err, "invalid argon2id parameters", params)
+			assert.False(t, match)
+		}
+	})
+}
+
+// TestPasswordHasher_LegacyVectors verifies the imported legacy formats. The PBKDF2
+// vectors come from RFC 6070 (SHA-1) and RFC 7914 section 11 (SHA-256).
+func TestPasswordHasher_LegacyVectors(t *testing.T) {
+	hasher, err := utils.NewPasswordHasher(newArgon2Config(1024))
+	require.NoError(t, err)
+
+	vectors := []struct {
+		name     string
+		hash     string
+		password string
+	}{
+		{"Salted SHA-256", "sha256$NaCl$b20ab74aa2549f7e13a0e886cb4471cc2e70fcd2ce8075c0ee6483abba6132f3", "password"},
+		{"PBKDF2-SHA1 RFC 6070", "pbkdf2_sha1$1$salt$DGDID5YfDnHzqbUkr2ASBi/gN6Y=", "password"},
+		{"PBKDF2-SHA256 RFC 7914", "pbkdf2_sha256$1$salt$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLxJypzM8Xm2RZkWZLOdd+8xfHG4RbHjC9UJESBB06GXgw==", "passwd"},
+		{"PBKDF2-SHA256", "pbkdf2_sha256$1000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c=", "password"},
+		{"PBKDF2-SHA512", "pbkdf2_sha512$1000$seasalt$TCtBOEq+xXm+XNZRLfJoHDneh4iBM8b625BLGQLietyIGZ27aNH0oiYQ1uvtIKO2f60DdYIkgYbHnIAbnk1sWw==", "password"},
+	}
+
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			require.NoError(t, utils.ValidatePasswordHash(v.hash))
+
+			match, needsRehash, err := hasher.Verify(v.hash, v.password)
+			require.NoError(t, err)
+			assert.True(t, match)
+			assert.True(t, needsRehash, "legacy hashes are always upgraded")
+
+			match, _, err = hasher.Verify(v.hash, v.password+"x")
+			require.NoError(t, err)
+			assert.False(t, match)
+		})
+	}
+
+	t.Run("Invalid Hashes", func(t *testing.T) {
+		for _, hash := range []string{
+			"sha256$salt$nothex",
+			"sha256$$b20ab74aa2549f7e13a0e886cb4471cc2e70fcd2ce8075c0ee6483abba6132f3",
+			"pbkdf2_sha256$0$salt$YWJj",
+			"pbkdf2_sha256$99999999999$salt$YWJj",
+			"pbkdf2_md5$1000$salt$YWJj",
+			"md5$salt$abc",
+		} {
+			assert.Error(t, utils.ValidatePasswordHash(hash), hash)
+		}
+	})
+}
+
+// TestAuthService_LoginRehash tests that an outdated hash is upgraded on a successful login
+func TestAuthService_LoginRehash(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	store := database.NewMemoryStore()
+	hasher, err := utils.NewPasswordHasher(newArgon2Config(1024))
+	require.NoError(t, err)
+
+	authService := service.NewAuthService(mockRepo, repository.NewTokenRepository(store), newTestSessionRepository(), repository.NewThrottleRepository(store), newTestLockoutService(mockRepo), hasher, utils.NewNoopBreachedPasswordChecker(),
+		utils.NewHMACKeySet("test-secret"), &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+
+	outdated, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(outdated), IsActive: true}
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool {
+		return strings.HasPrefix(u.Password, "$argon2id$")
+	})).Return(nil).Once()
+
+	_, err = authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+	require.NoError(t, err)
+
+	// The upgraded hash still verifies and is not rehashed again
+	_, err = authService.Login(&domain.LoginRequest{Email: "test@example.com", Password: "password123"}, domain.ClientInfo{})
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	t.Run("Imported PBKDF2 Hash", func(t *testing.T) {
+		imported := &domain.User{ID: 2, Email: "imported@example.com", IsActive: true,
+			Password: "pbkdf2_sha256$1000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c="}
+		mockRepo.On("GetByEmail", "imported@example.com").Return(imported, nil)
+		mockRepo.On("Update", imported).Return(nil).Once()
+
+		_, err := authService.Login(&domain.LoginRequest{Email: "imported@example.com", Password: "password"}, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(imported.Password, "$argon2id$"))
+	})
+}
@@ -0,0 +1,274 @@
+package test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MockPasswordResetRepository is a mock implementation of PasswordResetRepository
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) Create(token *domain.PasswordResetToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) GetByTokenHash(tokenHash string) (*domain.PasswordResetToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) MarkUsed(id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) DeleteByUserID(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// MockPasswordHistoryRepository is a mock implementation of PasswordHistoryRepository
+type MockPasswordHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordHistoryRepository) Create(entry *domain.PasswordHistory) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockPasswordHistoryRepository) ListRecentByUserID(userID uint, limit int) ([]domain.PasswordHistory, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]domain.PasswordHistory), args.Error(1)
+}
+
+func (m *MockPasswordHistoryRepository) Prune(userID uint, keep int) error {
+	args := m.Called(userID, keep)
+	return args.Error(0)
+}
+
+// capturingMailer records sent emails
+type capturingMailer struct {
+	sent []string
+}
+
+func (m *capturingMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, body)
+	return nil
+}
+
+func TestPasswordService_ResetFlow(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockResetRepo := new(MockPasswordResetRepository)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	mailer := &capturingMailer{}
+
+	sessionRepo := newTestSessionRepository()
+	passwordService := service.NewPasswordService(mockRepo, mockResetRepo, new(MockPasswordHistoryRepository), tokenRepo, sessionRepo, repository.NewThrottleRepository(database.NewMemoryStore()), newTestLockoutService(mockRepo),
+		service.NewSessionService(sessionRepo, tokenRepo, newTestJWTConfig()), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), mailer,
+		config.AuthConfig{PasswordResetURL: "http://app/reset", PasswordResetExpiration: time.Hour, PasswordResetRequestLimit: 3, PasswordResetRequestWindow: time.Minute},
+		config.PasswordConfig{}, newTestJWTConfig())
+
+	testUser := &domain.User{ID: 1, Email: "test@example.com", IsActive: true}
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		mockRepo.On("GetByEmail", "missing@example.com").Return(nil, gorm.ErrRecordNotFound).Once()
+
+		assert.NoError(t, passwordService.ForgotPassword("missing@example.com"))
+		assert.Empty(t, mailer.sent)
+	})
+
+	var stored *domain.PasswordResetToken
+	mockRepo.On("GetByEmail", "test@example.com").Return(testUser, nil).Once()
+	mockResetRepo.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*domain.PasswordResetToken)
+		stored.ID = 7
+		stored.CreatedAt = time.Now()
+	}).Once()
+
+	require.NoError(t, passwordService.ForgotPassword("test@example.com"))
+	require.Len(t, mailer.sent, 1)
+
+	match := regexp.MustCompile(`http://app/reset\?token=(\S+)`).FindStringSubmatch(mailer.sent[0])
+	require.Len(t, match, 2)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+
+	// The raw token is never stored
+	assert.NotEqual(t, token, stored.TokenHash)
+	assert.Len(t, stored.TokenHash, 64)
+
+	t.Run("Requests Throttled Per Email", func(t *testing.T) {
+		mockRepo.On("GetByEmail", "missing@example.com").Return(nil, gorm.ErrRecordNotFound).Twice()
+		assert.NoError(t, passwordService.ForgotPassword("missing@example.com"))
+		assert.NoError(t, passwordService.ForgotPassword("missing@example.com"))
+
+		// Together with "Unknown Email" above, the limit of three is used up
+		assert.EqualError(t, passwordService.ForgotPassword("Missing@example.com"), "too many password reset requests")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockResetRepo.On("GetByTokenHash", stored.TokenHash).Return(stored, nil).Once()
+		mockRepo.On("GetByID", uint(1)).Return(testUser, nil).Once()
+		mockResetRepo.On("MarkUsed", uint(7)).Return(true, nil).Once()
+		mockRepo.On("Update", testUser).Return(nil).Once()
+		mockResetRepo.On("DeleteByUserID", uint(1)).Return(nil).Once()
+
+		issuedAt := time.Now()
+		require.NoError(t, passwordService.ResetPassword(token, "NewPassword123"))
+
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(testUser.Password), []byte("NewPassword123")))
+		assert.NotNil(t, testUser.PasswordChangedAt)
+
+		// Existing sessions are revoked
+		revoked, err := tokenRepo.IsUserTokenRevoked(1, issuedAt)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("Token Already Used", func(t *testing.T) {
+		usedAt := time.Now()
+		stored.UsedAt = &usedAt
+		mockResetRepo.On("GetByTokenHash", stored.TokenHash).Return(stored, nil).Once()
+
+		err := passwordService.ResetPassword(token, "AnotherPassword123")
+		assert.EqualError(t, err, "invalid or expired reset token")
+	})
+
+	mockRepo.AssertExpectations(t)
+	mockResetRepo.AssertExpectations(t)
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	historyRepo := new(MockPasswordHistoryRepository)
+	sessionRepo := newTestSessionRepository()
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+
+	policy := config.PasswordConfig{
+		MinLength:     8,
+		MaxLength:     72,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireNumber: true,
+		MinStrength:   3,
+		HistoryCount:  3,
+	}
+	resetRepo := new(MockPasswordResetRepository)
+	passwordService := service.NewPasswordService(mockRepo, resetRepo, historyRepo, tokenRepo, sessionRepo, repository.NewThrottleRepository(database.NewMemoryStore()), newTestLockoutService(mockRepo),
+		service.NewSessionService(sessionRepo, tokenRepo, newTestJWTConfig()), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), &capturingMailer{},
+		config.AuthConfig{}, policy, newTestJWTConfig())
+
+	current, _ := bcrypt.GenerateFromPassword([]byte("Current123"), bcrypt.MinCost)
+	previous, _ := bcrypt.GenerateFromPassword([]byte("Previous123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(current), IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+	historyRepo.On("ListRecentByUserID", uint(1), 2).Return([]domain.PasswordHistory{{UserID: 1, PasswordHash: string(previous)}}, nil)
+
+	t.Run("Wrong Current Password", func(t *testing.T) {
+		err := passwordService.ChangePassword(1, "current-session", "wrong", "NewPassword123", domain.ClientInfo{})
+		assert.EqualError(t, err, "current password is incorrect")
+	})
+
+	t.Run("Policy Violations", func(t *testing.T) {
+		err := passwordService.ChangePassword(1, "current-session", "Current123", "short", domain.ClientInfo{})
+
+		var policyErr *service.PasswordPolicyError
+		require.ErrorAs(t, err, &policyErr)
+		rules := make([]string, 0, len(policyErr.Violations))
+		for _, v := range policyErr.Violations {
+			rules = append(rules, v.Rule)
+		}
+		assert.ElementsMatch(t, []string{"min_length", "upper", "number", "strength"}, rules)
+	})
+
+	t.Run("Reused Password", func(t *testing.T) {
+		for _, reused := range []string{"Current123", "Previous123"} {
+			err := passwordService.ChangePassword(1, "current-session", "Current123", reused, domain.ClientInfo{})
+
+			var policyErr *service.PasswordPolicyError
+			require.ErrorAs(t, err, &policyErr)
+			require.Len(t, policyErr.Violations, 1)
+			assert.Equal(t, "history", policyErr.Violations[0].Rule)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("Update", testUser).Return(nil).Once()
+		historyRepo.On("Create", mock.MatchedBy(func(entry *domain.PasswordHistory) bool {
+			return entry.UserID == 1 && entry.PasswordHash == string(current)
+		})).Return(nil).Once()
+		historyRepo.On("Prune", uint(1), 2).Return(nil).Once()
+		resetRepo.On("DeleteByUserID", uint(1)).Return(nil).Once()
+		sessionRepo.On("ListActiveByUserID", uint(1)).Return([]domain.Session{
+			{ID: "current-session", UserID: 1},
+			{ID: "other-session", UserID: 1},
+		}, nil).Once()
+
+		require.NoError(t, passwordService.ChangePassword(1, "current-session", "Current123", "NewPassword123", domain.ClientInfo{}))
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(testUser.Password), []byte("NewPassword123")))
+
+		// Only the other sessions are signed out
+		revoked, err := tokenRepo.IsFamilyRevoked("other-session")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		revoked, err = tokenRepo.IsFamilyRevoked("current-session")
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	historyRepo.AssertExpectations(t)
+}
+
+// TestPasswordService_ChangePasswordLockout tests that wrong current passwords lock the account
+func TestPasswordService_ChangePasswordLockout(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	sessionRepo := newTestSessionRepository()
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	lockoutService := newTestLockoutService(mockRepo)
+	passwordService := service.NewPasswordService(mockRepo, new(MockPasswordResetRepository), new(MockPasswordHistoryRepository), tokenRepo, sessionRepo, repository.NewThrottleRepository(database.NewMemoryStore()), lockoutService,
+		service.NewSessionService(sessionRepo, tokenRepo, newTestJWTConfig()), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), &capturingMailer{},
+		config.AuthConfig{}, config.PasswordConfig{}, newTestJWTConfig())
+
+	current, _ := bcrypt.GenerateFromPassword([]byte("Current123"), bcrypt.MinCost)
+	testUser := &domain.User{ID: 1, Email: "test@example.com", Password: string(current), IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+
+	for i := 0; i < 5; i++ {
+		err := passwordService.ChangePassword(1, "current-session", "wrong", "NewPassword123", domain.ClientInfo{IP: "10.0.0.1"})
+		assert.EqualError(t, err, "current password is incorrect")
+	}
+
+	// Even the correct password is refused while the account is locked
+	var throttled *service.LoginThrottledError
+	err := passwordService.ChangePassword(1, "current-session", "Current123", "NewPassword123", domain.ClientInfo{IP: "10.0.0.1"})
+	require.ErrorAs(t, err, &throttled)
+	assert.True(t, throttled.Locked)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+
+	// Password logins share the same lockout
+	err = lockoutService.Check("test@example.com", "10.0.0.2")
+	assert.ErrorAs(t, err, &throttled)
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// MockSessionRepository is a mock implementation of SessionRepository
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(session *domain.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetByID(id string) (*domain.Session, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListActiveByUserID(userID uint) ([]domain.Session, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]domain.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Touch(id, ip, userAgent string, expiresAt time.Time) error {
+	args := m.Called(id, ip, userAgent, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) Revoke(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeByUserID(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// newTestSessionRepository returns a session repository that accepts every write
+func newTestSessionRepository() *MockSessionRepository {
+	repo := new(MockSessionRepository)
+	repo.On("Create", mock.Anything).Return(nil).Maybe()
+	repo.On("Touch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	repo.On("Revoke", mock.Anything).Return(nil).Maybe()
+	repo.On("RevokeByUserID", mock.Anything).Return(nil).Maybe()
+	return repo
+}
+
+// TestSessionService_Revoke tests that revoking a session invalidates its tokens immediately
+func TestSessionService_Revoke(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	sessionRepo := newTestSessionRepository()
+	store := database.NewMemoryStore()
+	tokenRepo := repository.NewTokenRepository(store)
+	keys := utils.NewHMACKeySet("test-secret")
+
+	authService := service.NewAuthService(mockRepo, tokenRepo, sessionRepo, repository.NewThrottleRepository(store),
+		newTestLockoutService(mockRepo), newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(), keys, &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+	sessionService := service.NewSessionService(sessionRepo, tokenRepo, newTestJWTConfig())
+
+	testUser := &domain.User{ID: 1, Email: "test@example.com", IsActive: true}
+	mockRepo.On("GetByID", uint(1)).Return(testUser, nil)
+
+	client := domain.ClientInfo{IP: "203.0.113.7", UserAgent: "test-agent"}
+	laptop, err := authService.IssueTokens(testUser, client)
+	require.NoError(t, err)
+	phone, err := authService.IssueTokens(testUser, client)
+	require.NoError(t, err)
+
+	laptopClaims, _ := keys.ValidateToken(laptop.AccessToken)
+	phoneClaims, _ := keys.ValidateToken(phone.AccessToken)
+
+	sessionRepo.AssertCalled(t, "Create", mock.MatchedBy(func(s *domain.Session) bool {
+		return s.ID == laptopClaims.FamilyID && s.UserAgent == "test-agent" && s.IP == "203.0.113.7"
+	}))
+
+	t.Run("Other Users Session", func(t *testing.T) {
+		sessionRepo.On("GetByID", phoneClaims.FamilyID).Return(&domain.Session{ID: phoneClaims.FamilyID, UserID: 1}, nil).Once()
+		assert.EqualError(t, sessionService.Revoke(2, 2, phoneClaims.FamilyID), "session not found")
+	})
+
+	t.Run("Unknown Session", func(t *testing.T) {
+		sessionRepo.On("GetByID", "missing").Return(nil, gorm.ErrRecordNotFound).Once()
+		assert.EqualError(t, sessionService.Revoke(1, 1, "missing"), "session not found")
+	})
+
+	t.Run("Sign Out Everywhere Else", func(t *testing.T) {
+		sessionRepo.On("ListActiveByUserID", uint(1)).Return([]domain.Session{
+			{ID: laptopClaims.FamilyID, UserID: 1},
+			{ID: phoneClaims.FamilyID, UserID: 1},
+		}, nil).Once()
+
+		count, err := sessionService.RevokeOthers(1, 1, laptopClaims.FamilyID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		// The phone's refresh token and access token family are revoked immediately
+		_, err = authService.RefreshToken(phone.RefreshToken, client)
+		assert.EqualError(t, err, "invalid refresh token")
+		revoked, err := tokenRepo.IsFamilyRevoked(phoneClaims.FamilyID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		// The current session keeps working
+		_, err = authService.RefreshToken(laptop.RefreshToken, client)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,229 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_ListByKeyset(t *testing.T) {
+	position := &domain.UserKeyset{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: 42}
+
+	cases := []struct {
+		name     string
+		sort     []domain.UserSort
+		before   bool
+		operator string
+		order    string
+	}{
+		{"Forward", nil, false, `(created_at, id) > ($1, $2)`, `ORDER BY "created_at","id" LIMIT 11`},
+		{"Backward", nil, true, `(created_at, id) < ($1, $2)`, `ORDER BY "created_at" DESC,"id" DESC LIMIT 11`},
+		{"Forward Descending", []domain.UserSort{{Field: "created_at", Desc: true}}, false, `(created_at, id) < ($1, $2)`, `ORDER BY "created_at" DESC,"id" DESC LIMIT 11`},
+		{"Backward Descending", []domain.UserSort{{Field: "created_at", Desc: true}}, true, `(created_at, id) > ($1, $2)`, `ORDER BY "created_at","id" LIMIT 11`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, queries := newDryRunDB(t)
+			repo := repository.NewUserRepository(db)
+
+			p := *position
+			p.Before = tc.before
+			_, _, err := repo.ListByKeyset(&domain.UserQuery{Sort: tc.sort}, &p, 10)
+			require.NoError(t, err)
+			require.Len(t, *queries, 1)
+
+			// No COUNT and no OFFSET
+			assert.Contains(t, (*queries)[0].sql, tc.operator)
+			assert.True(t, strings.HasSuffix((*queries)[0].sql, tc.order), (*queries)[0].sql)
+			assert.Equal(t, []interface{}{position.CreatedAt, uint(42)}, (*queries)[0].vars)
+		})
+	}
+
+	t.Run("Unsupported Sort", func(t *testing.T) {
+		db, _ := newDryRunDB(t)
+		repo := repository.NewUserRepository(db)
+
+		_, _, err := repo.ListByKeyset(&domain.UserQuery{Sort: []domain.UserSort{{Field: "username"}}}, nil, 10)
+		assert.Error(t, err)
+	})
+}
+
+func newCursorTestUsers(n int) []domain.User {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]domain.User, n)
+	for i := range users {
+		users[i] = domain.User{ID: uint(i + 1), Username: "user", CreatedAt: created.Add(time.Duration(i) * time.Minute)}
+	}
+	return users
+}
+
+func TestUserService_GetUsersByCursor(t *testing.T) {
+	users := newCursorTestUsers(5)
+	query := &domain.UserQuery{Search: "user"}
+
+	mockRepo := new(MockUserRepository)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, new(MockRedisInterface), newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+
+	// First page
+	mockRepo.On("ListByKeyset", query, (*domain.UserKeyset)(nil), 2).Return(users[0:2], true, nil).Once()
+	page, pagination, err := userService.GetUsersByCursor(query, "", 2, false)
+	require.NoError(t, err)
+	assert.Equal(t, users[0:2], page)
+	assert.NotEmpty(t, pagination.NextCursor)
+	assert.Empty(t, pagination.PrevCursor)
+	assert.Nil(t, pagination.Total)
+	next := pagination.NextCursor
+
+	t.Run("Next Page", func(t *testing.T) {
+		after := &domain.UserKeyset{CreatedAt: users[1].CreatedAt, ID: users[1].ID}
+		mockRepo.On("ListByKeyset", query, after, 2).Return(users[2:4], true, nil).Once()
+		mockRepo.On("Count", query).Return(int64(5), nil).Once()
+
+		page, pagination, err := userService.GetUsersByCursor(query, next, 2, true)
+		require.NoError(t, err)
+		assert.Equal(t, users[2:4], page)
+		assert.NotEmpty(t, pagination.NextCursor)
+		assert.NotEmpty(t, pagination.PrevCursor)
+		require.NotNil(t, pagination.Total)
+		assert.Equal(t, int64(5), *pagination.Total)
+
+		// The previous cursor reads backwards from the first user of the page
+		before := &domain.UserKeyset{CreatedAt: users[2].CreatedAt, ID: users[2].ID, Before: true}
+		mockRepo.On("ListByKeyset", query, before, 2).Return(users[0:2], false, nil).Once()
+
+		page, pagination, err = userService.GetUsersByCursor(query, pagination.PrevCursor, 2, false)
+		require.NoError(t, err)
+		assert.Equal(t, users[0:2], page)
+		assert.NotEmpty(t, pagination.NextCursor)
+		assert.Empty(t, pagination.PrevCursor)
+	})
+
+	t.Run("Last Page", func(t *testing.T) {
+		after := &domain.UserKeyset{CreatedAt: users[3].CreatedAt, ID: users[3].ID}
+		mockRepo.On("ListByKeyset", query, after, 2).Return(users[4:5], false, nil).Once()
+
+		cursor := cursorAfter(t, query, users[2:4])
+		_, pagination, err := userService.GetUsersByCursor(query, cursor, 2, false)
+		require.NoError(t, err)
+		assert.Empty(t, pagination.NextCursor)
+		assert.NotEmpty(t, pagination.PrevCursor)
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		tampered := next[:len(next)-2] + "AA"
+		if tampered == next {
+			tampered = next[:len(next)-2] + "BB"
+		}
+		for _, cursor := range []string{"garbage", tampered} {
+			_, _, err := userService.GetUsersByCursor(query, cursor, 2, false)
+			assert.EqualError(t, err, "invalid cursor")
+		}
+
+		// A cursor only works with the filters it was issued for
+		_, _, err := userService.GetUsersByCursor(&domain.UserQuery{Search: "other"}, next, 2, false)
+		assert.EqualError(t, err, "invalid cursor")
+
+		// ... and the secret it was signed with
+		other := service.NewUserService(mockRepo, nil, nil, nil, new(MockRedisInterface), newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "other-cursor-secret")
+		_, _, err = other.GetUsersByCursor(query, next, 2, false)
+		assert.EqualError(t, err, "invalid cursor")
+	})
+
+	t.Run("Unsupported Sort", func(t *testing.T) {
+		_, _, err := userService.GetUsersByCursor(&domain.UserQuery{Sort: []domain.UserSort{{Field: "username"}}}, "", 2, false)
+		assert.EqualError(t, err, "cursor pagination only supports sorting by created_at")
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+// cursorAfter returns the next cursor of a page ending with the given users
+func cursorAfter(t *testing.T, query *domain.UserQuery, page []domain.User) string {
+	repo := new(MockUserRepository)
+	repo.On("ListByKeyset", query, mock.Anything, len(page)).Return(page, true, nil)
+	helper := service.NewUserService(repo, nil, nil, nil, new(MockRedisInterface), newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+
+	_, pagination, err := helper.GetUsersByCursor(query, "", len(page), false)
+	require.NoError(t, err)
+	return pagination.NextCursor
+}
+
+func TestUserHandler_GetUsersByCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	users := newCursorTestUsers(3)
+	mockRepo := new(MockUserRepository)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, new(MockRedisInterface), newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+	router := gin.New()
+	router.GET("/api/v1/users", handler.NewUserHandler(userService).GetUsers)
+
+	get := func(rawQuery string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/users?"+rawQuery, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("First Page", func(t *testing.T) {
+		mockRepo.On("ListByKeyset", &domain.UserQuery{IsActive: boolPtr(true)}, (*domain.UserKeyset)(nil), 2).Return(users[0:2], true, nil).Once()
+
+		w := get("cursor=&limit=2&is_active=true")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data domain.UserListResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Nil(t, response.Data.Pagination)
+		require.NotNil(t, response.Data.CursorPagination)
+		next := response.Data.CursorPagination.NextCursor
+		assert.NotEmpty(t, next)
+
+		link := w.Header().Get("Link")
+		assert.Contains(t, link, `</api/v1/users?cursor=&is_active=true&limit=2>; rel="first"`)
+		assert.Contains(t, link, `</api/v1/users?cursor=`+next+`&is_active=true&limit=2>; rel="next"`)
+		assert.NotContains(t, link, `rel="prev"`)
+	})
+
+	t.Run("Offset Mode Unchanged", func(t *testing.T) {
+		mockRepo.On("List", &domain.UserQuery{}, 0, 10).Return(users, int64(3), nil).Once()
+
+		w := get("")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Link"))
+		assert.Contains(t, w.Body.String(), `"pagination":{"page":1,"limit":10,"total":3,"total_pages":1}`)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		for _, rawQuery := range []string{
+			"cursor=garbage",
+			"cursor=&page=2",
+			"cursor=&include_total=maybe",
+			"cursor=&sort=username",
+		} {
+			w := get(rawQuery)
+			assert.Equal(t, http.StatusBadRequest, w.Code, rawQuery)
+		}
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
@@ -0,0 +1,160 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/middleware"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserRepository_ConditionalUpdate(t *testing.T) {
+	db, _ := newDryRunDB(t)
+	var sql string
+	var vars []interface{}
+	err := db.Callback().Update().After("gorm:update").Register("test:capture_update", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	require.NoError(t, err)
+
+	user := &domain.User{ID: 5, Email: "jane@example.com", Version: 3}
+	err = repository.NewUserRepository(db).Update(user)
+
+	assert.Contains(t, sql, `UPDATE "users" SET`)
+	assert.Contains(t, sql, `WHERE version = $`)
+	assert.Contains(t, sql, `"id" = $`)
+	assert.Contains(t, vars, uint(4), "the new version is written")
+	assert.Contains(t, vars, uint(3), "the read version is the condition")
+
+	// A dry run affects no rows, like an update that lost the race
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	assert.Equal(t, uint(3), user.Version)
+}
+
+func TestUserHandler_ETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockRedis := new(MockRedisInterface)
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", assert.AnError)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, 30*time.Minute).Return(nil)
+	keyRepo := new(MockAPIKeyRepository)
+	userHandler := handler.NewUserHandler(service.NewUserService(mockRepo, repository.NewTokenRepository(database.NewMemoryStore()), newTestSessionRepository(), keyRepo, mockRedis, newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret"))
+
+	router := gin.New()
+	router.Use(middleware.RequireIfMatch([]string{"DELETE /users/:id", " patch /users/:id "}))
+	router.GET("/users/:id", userHandler.GetUser)
+	router.PUT("/users/:id", userHandler.UpdateUser)
+	router.PATCH("/users/:id", userHandler.PatchUser)
+	router.DELETE("/users/:id", userHandler.DeleteUser)
+
+	send := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/users/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	newUser := func() *domain.User {
+		return &domain.User{ID: 1, Email: "jane@example.com", Username: "jane", IsActive: true, Version: 3}
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+
+		w := send(http.MethodGet, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("Matching Version", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		mockRepo.On("Update", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(0).(*domain.User).Version++
+		}).Once()
+
+		w := send(http.MethodPut, `"2", "3"`, `{"first_name": "Janet"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("Stale Version", func(t *testing.T) {
+		for _, ifMatch := range []string{`"2"`, `W/"3"`, `"abc"`} {
+			mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+
+			w := send(http.MethodPut, ifMatch, `{"first_name": "Janet"}`)
+			assert.Equal(t, http.StatusPreconditionFailed, w.Code, ifMatch)
+		}
+	})
+
+	t.Run("Lost Race", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		mockRepo.On("Update", mock.Anything).Return(repository.ErrVersionConflict).Once()
+
+		w := send(http.MethodPut, "", `{"first_name": "Janet"}`)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodDelete, "", "").Code)
+		assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodPatch, "", `{}`).Code)
+
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodDelete, `"2"`, "").Code)
+
+		mockRepo.On("GetByID", uint(1)).Return(newUser(), nil).Once()
+		mockRepo.On("Delete", uint(1)).Return(nil).Once()
+		keyRepo.On("RevokeByUserID", uint(1)).Return(nil).Once()
+		assert.Equal(t, http.StatusOK, send(http.MethodDelete, `*`, "").Code)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserRepository_WritesInvalidateCache(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	cache := database.NewMemoryStore()
+	userRepo := repository.NewCacheInvalidatingUserRepository(mockRepo, cache)
+	userService := service.NewUserService(userRepo, nil, nil, nil, cache, newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+
+	mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1, Version: 1}, nil).Once()
+	user, err := userService.GetUser(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), user.Version)
+
+	// Another service, such as a password change, bumps the version
+	mockRepo.On("Update", mock.Anything).Return(nil).Once()
+	require.NoError(t, userRepo.Update(&domain.User{ID: 1, Version: 2}))
+
+	mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1, Version: 2}, nil).Once()
+	user, err = userService.GetUser(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), user.Version, "the ETag must not come from the stale cache entry")
+
+	// Imports clear every updated user
+	_, _ = userService.GetUser(1)
+	mockRepo.On("SaveBatch", mock.Anything, mock.Anything).Return(nil).Once()
+	require.NoError(t, userRepo.SaveBatch(nil, []*domain.User{{ID: 1}}))
+	_, err = cache.Get(t.Context(), repository.UserCacheKey(1))
+	assert.Error(t, err)
+
+	mockRepo.AssertExpectations(t)
+	assert.Same(t, mockRepo, repository.NewCacheInvalidatingUserRepository(mockRepo, nil))
+}
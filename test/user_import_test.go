@@ -0,0 +1,460 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// MockUserImportJobRepository is a mock implementation of UserImportJobRepository
+type MockUserImportJobRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserImportJobRepository) Create(job *domain.UserImportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockUserImportJobRepository) GetByID(id string) (*domain.UserImportJob, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserImportJob), args.Error(1)
+}
+
+func (m *MockUserImportJobRepository) Update(job *domain.UserImportJob) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockUserImportJobRepository) FailStale(before time.Time, reason string) (int64, error) {
+	args := m.Called(before, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+var testImportConfig = config.ImportConfig{MaxBytes: 1 << 20, MaxRows: 100, AsyncThreshold: 100, BatchSize: 100}
+
+func newTestImportService(userRepo *MockUserRepository, importCfg config.ImportConfig) (service.UserImportService, *MockUserImportJobRepository) {
+	jobRepo := new(MockUserImportJobRepository)
+	jobRepo.On("Create", mock.Anything).Return(nil)
+	return service.NewUserImportService(userRepo, jobRepo, newTestPasswordHasher(), importCfg), jobRepo
+}
+
+// rowStatuses maps the line of each row result to its status
+func rowStatuses(job *domain.UserImportJob) map[int]domain.UserImportRowStatus {
+	statuses := make(map[int]domain.UserImportRowStatus)
+	for _, result := range job.Results {
+		statuses[result.Line] = result.Status
+	}
+	return statuses
+}
+
+const testImportCSV = "email,username,first_name,password_hash,email_verified\n" +
+	"new@example.com,newbie,New,,true\n" +
+	"not-an-email,badrow,Bad,,\n" +
+	"jane@example.com,jane,Janet,,\n" +
+	"other@example.com,newbie,Copy,,\n" +
+	"short@example.com,ab,,,\n" +
+	"too,many,fields,here,now,extra\n"
+
+func TestUserImportService_CSV(t *testing.T) {
+	jane := domain.User{ID: 7, Email: "jane@example.com", Username: "jane", FirstName: "Jane", Version: 2}
+
+	t.Run("Dry Run", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByEmailsOrUsernames", []string{"new@example.com", "jane@example.com"}, []string{"newbie", "jane"}).Return([]domain.User{jane}, nil).Once()
+		importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+		jobRepo.On("Update", mock.Anything).Return(nil)
+
+		job, err := importService.Import(1, strings.NewReader(testImportCSV), domain.UserImportOptions{Format: domain.UserFileCSV, DryRun: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, domain.ImportJobCompleted, job.Status)
+		assert.Equal(t, 6, job.TotalRows)
+		assert.Equal(t, map[int]domain.UserImportRowStatus{
+			2: domain.ImportRowCreated,
+			3: domain.ImportRowInvalid,
+			4: domain.ImportRowSkipped,
+			5: domain.ImportRowInvalid, // Username repeats line 2
+			6: domain.ImportRowInvalid,
+			7: domain.ImportRowInvalid,
+		}, rowStatuses(job))
+		assert.Equal(t, []domain.FieldError{{Field: "username", Message: "already appears on line 2"}}, job.Results[3].Errors)
+		assert.Equal(t, []domain.FieldError{{Field: "email", Message: "already exists"}}, job.Results[2].Errors)
+		assert.Equal(t, 1, job.Created)
+		assert.Equal(t, 4, job.Invalid)
+
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "SaveBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		existing := jane
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{existing}, nil).Once()
+		mockRepo.On("SaveBatch", mock.MatchedBy(func(creates []*domain.User) bool {
+			return len(creates) == 1 && creates[0].Email == "new@example.com" && creates[0].EmailVerifiedAt != nil &&
+				strings.HasPrefix(creates[0].Password, "$2") && creates[0].Role == domain.RoleUser
+		}), mock.MatchedBy(func(updates []*domain.User) bool {
+			return len(updates) == 1 && updates[0].ID == 7 && updates[0].FirstName == "Janet" && updates[0].Version == 2
+		})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(0).([]*domain.User)[0].ID = 8
+		}).Once()
+		importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+		jobRepo.On("Update", mock.Anything).Return(nil)
+
+		job, err := importService.Import(1, strings.NewReader(testImportCSV), domain.UserImportOptions{Format: domain.UserFileCSV, DuplicatePolicy: domain.DuplicateUpdate})
+		require.NoError(t, err)
+
+		assert.Equal(t, domain.ImportJobCompleted, job.Status)
+		assert.Equal(t, domain.ImportRowCreated, job.Results[0].Status)
+		assert.Equal(t, uint(8), job.Results[0].UserID)
+		assert.Equal(t, domain.ImportRowUpdated, job.Results[2].Status)
+		assert.Equal(t, uint(7), job.Results[2].UserID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Update Keeps Password", func(t *testing.T) {
+		existing := jane
+		existing.Password = "$2a$04$existing"
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{existing}, nil).Once()
+		importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+		jobRepo.On("Update", mock.Anything).Return(nil)
+
+		csv := "email,username,password_hash\njane@example.com,jane,sha256$NaCl$b20ab74aa2549f7e13a0e886cb4471cc2e70fcd2ce8075c0ee6483abba6132f3\n"
+		job, err := importService.Import(1, strings.NewReader(csv), domain.UserImportOptions{Format: domain.UserFileCSV, DuplicatePolicy: domain.DuplicateUpdate})
+		require.NoError(t, err)
+
+		assert.Equal(t, domain.ImportRowInvalid, job.Results[0].Status)
+		assert.Equal(t, []domain.FieldError{{Field: "password_hash", Message: "cannot be changed for an existing user"}}, job.Results[0].Errors)
+		mockRepo.AssertNotCalled(t, "SaveBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fail On Duplicate", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{jane}, nil).Once()
+		importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+		jobRepo.On("Update", mock.Anything).Return(nil)
+
+		job, err := importService.Import(1, strings.NewReader(testImportCSV), domain.UserImportOptions{Format: domain.UserFileCSV, DuplicatePolicy: domain.DuplicateFail})
+		require.NoError(t, err)
+
+		// Nothing is imported, not even the valid row
+		assert.Equal(t, domain.ImportJobFailed, job.Status)
+		assert.Equal(t, "import contains duplicates", job.Error)
+		assert.Equal(t, domain.ImportRowSkipped, job.Results[0].Status)
+		assert.Equal(t, domain.ImportRowDuplicate, job.Results[2].Status)
+		mockRepo.AssertNotCalled(t, "SaveBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Batch Failure", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{}, nil)
+		mockRepo.On("SaveBatch", mock.Anything, mock.Anything).Return(gorm.ErrDuplicatedKey).Once()
+		mockRepo.On("SaveBatch", mock.Anything, mock.Anything).Return(nil).Once()
+		cfg := testImportConfig
+		cfg.BatchSize = 2
+		importService, jobRepo := newTestImportService(mockRepo, cfg)
+		jobRepo.On("Update", mock.Anything).Return(nil)
+
+		csv := "email,username\na@example.com,user_a\nb@example.com,user_b\nc@example.com,user_c\n"
+		job, err := importService.Import(1, strings.NewReader(csv), domain.UserImportOptions{Format: domain.UserFileCSV})
+		require.NoError(t, err)
+
+		// Only the rows of the failed batch fail
+		assert.Equal(t, map[int]domain.UserImportRowStatus{
+			2: domain.ImportRowFailed,
+			3: domain.ImportRowFailed,
+			4: domain.ImportRowCreated,
+		}, rowStatuses(job))
+		mockRepo.AssertNumberOfCalls(t, "FindByEmailsOrUsernames", 2)
+		mockRepo.AssertNumberOfCalls(t, "SaveBatch", 2)
+	})
+}
+
+func TestUserImportService_NDJSON(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("FindByEmailsOrUsernames", []string{"a@example.com"}, []string{"user_a"}).Return([]domain.User{}, nil).Once()
+	importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+	jobRepo.On("Update", mock.Anything).Return(nil)
+
+	body := `{"email": "a@example.com", "username": "user_a", "email_verified": true}
+
+{"email": "b@example.com", "username": "user_b", "role": "admin"}
+null
+{"email": "c@example.com", "username": "user_c"} {}
+`
+	job, err := importService.Import(1, strings.NewReader(body), domain.UserImportOptions{Format: domain.UserFileNDJSON, DryRun: true})
+	require.NoError(t, err)
+
+	// Blank lines are skipped but counted
+	assert.Equal(t, map[int]domain.UserImportRowStatus{
+		1: domain.ImportRowCreated,
+		3: domain.ImportRowInvalid,
+		4: domain.ImportRowInvalid,
+		5: domain.ImportRowInvalid,
+	}, rowStatuses(job))
+	assert.Contains(t, job.Results[1].Errors[0].Message, `unknown field "role"`)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserImportService_JSON(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("FindByEmailsOrUsernames", []string{"new@example.com"}, []string{"newuser"}).Return([]domain.User{}, nil).Once()
+	mockRepo.On("SaveBatch", mock.MatchedBy(func(creates []*domain.User) bool {
+		return len(creates) == 1 && creates[0].Email == "new@example.com" &&
+			strings.HasPrefix(creates[0].Password, "pbkdf2_sha256$") && creates[0].EmailVerifiedAt != nil
+	}), mock.Anything).Return(nil).Once()
+	importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+	jobRepo.On("Update", mock.Anything).Return(nil)
+
+	body := `{"users": [
+		{"email": "new@example.com", "username": "newuser", "password_hash": "pbkdf2_sha256$1000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c=", "email_verified": true},
+		{"email": "plain@example.com", "username": "plain", "password_hash": "hunter2"},
+		{"email": "role@example.com", "username": "role", "role": "admin"},
+		"not an object"
+	]}`
+	job, err := importService.Import(1, strings.NewReader(body), domain.UserImportOptions{Format: domain.UserFileJSON})
+	require.NoError(t, err)
+
+	// Rows are numbered by their position in users
+	assert.Equal(t, map[int]domain.UserImportRowStatus{
+		1: domain.ImportRowCreated,
+		2: domain.ImportRowInvalid,
+		3: domain.ImportRowInvalid,
+		4: domain.ImportRowInvalid,
+	}, rowStatuses(job))
+	assert.Equal(t, []domain.FieldError{{Field: "password_hash", Message: "unknown password hash format"}}, job.Results[1].Errors)
+	mockRepo.AssertExpectations(t)
+
+	for _, body := range []string{`[]`, `{"users": [{}]} {}`, `{"accounts": []}`, `{"users": []}`} {
+		_, err := importService.Import(1, strings.NewReader(body), domain.UserImportOptions{Format: domain.UserFileJSON})
+		var fileErr *service.ImportFileError
+		assert.ErrorAs(t, err, &fileErr, body)
+	}
+}
+
+func TestUserImportService_FileErrors(t *testing.T) {
+	importService, _ := newTestImportService(new(MockUserRepository), config.ImportConfig{MaxBytes: 64, MaxRows: 2, AsyncThreshold: 100, BatchSize: 100})
+	csv := domain.UserImportOptions{Format: domain.UserFileCSV}
+
+	cases := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{"Empty", "", "import file is empty"},
+		{"No Rows", "email,username\n", "import file has no rows"},
+		{"Unknown Column", "email,username,role\n", "unknown column: role"},
+		{"Duplicate Column", "email,username,Email\n", "duplicate column: email"},
+		{"Missing Column", "\ufeffemail,first_name\n", "missing column: username"},
+		{"Malformed", "email,username\n\"a@example.com,user\n", ""},
+		{"Too Many Rows", "email,username\na@x.io,aaa\nb@x.io,bbb\nc@x.io,ccc\n", "import has too many rows"},
+		{"Too Large", "email,username\n" + strings.Repeat("a", 100), "import file too large"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := importService.Import(1, strings.NewReader(tc.body), csv)
+			require.Error(t, err)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+
+	_, err := importService.Import(1, strings.NewReader("email,username\n"), domain.UserImportOptions{Format: domain.UserFileCSV, DuplicatePolicy: "merge"})
+	assert.EqualError(t, err, "invalid duplicate policy")
+}
+
+func TestUserImportService_Async(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{}, nil)
+	mockRepo.On("SaveBatch", mock.Anything, mock.Anything).Return(nil)
+	cfg := testImportConfig
+	cfg.AsyncThreshold = 1
+	importService, jobRepo := newTestImportService(mockRepo, cfg)
+
+	finished := make(chan *domain.UserImportJob, 1)
+	jobRepo.On("Update", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		if job := args.Get(0).(*domain.UserImportJob); job.Finished() {
+			finished <- job
+		}
+	})
+
+	job, err := importService.Import(1, strings.NewReader("email,username\na@example.com,user_a\nb@example.com,user_b\n"), domain.UserImportOptions{Format: domain.UserFileCSV})
+	require.NoError(t, err)
+	assert.Equal(t, domain.ImportJobPending, job.Status)
+	assert.Empty(t, job.Results)
+
+	select {
+	case done := <-finished:
+		assert.Equal(t, job.ID, done.ID)
+		assert.Equal(t, domain.ImportJobCompleted, done.Status)
+		assert.Equal(t, 2, done.Created)
+		assert.Equal(t, 2, done.ProcessedRows)
+	case <-time.After(5 * time.Second):
+		t.Fatal("import job did not finish")
+	}
+}
+
+func TestAdminHandler_ImportAndExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, nil, newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+	importService, jobRepo := newTestImportService(mockRepo, testImportConfig)
+	finished := make(chan string, 10)
+	jobRepo.On("Update", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		if job := args.Get(0).(*domain.UserImportJob); job.Finished() {
+			finished <- job.ID
+		}
+	})
+	adminHandler := handler.NewAdminHandler(userService, nil, nil, importService)
+
+	router := gin.New()
+	router.POST("/admin/users/import", adminHandler.ImportUsers)
+	router.GET("/admin/users/import/:id", adminHandler.GetImportJob)
+	router.GET("/admin/users/export", adminHandler.ExportUsers)
+	router.GET("/admin/users/:id/sessions", func(c *gin.Context) {}) // Shares the path prefix
+
+	send := func(method, target, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, target, bytes.NewBufferString(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Import", func(t *testing.T) {
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{}, nil).Once()
+
+		w := send(http.MethodPost, "/admin/users/import?dry_run=true&on_duplicate=fail", "text/csv; charset=utf-8", "email,username\na@example.com,user_a\n")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data domain.UserImportJob `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, domain.ImportJobCompleted, response.Data.Status)
+		assert.True(t, response.Data.DryRun)
+		assert.Equal(t, domain.DuplicateFail, response.Data.DuplicatePolicy)
+	})
+
+	t.Run("Import JSON", func(t *testing.T) {
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{}, nil).Once()
+
+		w := send(http.MethodPost, "/admin/users/import?dry_run=true", "application/json", `{"users": [{"email": "a@example.com", "username": "user_a"}]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"format":"json"`)
+	})
+
+	t.Run("Import Async", func(t *testing.T) {
+		mockRepo.On("FindByEmailsOrUsernames", mock.Anything, mock.Anything).Return([]domain.User{}, nil).Once()
+
+		w := send(http.MethodPost, "/admin/users/import?async=true&dry_run=true", "application/x-ndjson", `{"email": "a@example.com", "username": "user_a"}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		assert.Regexp(t, `^/admin/users/import/[0-9a-f-]{36}$`, w.Header().Get("Location"))
+
+		id := strings.TrimPrefix(w.Header().Get("Location"), "/admin/users/import/")
+		for {
+			select {
+			case done := <-finished:
+				if done != id {
+					continue
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("import job did not finish")
+			}
+			break
+		}
+	})
+
+	t.Run("Import Rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, send(http.MethodPost, "/admin/users/import", "application/xml", `<users/>`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/users/import?on_duplicate=merge", "text/csv", "email,username\n").Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/users/import", "text/csv", "email,role\n").Code)
+	})
+
+	t.Run("Job Status", func(t *testing.T) {
+		jobRepo.On("GetByID", "job-1").Return(&domain.UserImportJob{ID: "job-1", Status: domain.ImportJobRunning, TotalRows: 10, ProcessedRows: 4}, nil).Once()
+		jobRepo.On("GetByID", "job-2").Return(nil, gorm.ErrRecordNotFound).Once()
+
+		w := send(http.MethodGet, "/admin/users/import/job-1", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"processed_rows":4`)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/admin/users/import/job-2", "", "").Code)
+	})
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := []domain.User{
+		{ID: 1, Email: "jane@example.com", Username: "jane", FirstName: "=HYPERLINK(\"x\")", Role: domain.RoleUser, IsActive: true, CreatedAt: created, UpdatedAt: created},
+		{ID: 2, Email: "john@example.com", Username: "john", LastName: "Doe, Jr.", Role: domain.RoleAdmin, EmailVerifiedAt: &created, CreatedAt: created, UpdatedAt: created},
+	}
+
+	t.Run("Export CSV", func(t *testing.T) {
+		active := true
+		mockRepo.On("ListByKeyset", &domain.UserQuery{IsActive: &active}, (*domain.UserKeyset)(nil), 500).Return(users, false, nil).Once()
+
+		w := send(http.MethodGet, "/admin/users/export?is_active=true", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="users.csv"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "id,email,username,first_name,last_name,role,is_active,email_verified,created_at,updated_at\n"+
+			"1,jane@example.com,jane,\"'=HYPERLINK(\"\"x\"\")\",,user,true,false,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z\n"+
+			"2,john@example.com,john,,\"Doe, Jr.\",admin,false,true,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z\n", w.Body.String())
+	})
+
+	t.Run("Export NDJSON Pages", func(t *testing.T) {
+		after := &domain.UserKeyset{CreatedAt: created, ID: 1}
+		mockRepo.On("ListByKeyset", &domain.UserQuery{}, (*domain.UserKeyset)(nil), 500).Return(users[:1], true, nil).Once()
+		mockRepo.On("ListByKeyset", &domain.UserQuery{}, after, 500).Return(users[1:], false, nil).Once()
+
+		w := send(http.MethodGet, "/admin/users/export?format=ndjson", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		var row domain.UserExportRow
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+		assert.Equal(t, uint(2), row.ID)
+		assert.True(t, row.EmailVerified)
+		assert.NotContains(t, w.Body.String(), "password")
+	})
+
+	t.Run("Export Empty", func(t *testing.T) {
+		mockRepo.On("ListByKeyset", &domain.UserQuery{Search: "nobody"}, (*domain.UserKeyset)(nil), 500).Return([]domain.User{}, false, nil).Once()
+
+		w := send(http.MethodGet, "/admin/users/export?q=nobody", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "id,email,username,first_name,last_name,role,is_active,email_verified,created_at,updated_at\n", w.Body.String())
+	})
+
+	t.Run("Export Rejected", func(t *testing.T) {
+		for _, rawQuery := range []string{"format=xlsx", "page=2", "sort=username", "cursor=", "deleted=only"} {
+			assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/admin/users/export?"+rawQuery, "", "").Code, rawQuery)
+		}
+	})
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestPatchUserRequest_Unmarshal(t *testing.T) {
+	var patch domain.PatchUserRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"first_name": null, "last_name": "Doe", "is_active": false}`), &patch))
+
+	assert.Equal(t, domain.PatchString{Set: true, Null: true}, patch.FirstName)
+	assert.Equal(t, domain.PatchString{Set: true, Value: "Doe"}, patch.LastName)
+	assert.Equal(t, domain.PatchString{}, patch.Email)
+	assert.Equal(t, domain.PatchBool{Set: true, Value: false}, patch.IsActive)
+}
+
+func TestUserHandler_Patch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockRedis := new(MockRedisInterface)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, 30*time.Minute).Return(nil)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	keyRepo := new(MockAPIKeyRepository)
+	userHandler := handler.NewUserHandler(service.NewUserService(mockRepo, tokenRepo, newTestSessionRepository(), keyRepo, mockRedis, newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret"))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.PATCH("/users/profile", userHandler.PatchProfile)
+	router.PATCH("/users/:id", userHandler.PatchUser)
+	router.PUT("/users/:id", userHandler.UpdateUser)
+
+	newUser := func(id uint) *domain.User {
+		return &domain.User{ID: id, Email: "jane@example.com", Username: "jane", FirstName: "Jane", LastName: "Doe", Avatar: "https://cdn.example.com/jane.png", IsActive: true}
+	}
+
+	patch := func(path, contentType, body string) (*httptest.ResponseRecorder, domain.APIResponse) {
+		req, _ := http.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response domain.APIResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	t.Run("Null Clears And Absent Keeps", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(newUser(1), nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool {
+			return u.FirstName == "Jane" && u.LastName == "" && u.Avatar == "https://cdn.example.com/jane.png" && u.Email == "jane@example.com"
+		})).Return(nil).Once()
+
+		w, _ := patch("/users/profile", "application/merge-patch+json", `{"last_name": null}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Avatar Is Not Patchable", func(t *testing.T) {
+		// Avatars are uploaded; a URL from the client is not accepted
+		w, _ := patch("/users/profile", "application/merge-patch+json", `{"avatar": "https://evil.example.com/a.png"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Per Field Validation", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(newUser(1), nil).Once()
+
+		w, response := patch("/users/profile", "application/json", `{"email": "not-an-email", "username": null}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		encoded, _ := json.Marshal(response.Error)
+		var fields []domain.FieldError
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		assert.Equal(t, []domain.FieldError{
+			{Field: "email", Message: "must be a valid email address"},
+			{Field: "username", Message: "cannot be null"},
+		}, fields)
+	})
+
+	t.Run("Privileged Field On Own Account", func(t *testing.T) {
+		for _, path := range []string{"/users/profile", "/users/1"} {
+			w, _ := patch(path, "application/merge-patch+json", `{"is_active": false}`)
+			assert.Equal(t, http.StatusForbidden, w.Code, path)
+		}
+	})
+
+	t.Run("Privileged Field On Another Account", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(2)).Return(newUser(2), nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool {
+			return u.ID == 2 && !u.IsActive && u.FirstName == "Jane"
+		})).Return(nil).Once()
+		keyRepo.On("RevokeByUserID", uint(2)).Return(nil).Once()
+
+		w, _ := patch("/users/2", "application/merge-patch+json", `{"is_active": false}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Deactivation signs the user out everywhere
+		revoked, err := tokenRepo.IsUserTokenRevoked(2, time.Now().Add(-time.Second))
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		keyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(newUser(1), nil).Once()
+		mockRepo.On("GetByUsername", "john").Return(&domain.User{ID: 3}, nil).Once()
+
+		w, _ := patch("/users/profile", "application/merge-patch+json", `{"username": "john"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Put Conflict", func(t *testing.T) {
+		// PUT checks uniqueness like PATCH instead of failing on the unique index
+		mockRepo.On("GetByID", uint(2)).Return(newUser(2), nil).Once()
+		mockRepo.On("GetByEmail", "john@example.com").Return(&domain.User{ID: 3}, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodPut, "/users/2", bytes.NewBufferString(`{"email": "john@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockRepo.AssertNotCalled(t, "Update", mock.MatchedBy(func(u *domain.User) bool { return u.Email == "john@example.com" }))
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+		w, _ := patch("/users/9", "application/merge-patch+json", `{"first_name": "X"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Invalid Patch", func(t *testing.T) {
+		for _, body := range []string{`{"role": "admin"}`, `null`, `[]`, `{"first_name": 1}`, `{} {}`} {
+			w, _ := patch("/users/profile", "application/merge-patch+json", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+
+		w, _ := patch("/users/profile", "text/plain", `{}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
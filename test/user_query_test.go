@@ -0,0 +1,140 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type capturedQuery struct {
+	sql  string
+	vars []interface{}
+}
+
+// newDryRunDB returns a postgres gorm DB that records queries instead of running them
+func newDryRunDB(t *testing.T) (*gorm.DB, *[]capturedQuery) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1 sslmode=disable"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+
+	queries := &[]capturedQuery{}
+	err = db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		*queries = append(*queries, capturedQuery{sql: tx.Statement.SQL.String(), vars: tx.Statement.Vars})
+	})
+	require.NoError(t, err)
+
+	return db, queries
+}
+
+func TestUserRepository_ListQuery(t *testing.T) {
+	t.Run("Filters And Sort", func(t *testing.T) {
+		db, queries := newDryRunDB(t)
+		repo := repository.NewUserRepository(db)
+
+		active := false
+		after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		_, _, err := repo.List(&domain.UserQuery{
+			IsActive:     &active,
+			CreatedAfter: &after,
+			EmailDomain:  "example.com",
+			Search:       "50%_off",
+			Sort:         []domain.UserSort{{Field: "created_at", Desc: true}, {Field: "username"}},
+		}, 20, 10)
+		require.NoError(t, err)
+		require.Len(t, *queries, 2)
+
+		list := (*queries)[1]
+		assert.Contains(t, list.sql, "is_active = $1 AND created_at >= $2 AND LOWER(email) LIKE $3")
+		assert.Contains(t, list.sql, `ORDER BY "created_at" DESC,"username","id" LIMIT 10 OFFSET 20`)
+		assert.Equal(t, []interface{}{false, after, "%@example.com", `%50\%\_off%`}, list.vars[:4])
+	})
+
+	t.Run("Default Order", func(t *testing.T) {
+		db, queries := newDryRunDB(t)
+		repo := repository.NewUserRepository(db)
+
+		_, _, err := repo.List(nil, 0, 10)
+		require.NoError(t, err)
+		assert.Contains(t, (*queries)[1].sql, `ORDER BY "id" LIMIT 10`)
+		assert.NotContains(t, (*queries)[1].sql, "WHERE is_active")
+	})
+
+	t.Run("Sort Field Not Allowed", func(t *testing.T) {
+		db, _ := newDryRunDB(t)
+		repo := repository.NewUserRepository(db)
+
+		_, _, err := repo.List(&domain.UserQuery{Sort: []domain.UserSort{{Field: "password; DROP TABLE users"}}}, 0, 10)
+		assert.EqualError(t, err, "invalid sort field: password; DROP TABLE users")
+	})
+}
+
+func TestUserHandler_GetUsersQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, new(MockRedisInterface), newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+	router := gin.New()
+	router.GET("/users", handler.NewUserHandler(userService).GetUsers)
+
+	get := func(rawQuery string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/users?"+rawQuery, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Typed Query", func(t *testing.T) {
+		active := true
+		after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		expected := &domain.UserQuery{
+			IsActive:      &active,
+			CreatedAfter:  &after,
+			CreatedBefore: &before,
+			EmailDomain:   "example.com",
+			Search:        "jane doe",
+			Sort:          []domain.UserSort{{Field: "created_at", Desc: true}, {Field: "username"}},
+		}
+		mockRepo.On("List", expected, 10, 10).Return([]domain.User{}, int64(0), nil).Once()
+
+		w := get("page=2&is_active=true&created_after=2024-01-01&created_before=2024-06-01T12:00:00Z&email_domain=@Example.COM&q=jane+doe&sort=-created_at,username")
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		for _, rawQuery := range []string{
+			"role=admin",
+			"sort=password",
+			"sort=-created_at,created_at",
+			"sort=username;DROP TABLE users",
+			"is_active=maybe",
+			"created_after=yesterday",
+			"email_domain=%25",
+			"q=a&q=b",
+		} {
+			w := get(rawQuery)
+			assert.Equal(t, http.StatusBadRequest, w.Code, rawQuery)
+
+			var response domain.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "Invalid query", response.Message)
+		}
+		// Only the typed query above reached the repository
+		mockRepo.AssertNumberOfCalls(t, "List", 1)
+	})
+}
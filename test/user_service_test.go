@@ -7,10 +7,14 @@ import (
 
 	"go-template-structure/internal/config"
 	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
 	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 // MockUserRepository is a mock implementation of UserRepository
@@ -57,11 +61,49 @@ func (m *MockUserRepository) Delete(id uint) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List(offset, limit int) ([]domain.User, int64, error) {
-	args := m.Called(offset, limit)
+func (m *MockUserRepository) GetByIDWithDeleted(id uint) (*domain.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Restore(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) HardDelete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) FindByEmailsOrUsernames(emails, usernames []string) ([]domain.User, error) {
+	args := m.Called(emails, usernames)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) SaveBatch(creates, updates []*domain.User) error {
+	args := m.Called(creates, updates)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) List(query *domain.UserQuery, offset, limit int) ([]domain.User, int64, error) {
+	args := m.Called(query, offset, limit)
 	return args.Get(0).([]domain.User), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserRepository) ListByKeyset(query *domain.UserQuery, position *domain.UserKeyset, limit int) ([]domain.User, bool, error) {
+	args := m.Called(query, position, limit)
+	return args.Get(0).([]domain.User), args.Bool(1), args.Error(2)
+}
+
+func (m *MockUserRepository) Count(query *domain.UserQuery) (int64, error) {
+	args := m.Called(query)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockUserRepository) Exists(email, username string) (bool, error) {
 	args := m.Called(email, username)
 	return args.Bool(0), args.Error(1)
@@ -77,6 +119,11 @@ func (m *MockRedisInterface) Set(ctx context.Context, key string, value interfac
 	return args.Error(0)
 }
 
+func (m *MockRedisInterface) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	args := m.Called(ctx, key, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockRedisInterface) Get(ctx context.Context, key string) (string, error) {
 	args := m.Called(ctx, key)
 	return args.String(0), args.Error(1)
@@ -87,13 +134,28 @@ func (m *MockRedisInterface) Del(ctx context.Context, keys ...string) error {
 	return args.Error(0)
 }
 
+func (m *MockRedisInterface) Incr(ctx context.Context, key string) (int64, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRedisInterface) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	args := m.Called(ctx, key, expiration)
+	return args.Error(0)
+}
+
+func (m *MockRedisInterface) CompareAndSwap(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	args := m.Called(ctx, key, old, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
 // TestUserService_GetProfile tests the GetProfile method
 func TestUserService_GetProfile(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockRedis := new(MockRedisInterface)
 	jwtConfig := config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}
 
-	userService := service.NewUserService(mockRepo, mockRedis, jwtConfig)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, mockRedis, newTestPasswordHasher(), nil, jwtConfig, "test-cursor-secret")
 
 	testUser := &domain.User{
 		ID:        1,
@@ -104,6 +166,8 @@ func TestUserService_GetProfile(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
+		mockRedis.On("Get", mock.Anything, "user:1").Return("", assert.AnError).Once()
+		mockRedis.On("Set", mock.Anything, "user:1", mock.Anything, 30*time.Minute).Return(nil).Once()
 		mockRepo.On("GetByID", uint(1)).Return(testUser, nil).Once()
 
 		result, err := userService.GetProfile(1)
@@ -116,6 +180,7 @@ func TestUserService_GetProfile(t *testing.T) {
 	})
 
 	t.Run("User Not Found", func(t *testing.T) {
+		mockRedis.On("Get", mock.Anything, "user:999").Return("", assert.AnError).Once()
 		mockRepo.On("GetByID", uint(999)).Return(nil, assert.AnError).Once()
 
 		result, err := userService.GetProfile(999)
@@ -132,7 +197,7 @@ func TestUserService_GetUsers(t *testing.T) {
 	mockRedis := new(MockRedisInterface)
 	jwtConfig := config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}
 
-	userService := service.NewUserService(mockRepo, mockRedis, jwtConfig)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, mockRedis, newTestPasswordHasher(), nil, jwtConfig, "test-cursor-secret")
 
 	testUsers := []domain.User{
 		{ID: 1, Email: "user1@example.com", Username: "user1"},
@@ -140,9 +205,10 @@ func TestUserService_GetUsers(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("List", 0, 10).Return(testUsers, int64(2), nil).Once()
+		query := &domain.UserQuery{Search: "user"}
+		mockRepo.On("List", query, 0, 10).Return(testUsers, int64(2), nil).Once()
 
-		result, pagination, err := userService.GetUsers(1, 10)
+		result, pagination, err := userService.GetUsers(query, 1, 10)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -153,9 +219,9 @@ func TestUserService_GetUsers(t *testing.T) {
 	})
 
 	t.Run("Error", func(t *testing.T) {
-		mockRepo.On("List", 0, 10).Return([]domain.User{}, int64(0), assert.AnError).Once()
+		mockRepo.On("List", (*domain.UserQuery)(nil), 0, 10).Return([]domain.User{}, int64(0), assert.AnError).Once()
 
-		result, pagination, err := userService.GetUsers(1, 10)
+		result, pagination, err := userService.GetUsers(nil, 1, 10)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -168,25 +234,116 @@ func TestUserService_GetUsers(t *testing.T) {
 func TestUserService_DeleteUser(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockRedis := new(MockRedisInterface)
-	jwtConfig := config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}
+	jwtConfig := config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour, RefreshExpiration: 7 * 24 * time.Hour}
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	sessionRepo := newTestSessionRepository()
+	keyRepo := new(MockAPIKeyRepository)
 
-	userService := service.NewUserService(mockRepo, mockRedis, jwtConfig)
+	userService := service.NewUserService(mockRepo, tokenRepo, sessionRepo, keyRepo, mockRedis, newTestPasswordHasher(), nil, jwtConfig, "test-cursor-secret")
 
 	t.Run("Success", func(t *testing.T) {
+		issuedAt := time.Now()
+		mockRepo.On("GetByID", uint(1)).Return(&domain.User{ID: 1}, nil).Once()
 		mockRepo.On("Delete", uint(1)).Return(nil).Once()
+		keyRepo.On("RevokeByUserID", uint(1)).Return(nil).Once()
 
-		err := userService.DeleteUser(1)
+		err := userService.DeleteUser(1, nil)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
+
+		// A deleted user is signed out everywhere and their API keys stop working
+		revoked, err := tokenRepo.IsUserTokenRevoked(1, issuedAt)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		sessionRepo.AssertCalled(t, "RevokeByUserID", uint(1))
+		keyRepo.AssertExpectations(t)
 	})
 
 	t.Run("Error", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(999)).Return(&domain.User{ID: 999}, nil).Once()
 		mockRepo.On("Delete", uint(999)).Return(assert.AnError).Once()
 
-		err := userService.DeleteUser(999)
+		err := userService.DeleteUser(999, nil)
 
 		assert.Error(t, err)
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+// TestUserService_EmailChangeRequiresVerification tests that a changed email has to be verified again
+func TestUserService_EmailChangeRequiresVerification(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRedis := new(MockRedisInterface)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, 30*time.Minute).Return(nil)
+	verifier := new(MockAuthService)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, mockRedis, newTestPasswordHasher(), verifier, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+
+	verifiedUser := func() *domain.User {
+		verifiedAt := time.Now()
+		return &domain.User{ID: 1, Email: "jane@example.com", Username: "jane", EmailVerifiedAt: &verifiedAt}
+	}
+	unverifiedNewEmail := mock.MatchedBy(func(u *domain.User) bool {
+		return u.Email == "new@example.com" && u.EmailVerifiedAt == nil
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(verifiedUser(), nil).Once()
+		mockRepo.On("GetByEmail", "new@example.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockRepo.On("Update", unverifiedNewEmail).Return(nil).Once()
+		verifier.On("SendVerificationEmail", unverifiedNewEmail).Return(nil).Once()
+
+		user, err := userService.UpdateUser(1, &domain.UpdateUserRequest{Email: "new@example.com"}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, user.EmailVerifiedAt)
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(verifiedUser(), nil).Once()
+		mockRepo.On("GetByEmail", "new@example.com").Return(nil, gorm.ErrRecordNotFound).Once()
+		mockRepo.On("Update", unverifiedNewEmail).Return(nil).Once()
+		verifier.On("SendVerificationEmail", unverifiedNewEmail).Return(nil).Once()
+
+		user, err := userService.PatchProfile(1, &domain.PatchUserRequest{Email: domain.PatchString{Set: true, Value: "new@example.com"}}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, user.EmailVerifiedAt)
+	})
+
+	t.Run("Same Email Stays Verified", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(1)).Return(verifiedUser(), nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool { return u.EmailVerifiedAt != nil })).Return(nil).Once()
+
+		user, err := userService.UpdateUser(1, &domain.UpdateUserRequest{Email: "jane@example.com", FirstName: "Jane"}, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, user.EmailVerifiedAt)
+	})
+
+	mockRepo.AssertExpectations(t)
+	verifier.AssertExpectations(t)
+	verifier.AssertNumberOfCalls(t, "SendVerificationEmail", 2)
+}
+
+// TestUserService_ChangeRoleRevokesTokens tests that a role change signs the user out everywhere
+func TestUserService_ChangeRoleRevokesTokens(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRedis := new(MockRedisInterface)
+	mockRedis.On("Set", mock.Anything, "user:2", mock.Anything, 30*time.Minute).Return(nil)
+	tokenRepo := repository.NewTokenRepository(database.NewMemoryStore())
+	sessionRepo := newTestSessionRepository()
+	userService := service.NewUserService(mockRepo, tokenRepo, sessionRepo, nil, mockRedis, newTestPasswordHasher(), nil, newTestJWTConfig(), "test-cursor-secret")
+
+	issuedAt := time.Now()
+	mockRepo.On("GetByID", uint(2)).Return(&domain.User{ID: 2, Role: domain.RoleAdmin}, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(u *domain.User) bool { return u.Role == domain.RoleUser })).Return(nil).Once()
+
+	user, err := userService.ChangeRole(1, 2, domain.RoleUser)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleUser, user.Role)
+
+	// Tokens carrying the old role no longer work
+	revoked, err := tokenRepo.IsUserTokenRevoked(2, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	sessionRepo.AssertCalled(t, "RevokeByUserID", uint(2))
+	mockRepo.AssertExpectations(t)
+}
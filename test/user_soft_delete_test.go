@@ -0,0 +1,190 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/handler"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserRepository_DeletedFilter(t *testing.T) {
+	cases := []struct {
+		deleted  domain.DeletedFilter
+		contains []string
+		excludes []string
+	}{
+		{domain.DeletedExclude, []string{`"users"."deleted_at" IS NULL`}, []string{`IS NOT NULL`}},
+		{domain.DeletedInclude, nil, []string{`deleted_at`}},
+		{domain.DeletedOnly, []string{`deleted_at IS NOT NULL`}, []string{`"users"."deleted_at" IS NULL`}},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.deleted), func(t *testing.T) {
+			db, queries := newDryRunDB(t)
+			_, _, err := repository.NewUserRepository(db).List(&domain.UserQuery{Deleted: tc.deleted}, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, *queries, 2)
+
+			// Both the count and the page are filtered
+			for _, q := range *queries {
+				for _, s := range tc.contains {
+					assert.Contains(t, q.sql, s)
+				}
+				for _, s := range tc.excludes {
+					assert.NotContains(t, q.sql, s)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepository_Restore(t *testing.T) {
+	db, _ := newDryRunDB(t)
+	var sql string
+	var vars []interface{}
+	err := db.Callback().Update().After("gorm:update").Register("test:capture_update", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	require.NoError(t, err)
+
+	err = repository.NewUserRepository(db).Restore(7)
+
+	require.NotEmpty(t, vars)
+	assert.Contains(t, sql, `"deleted_at"=$1`)
+	assert.Nil(t, vars[0])
+	assert.Contains(t, sql, `"version"=version + 1`)
+	assert.Contains(t, sql, `id = $`)
+	assert.Contains(t, sql, `deleted_at IS NOT NULL`)
+
+	// A dry run affects no rows, like a user that is not deleted
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestUserService_RestoreUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRedis := new(MockRedisInterface)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, 30*time.Minute).Return(nil)
+	userService := service.NewUserService(mockRepo, nil, nil, nil, mockRedis, newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+
+	deletedUser := func() *domain.User {
+		return &domain.User{ID: 2, Email: "jane@example.com", Username: "jane", Version: 4,
+			DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("GetByIDWithDeleted", uint(2)).Return(deletedUser(), nil).Once()
+		mockRepo.On("Exists", "jane@example.com", "jane").Return(false, nil).Once()
+		mockRepo.On("Restore", uint(2)).Return(nil).Once()
+		mockRepo.On("GetByID", uint(2)).Return(&domain.User{ID: 2, Email: "jane@example.com", Username: "jane", Version: 5}, nil).Once()
+
+		user, err := userService.RestoreUser(1, 2)
+		require.NoError(t, err)
+		assert.False(t, user.DeletedAt.Valid)
+		assert.Equal(t, uint(5), user.Version)
+	})
+
+	t.Run("Email Taken Since", func(t *testing.T) {
+		mockRepo.On("GetByIDWithDeleted", uint(2)).Return(deletedUser(), nil).Once()
+		mockRepo.On("Exists", "jane@example.com", "jane").Return(true, nil).Once()
+
+		_, err := userService.RestoreUser(1, 2)
+		assert.EqualError(t, err, "email or username already in use")
+	})
+
+	t.Run("Not Deleted", func(t *testing.T) {
+		mockRepo.On("GetByIDWithDeleted", uint(3)).Return(&domain.User{ID: 3}, nil).Once()
+
+		_, err := userService.RestoreUser(1, 3)
+		assert.EqualError(t, err, "user is not deleted")
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo.On("GetByIDWithDeleted", uint(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+		_, err := userService.RestoreUser(1, 9)
+		assert.EqualError(t, err, "user not found")
+	})
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Restore", uint(3))
+}
+
+func TestAdminHandler_SoftDeleteLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockRedis := new(MockRedisInterface)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, 30*time.Minute).Return(nil)
+	keyRepo := new(MockAPIKeyRepository)
+	userService := service.NewUserService(mockRepo, repository.NewTokenRepository(database.NewMemoryStore()), newTestSessionRepository(), keyRepo, mockRedis, newTestPasswordHasher(), nil, config.JWTConfig{Secret: "test-secret"}, "test-cursor-secret")
+	adminHandler := handler.NewAdminHandler(userService, nil, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.GET("/users", handler.NewUserHandler(userService).GetUsers)
+	router.GET("/admin/users", adminHandler.ListUsers)
+	router.POST("/admin/users/:id/restore", adminHandler.RestoreUser)
+	router.DELETE("/admin/users/:id", adminHandler.DeleteUser)
+
+	send := func(method, target string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List Deleted", func(t *testing.T) {
+		mockRepo.On("List", &domain.UserQuery{Deleted: domain.DeletedOnly}, 0, 10).Return([]domain.User{}, int64(0), nil).Once()
+
+		assert.Equal(t, http.StatusOK, send(http.MethodGet, "/admin/users?deleted=only").Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/admin/users?deleted=bogus").Code)
+
+		// Only admins can see deleted users
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/users?deleted=only").Code)
+	})
+
+	t.Run("Restore Conflict", func(t *testing.T) {
+		mockRepo.On("GetByIDWithDeleted", uint(2)).Return(&domain.User{ID: 2, Email: "jane@example.com", Username: "jane",
+			DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}, nil).Once()
+		mockRepo.On("Exists", "jane@example.com", "jane").Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/admin/users/2/restore").Code)
+	})
+
+	t.Run("Hard Delete", func(t *testing.T) {
+		mockRepo.On("GetByIDWithDeleted", uint(2)).Return(&domain.User{ID: 2}, nil).Once()
+		mockRepo.On("HardDelete", uint(2)).Return(nil).Once()
+
+		assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/admin/users/2?hard=true").Code)
+		assert.Equal(t, http.StatusForbidden, send(http.MethodDelete, "/admin/users/1?hard=true").Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodDelete, "/admin/users/2?hard=maybe").Code)
+	})
+
+	t.Run("Soft Delete", func(t *testing.T) {
+		mockRepo.On("GetByID", uint(2)).Return(&domain.User{ID: 2}, nil).Once()
+		mockRepo.On("Delete", uint(2)).Return(nil).Once()
+		keyRepo.On("RevokeByUserID", uint(2)).Return(nil).Once()
+
+		assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/admin/users/2").Code)
+		keyRepo.AssertExpectations(t)
+	})
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "HardDelete", 1)
+}
@@ -0,0 +1,344 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-template-structure/internal/config"
+	"go-template-structure/internal/domain"
+	"go-template-structure/internal/repository"
+	"go-template-structure/internal/service"
+	"go-template-structure/pkg/database"
+	"go-template-structure/pkg/utils"
+	"go-template-structure/pkg/webauthn"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// MockWebAuthnCredentialRepository is a mock implementation of WebAuthnCredentialRepository
+type MockWebAuthnCredentialRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnCredentialRepository) Create(credential *domain.WebAuthnCredential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnCredentialRepository) GetByID(id uint) (*domain.WebAuthnCredential, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebAuthnCredential), args.Error(1)
+}
+
+func (m *MockWebAuthnCredentialRepository) GetByCredentialID(credentialID string) (*domain.WebAuthnCredential, error) {
+	args := m.Called(credentialID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebAuthnCredential), args.Error(1)
+}
+
+func (m *MockWebAuthnCredentialRepository) ListByUserID(userID uint) ([]domain.WebAuthnCredential, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]domain.WebAuthnCredential), args.Error(1)
+}
+
+func (m *MockWebAuthnCredentialRepository) UpdateSignCount(id uint, signCount uint32) error {
+	args := m.Called(id, signCount)
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnCredentialRepository) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// softwareAuthenticator is a platform authenticator in software holding one
+// ES256 passkey. It produces the JSON a browser returns from the WebAuthn API.
+type softwareAuthenticator struct {
+	t            *testing.T
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   string
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T, origin string) *softwareAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softwareAuthenticator{t: t, origin: origin, key: key, credentialID: credentialID}
+}
+
+func (a *softwareAuthenticator) clientData(ceremony, challenge string) string {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	require.NoError(a.t, err)
+	return webauthn.EncodeBase64URL(data)
+}
+
+// authenticatorData builds authenticator data with user presence and verification
+func (a *softwareAuthenticator) authenticatorData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, encodeCBOR(map[interface{}]interface{}{
+			1:  2,  // kty: EC2
+			3:  -7, // alg: ES256
+			-1: 1,  // crv: P-256
+			-2: a.key.X.FillBytes(make([]byte, 32)),
+			-3: a.key.Y.FillBytes(make([]byte, 32)),
+		})...)
+	}
+	return data
+}
+
+func (a *softwareAuthenticator) create(options *webauthn.CreationOptions) webauthn.AttestationResponse {
+	a.userHandle = options.User.ID
+	attestationObject := encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authenticatorData(options.RP.ID, true),
+	})
+
+	return webauthn.AttestationResponse{
+		ID:   webauthn.EncodeBase64URL(a.credentialID),
+		Type: webauthn.CredentialTypePublicKey,
+		Response: webauthn.AuthenticatorAttestationResponse{
+			ClientDataJSON:    a.clientData("webauthn.create", options.Challenge),
+			AttestationObject: webauthn.EncodeBase64URL(attestationObject),
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+func (a *softwareAuthenticator) get(options *webauthn.RequestOptions) webauthn.AssertionResponse {
+	a.signCount++
+	authData := a.authenticatorData(options.RPID, false)
+	clientData := a.clientData("webauthn.get", options.Challenge)
+
+	rawClientData, err := webauthn.DecodeBase64URL(clientData)
+	require.NoError(a.t, err)
+	clientDataHash := sha256.Sum256(rawClientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	return webauthn.AssertionResponse{
+		ID:   webauthn.EncodeBase64URL(a.credentialID),
+		Type: webauthn.CredentialTypePublicKey,
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: webauthn.EncodeBase64URL(authData),
+			Signature:         webauthn.EncodeBase64URL(signature),
+			UserHandle:        a.userHandle,
+		},
+	}
+}
+
+// encodeCBOR encodes the subset of CBOR used by the software authenticator
+func encodeCBOR(value interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		default:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+	}
+
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		out := head(5, uint64(len(v)))
+		for key, item := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	default:
+		panic("unsupported CBOR value")
+	}
+}
+
+func TestWebAuthnService(t *testing.T) {
+	webAuthnConfig := config.WebAuthnConfig{
+		RPID:             "example.com",
+		RPName:           "Example",
+		Origins:          []string{"https://app.example.com"},
+		Timeout:          time.Minute,
+		UserVerification: webauthn.UserVerificationRequired,
+	}
+
+	newService := func(userRepo *MockUserRepository, credentialRepo *MockWebAuthnCredentialRepository) service.WebAuthnService {
+		store := database.NewMemoryStore()
+		lockoutService := newTestLockoutService(userRepo)
+		authService := service.NewAuthService(userRepo, repository.NewTokenRepository(store), newTestSessionRepository(), repository.NewThrottleRepository(store), lockoutService, newTestPasswordHasher(), utils.NewNoopBreachedPasswordChecker(),
+			utils.NewHMACKeySet("test-secret"), &capturingMailer{}, newTestJWTConfig(), config.AuthConfig{}, config.PasswordConfig{})
+		return service.NewWebAuthnService(webauthn.NewRelyingParty(webAuthnConfig), userRepo, credentialRepo, repository.NewWebAuthnChallengeRepository(store), lockoutService, authService)
+	}
+
+	testUser := &domain.User{ID: 1, Email: "test@example.com", FirstName: "Test", LastName: "User", IsActive: true}
+
+	// register runs a registration ceremony and returns the stored credential
+	register := func(t *testing.T, webAuthnService service.WebAuthnService, userRepo *MockUserRepository, credentialRepo *MockWebAuthnCredentialRepository, authenticator *softwareAuthenticator) *domain.WebAuthnCredential {
+		userRepo.On("GetByID", uint(1)).Return(testUser, nil)
+		credentialRepo.On("ListByUserID", uint(1)).Return([]domain.WebAuthnCredential{}, nil).Once()
+		credentialRepo.On("GetByCredentialID", webauthn.EncodeBase64URL(authenticator.credentialID)).Return(nil, gorm.ErrRecordNotFound).Once()
+
+		var stored *domain.WebAuthnCredential
+		credentialRepo.On("Create", mock.AnythingOfType("*domain.WebAuthnCredential")).Run(func(args mock.Arguments) {
+			stored = args.Get(0).(*domain.WebAuthnCredential)
+			stored.ID = 10
+		}).Return(nil).Once()
+
+		options, err := webAuthnService.BeginRegistration(1)
+		require.NoError(t, err)
+		assert.Equal(t, "example.com", options.RP.ID)
+		assert.Equal(t, "Test User", options.User.DisplayName)
+		assert.True(t, options.AuthenticatorSelection.RequireResidentKey)
+
+		credential, err := webAuthnService.FinishRegistration(1, &domain.RegisterPasskeyRequest{Name: "Laptop", Credential: authenticator.create(options)})
+		require.NoError(t, err)
+		require.Same(t, stored, credential)
+		return credential
+	}
+
+	t.Run("registers and logs in with a passkey", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		credentialRepo := new(MockWebAuthnCredentialRepository)
+		webAuthnService := newService(userRepo, credentialRepo)
+		authenticator := newSoftwareAuthenticator(t, "https://app.example.com")
+
+		credential := register(t, webAuthnService, userRepo, credentialRepo, authenticator)
+		assert.Equal(t, "Laptop", credential.Name)
+		assert.Equal(t, webauthn.EncodeBase64URL(authenticator.credentialID), credential.CredentialID)
+		assert.Equal(t, "internal", credential.Transports)
+		assert.Equal(t, "00000000-0000-0000-0000-000000000000", credential.AAGUID)
+
+		credentialRepo.On("GetByCredentialID", credential.CredentialID).Return(credential, nil)
+		credentialRepo.On("UpdateSignCount", uint(10), uint32(1)).Return(nil).Once()
+
+		options, err := webAuthnService.BeginLogin()
+		require.NoError(t, err)
+		assertion := authenticator.get(options)
+
+		resp, err := webAuthnService.FinishLogin(&assertion, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		credentialRepo.AssertExpectations(t)
+
+		// The challenge is single-use, so a replayed assertion is rejected
+		_, err = webAuthnService.FinishLogin(&assertion, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid or expired challenge")
+	})
+
+	t.Run("rejects a cloned authenticator", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		credentialRepo := new(MockWebAuthnCredentialRepository)
+		webAuthnService := newService(userRepo, credentialRepo)
+		authenticator := newSoftwareAuthenticator(t, "https://app.example.com")
+
+		credential := register(t, webAuthnService, userRepo, credentialRepo, authenticator)
+		credential.SignCount = 5
+		credentialRepo.On("GetByCredentialID", credential.CredentialID).Return(credential, nil)
+
+		options, err := webAuthnService.BeginLogin()
+		require.NoError(t, err)
+		assertion := authenticator.get(options)
+
+		_, err = webAuthnService.FinishLogin(&assertion, domain.ClientInfo{})
+		assert.EqualError(t, err, "invalid passkey")
+		credentialRepo.AssertNotCalled(t, "UpdateSignCount", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects another origin", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		credentialRepo := new(MockWebAuthnCredentialRepository)
+		webAuthnService := newService(userRepo, credentialRepo)
+		phishing := newSoftwareAuthenticator(t, "https://app.example.com.evil.test")
+
+		userRepo.On("GetByID", uint(1)).Return(testUser, nil)
+		credentialRepo.On("ListByUserID", uint(1)).Return([]domain.WebAuthnCredential{}, nil)
+
+		options, err := webAuthnService.BeginRegistration(1)
+		require.NoError(t, err)
+
+		_, err = webAuthnService.FinishRegistration(1, &domain.RegisterPasskeyRequest{Credential: phishing.create(options)})
+		assert.EqualError(t, err, "invalid passkey")
+		credentialRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("binds registration to the user", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		credentialRepo := new(MockWebAuthnCredentialRepository)
+		webAuthnService := newService(userRepo, credentialRepo)
+		authenticator := newSoftwareAuthenticator(t, "https://app.example.com")
+
+		userRepo.On("GetByID", uint(1)).Return(testUser, nil)
+		credentialRepo.On("ListByUserID", uint(1)).Return([]domain.WebAuthnCredential{}, nil)
+
+		options, err := webAuthnService.BeginRegistration(1)
+		require.NoError(t, err)
+
+		_, err = webAuthnService.FinishRegistration(2, &domain.RegisterPasskeyRequest{Credential: authenticator.create(options)})
+		assert.EqualError(t, err, "invalid or expired challenge")
+	})
+
+	t.Run("deletes only own passkeys", func(t *testing.T) {
+		credentialRepo := new(MockWebAuthnCredentialRepository)
+		webAuthnService := newService(new(MockUserRepository), credentialRepo)
+
+		credentialRepo.On("GetByID", uint(10)).Return(&domain.WebAuthnCredential{ID: 10, UserID: 1}, nil)
+		credentialRepo.On("Delete", uint(10)).Return(nil).Once()
+
+		assert.EqualError(t, webAuthnService.DeleteCredential(2, 10), "passkey not found")
+		assert.NoError(t, webAuthnService.DeleteCredential(1, 10))
+		credentialRepo.AssertExpectations(t)
+	})
+}